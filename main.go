@@ -9,5 +9,6 @@ func main() {
 	
 	plugin.Serve( &plugin.ServeOpts {
 		ProviderFunc: vsphere.Provider,
+		ProvisionerFunc: vsphere.Provisioner,
 	} )
 }
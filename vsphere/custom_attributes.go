@@ -0,0 +1,179 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// Returns the custom attribute definitions of vCenter.
+func getCustomFieldDefs(ctx context.Context, client *govmomi.Client) ([]types.CustomFieldDef, error) {
+
+	ref := client.Client.ServiceContent.CustomFieldsManager
+	if ref == nil {
+		return nil, fmt.Errorf("custom attributes are only supported by vCenter")
+	}
+
+	var m mo.CustomFieldsManager
+
+	err := property.DefaultCollector(client.Client).RetrieveOne(ctx, *ref, []string{"field"}, &m)
+	if err != nil {
+		return nil, err
+	}
+	return m.Field, nil
+}
+
+// Returns the definition of the custom attribute with the given name that
+// applies to the managed object type or nil if there is none. Definitions
+// without a managed object type apply to all types.
+func findCustomFieldDef(defs []types.CustomFieldDef, name, moType string) *types.CustomFieldDef {
+
+	for i, def := range defs {
+		if def.Name == name && (def.ManagedObjectType == "" || def.ManagedObjectType == moType) {
+			return &defs[i]
+		}
+	}
+	return nil
+}
+
+// Returns the keys and values of the custom attributes to set on an object
+// of the given type to change its configured attributes from old to new.
+// Attributes no longer configured are cleared.
+func getCustomAttributeChanges(defs []types.CustomFieldDef, moType string, old, new map[string]interface{}) (map[int]string, error) {
+
+	changes := make(map[int]string)
+
+	for name, v := range new {
+		if o, ok := old[name]; ok && o == v {
+			continue
+		}
+		def := findCustomFieldDef(defs, name, moType)
+		if def == nil {
+			return nil, fmt.Errorf("custom attribute '%s' is not defined for %s objects", name, moType)
+		}
+		changes[def.Key] = v.(string)
+	}
+	for name := range old {
+		if _, ok := new[name]; !ok {
+			if def := findCustomFieldDef(defs, name, moType); def != nil {
+				changes[def.Key] = ""
+			}
+		}
+	}
+	return changes, nil
+}
+
+// Returns the values of the configured custom attributes of an object. Only
+// configured attributes are returned as other attributes may be managed
+// by other tools.
+func getCustomAttributeValues(defs []types.CustomFieldDef, values []types.BaseCustomFieldValue, configured map[string]interface{}) map[string]interface{} {
+
+	names := make(map[int]string)
+	for _, def := range defs {
+		names[def.Key] = def.Name
+	}
+
+	attributes := make(map[string]interface{})
+	for _, v := range values {
+		if s, ok := v.(*types.CustomFieldStringValue); ok {
+			name := names[s.Key]
+			if _, ok := configured[name]; ok && s.Value != "" {
+				attributes[name] = s.Value
+			}
+		}
+	}
+	return attributes
+}
+
+// Sets the changed custom_attributes of a resource on its managed object.
+func updateCustomAttributes(d *schema.ResourceData, meta interface{}, ref types.ManagedObjectReference) error {
+
+	if !d.HasChange("custom_attributes") {
+		return nil
+	}
+
+	client := meta.(*govmomi.Client)
+
+	defs, err := getCustomFieldDefs(context.Background(), client)
+	if err != nil {
+		return err
+	}
+
+	old, new := d.GetChange("custom_attributes")
+	changes, err := getCustomAttributeChanges(defs, ref.Type, old.(map[string]interface{}), new.(map[string]interface{}))
+	if err != nil {
+		return err
+	}
+
+	for key, value := range changes {
+
+		log.Printf("[DEBUG] Setting custom attribute %d of '%s' to '%s'", key, ref.Value, value)
+
+		req := types.SetField{
+			This: *client.Client.ServiceContent.CustomFieldsManager,
+			Entity: ref,
+			Key: key,
+			Value: value,
+		}
+		_, err = methods.SetField(context.Background(), client.Client, &req)
+		if err != nil {
+			log.Printf("[ERROR] Unable to set custom attribute %d of '%s'", key, ref.Value)
+			return err
+		}
+	}
+	return nil
+}
+
+// Reads the configured custom_attributes of a resource from its managed object.
+func readCustomAttributes(d *schema.ResourceData, meta interface{}, ref types.ManagedObjectReference) error {
+
+	configured := d.Get("custom_attributes").(map[string]interface{})
+	if len(configured) == 0 {
+		return nil
+	}
+
+	client := meta.(*govmomi.Client)
+
+	defs, err := getCustomFieldDefs(context.Background(), client)
+	if err != nil {
+		return err
+	}
+
+	req := types.RetrieveProperties{
+		SpecSet: []types.PropertyFilterSpec{
+			types.PropertyFilterSpec{
+				ObjectSet: []types.ObjectSpec{
+					types.ObjectSpec{ Obj: ref },
+				},
+				PropSet: []types.PropertySpec{
+					types.PropertySpec{ Type: ref.Type, PathSet: []string{"customValue"} },
+				},
+			},
+		},
+	}
+
+	res, err := property.DefaultCollector(client.Client).RetrieveProperties(context.Background(), req)
+	if err != nil {
+		return err
+	}
+
+	var values []types.BaseCustomFieldValue
+	for _, oc := range res.Returnval {
+		for _, p := range oc.PropSet {
+			if a, ok := p.Val.(types.ArrayOfCustomFieldValue); ok {
+				values = a.CustomFieldValue
+			}
+		}
+	}
+
+	d.Set("custom_attributes", getCustomAttributeValues(defs, values, configured))
+	return nil
+}
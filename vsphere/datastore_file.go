@@ -0,0 +1,95 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"path"
+
+	"golang.org/x/net/context"
+
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// Uploads a local file to a path in the datastore through the datastore's
+// HTTP file access URL. Folders in the path are created if they do not exist.
+func uploadDatastoreFile(ctx context.Context, client *govmomi.Client, datacenter *object.Datacenter, datastore *object.Datastore, localPath, remotePath string) error {
+
+	err := makeDatastoreFileFolder(ctx, client, datacenter, datastore, remotePath)
+	if err != nil {
+		return err
+	}
+
+	u, err := datastore.URL(ctx, datacenter, remotePath)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[DEBUG] Uploading '%s' to '%s'", localPath, datastore.Path(remotePath))
+
+	return client.Client.UploadFile(localPath, u, nil)
+}
+
+// Creates the folder of a file in the datastore and any missing parent folders.
+func makeDatastoreFileFolder(ctx context.Context, client *govmomi.Client, datacenter *object.Datacenter, datastore *object.Datastore, remotePath string) error {
+
+	dir := path.Dir(remotePath)
+	if dir == "." || dir == "/" {
+		return nil
+	}
+
+	err := object.NewFileManager(client.Client).MakeDirectory(ctx, datastore.Path(dir), datacenter, true)
+	if err != nil && !isFileAlreadyExists(err) {
+		log.Printf("[ERROR] Unable to create folder '%s'", datastore.Path(dir))
+		return err
+	}
+	return nil
+}
+
+// Returns whether a file exists at the given path in the datastore.
+func datastoreFileExists(ctx context.Context, client *govmomi.Client, datastore *object.Datastore, remotePath string) (bool, error) {
+
+	file, err := searchDatastoreFile(ctx, client, datastore, remotePath, nil)
+	return file != nil, err
+}
+
+// Returns the details of the file at the given path in the datastore
+// selected by the queries or nil if the file does not exist.
+func searchDatastoreFile(ctx context.Context, client *govmomi.Client, datastore *object.Datastore, remotePath string, query []types.BaseFileQuery) (types.BaseFileInfo, error) {
+
+	browser, err := datastore.Browser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	folder := fmt.Sprintf("[%s]", datastore.Name())
+	if dir := path.Dir(remotePath); dir != "." && dir != "/" {
+		folder = datastore.Path(dir)
+	}
+
+	spec := types.HostDatastoreBrowserSearchSpec{
+		Query: query,
+		Details: &types.FileQueryFlags{ FileType: true, FileSize: true },
+		MatchPattern: []string{ path.Base(remotePath) },
+	}
+
+	task, err := browser.SearchDatastore(ctx, folder, &spec)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := waitForTask(client.Client, task, fmt.Sprintf("search datastore folder '%s'", folder), defaultTaskTimeout)
+	if err != nil {
+		if isFileNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	results, ok := info.Result.(types.HostDatastoreBrowserSearchResults)
+	if !ok || len(results.File) == 0 {
+		return nil, nil
+	}
+	return results.File[0], nil
+}
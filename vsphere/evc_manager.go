@@ -0,0 +1,183 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+
+	"golang.org/x/net/context"
+
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// The vendored vim25 bindings predate the ClusterEVCManager managed object
+// so the few methods needed to configure EVC are declared here.
+
+type evcManagerRequest struct {
+	This types.ManagedObjectReference `xml:"_this"`
+}
+
+type evcManagerResponse struct {
+	Returnval *types.ManagedObjectReference `xml:"returnval,omitempty"`
+}
+
+type evcManagerBody struct {
+	Req    *evcManagerRequest  `xml:"urn:vim25 EvcManager,omitempty"`
+	Res    *evcManagerResponse `xml:"urn:vim25 EvcManagerResponse,omitempty"`
+	Fault_ *soap.Fault         `xml:"http://schemas.xmlsoap.org/soap/envelope/ Fault,omitempty"`
+}
+
+func (b *evcManagerBody) Fault() *soap.Fault { return b.Fault_ }
+
+type configureEvcModeRequest struct {
+	This       types.ManagedObjectReference `xml:"_this"`
+	EvcModeKey string                       `xml:"evcModeKey"`
+}
+
+type evcTaskResponse struct {
+	Returnval types.ManagedObjectReference `xml:"returnval"`
+}
+
+type configureEvcModeBody struct {
+	Req    *configureEvcModeRequest `xml:"urn:vim25 ConfigureEvcMode_Task,omitempty"`
+	Res    *evcTaskResponse         `xml:"urn:vim25 ConfigureEvcMode_TaskResponse,omitempty"`
+	Fault_ *soap.Fault              `xml:"http://schemas.xmlsoap.org/soap/envelope/ Fault,omitempty"`
+}
+
+func (b *configureEvcModeBody) Fault() *soap.Fault { return b.Fault_ }
+
+type disableEvcModeBody struct {
+	Req    *evcManagerRequest `xml:"urn:vim25 DisableEvcMode_Task,omitempty"`
+	Res    *evcTaskResponse   `xml:"urn:vim25 DisableEvcMode_TaskResponse,omitempty"`
+	Fault_ *soap.Fault        `xml:"http://schemas.xmlsoap.org/soap/envelope/ Fault,omitempty"`
+}
+
+func (b *disableEvcModeBody) Fault() *soap.Fault { return b.Fault_ }
+
+func getEvcManager(ctx context.Context, client *govmomi.Client, cluster *object.ClusterComputeResource) (*types.ManagedObjectReference, error) {
+
+	reqBody := evcManagerBody{ Req: &evcManagerRequest{ This: cluster.Reference() } }
+	resBody := evcManagerBody{}
+
+	if err := client.RoundTrip(ctx, &reqBody, &resBody); err != nil {
+		return nil, fmt.Errorf("unable to retrieve the EVC manager of the cluster. vCenter may not support configuring EVC through the API: %s", err.Error())
+	}
+	if resBody.Res == nil || resBody.Res.Returnval == nil {
+		return nil, fmt.Errorf("the EVC manager of the cluster is not available")
+	}
+
+	return resBody.Res.Returnval, nil
+}
+
+// Sets the EVC baseline of the cluster or disables EVC if the given mode is empty.
+func configureEvcMode(ctx context.Context, client *govmomi.Client, cluster *object.ClusterComputeResource, evcModeKey string) error {
+
+	evcManager, err := getEvcManager(ctx, client, cluster)
+	if err != nil {
+		return err
+	}
+
+	var task types.ManagedObjectReference
+
+	if evcModeKey == "" {
+
+		reqBody := disableEvcModeBody{ Req: &evcManagerRequest{ This: *evcManager } }
+		resBody := disableEvcModeBody{}
+
+		if err := client.RoundTrip(ctx, &reqBody, &resBody); err != nil {
+			return err
+		}
+		task = resBody.Res.Returnval
+
+	} else {
+
+		reqBody := configureEvcModeBody{ Req: &configureEvcModeRequest{ This: *evcManager, EvcModeKey: evcModeKey } }
+		resBody := configureEvcModeBody{}
+
+		if err := client.RoundTrip(ctx, &reqBody, &resBody); err != nil {
+			return err
+		}
+		task = resBody.Res.Returnval
+	}
+
+	_, err = waitForTask(client.Client, object.NewTask(client.Client, task), fmt.Sprintf("configure EVC mode of cluster '%s'", cluster.Reference().Value), defaultTaskTimeout)
+	return err
+}
+
+func getCurrentEvcMode(ctx context.Context, cluster *object.ClusterComputeResource) (string, error) {
+
+	var mccr mo.ClusterComputeResource
+
+	err := cluster.Properties(ctx, cluster.Reference(), []string{"summary"}, &mccr)
+	if err != nil {
+		return "", err
+	}
+
+	summary, ok := mccr.Summary.(*types.ClusterComputeResourceSummary)
+	if !ok {
+		return "", fmt.Errorf("unexpected summary type '%T' for cluster", mccr.Summary)
+	}
+	return summary.CurrentEVCModeKey, nil
+}
+
+// Verifies that the EVC mode is known to vCenter and that every host in
+// the cluster is capable of running at that baseline.
+func validateEvcMode(ctx context.Context, client *govmomi.Client, cluster *object.ClusterComputeResource, evcModeKey string) error {
+
+	var si mo.ServiceInstance
+
+	ref := types.ManagedObjectReference{ Type: "ServiceInstance", Value: "ServiceInstance" }
+	err := client.RetrieveOne(ctx, ref, []string{"capability"}, &si)
+	if err != nil {
+		return err
+	}
+
+	modes := make(map[string]types.EVCMode)
+	for _, m := range si.Capability.SupportedEVCMode {
+		modes[m.Key] = m
+	}
+
+	mode, ok := modes[evcModeKey]
+	if !ok {
+		var keys []string
+		for _, m := range si.Capability.SupportedEVCMode {
+			keys = append(keys, m.Key)
+		}
+		return fmt.Errorf("EVC mode '%s' is not supported by vCenter. it should be one of %v", evcModeKey, keys)
+	}
+
+	var mccr mo.ClusterComputeResource
+
+	err = cluster.Properties(ctx, cluster.Reference(), []string{"host"}, &mccr)
+	if err != nil {
+		return err
+	}
+
+	for _, h := range mccr.Host {
+
+		var mhs mo.HostSystem
+
+		err = cluster.Properties(ctx, h, []string{"name", "summary"}, &mhs)
+		if err != nil {
+			return err
+		}
+
+		maxModeKey := mhs.Summary.MaxEVCModeKey
+		if maxModeKey == "" {
+			log.Printf("[DEBUG] Host '%s' does not report a maximum EVC mode", mhs.Name)
+			continue
+		}
+
+		maxMode, ok := modes[maxModeKey]
+		if !ok || maxMode.Vendor != mode.Vendor || maxMode.VendorTier < mode.VendorTier {
+			return fmt.Errorf(
+				"EVC mode '%s' cannot be applied as host '%s' supports at most EVC mode '%s'",
+				evcModeKey, mhs.Name, maxModeKey)
+		}
+	}
+
+	return nil
+}
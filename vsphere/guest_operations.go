@@ -0,0 +1,361 @@
+package vsphere
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/guest"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// Interval at which a program started in a guest is polled for its output
+// and exit code
+const guestProcessPollInterval = 2 * time.Second
+
+// Runs file and process operations in the guest of a VM through VMware
+// Tools using the credentials of a guest account
+type guestSession struct {
+	client *govmomi.Client
+	vm *object.VirtualMachine
+	auth types.BaseGuestAuthentication
+	files *guest.FileManager
+	processes *guest.ProcessManager
+	windows bool
+}
+
+func newGuestSession(ctx context.Context, client *govmomi.Client, vm *object.VirtualMachine, username, password string) (*guestSession, error) {
+
+	if username == "" {
+		return nil, fmt.Errorf("a guest user is required to access the guest of VM '%s'", vm.Reference().Value)
+	}
+
+	auth := &types.NamePasswordAuthentication{
+		Username: username,
+		Password: password,
+	}
+
+	var mvm mo.VirtualMachine
+
+	err := vm.Properties(ctx, vm.Reference(), []string{"config.guestId", "guest.guestFamily"}, &mvm)
+	if err != nil {
+		return nil, err
+	}
+
+	var guestID, guestFamily string
+	if mvm.Config != nil {
+		guestID = mvm.Config.GuestId
+	}
+	if mvm.Guest != nil {
+		guestFamily = mvm.Guest.GuestFamily
+	}
+
+	ops := guest.NewOperationsManager(client.Client, vm.Reference())
+
+	authManager, err := ops.AuthManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = authManager.ValidateCredentials(ctx, auth)
+	if err != nil {
+		log.Printf("[ERROR] Unable to authenticate guest user '%s' of VM '%s'", username, vm.Reference().Value)
+		return nil, err
+	}
+
+	files, err := ops.FileManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+	processes, err := ops.ProcessManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &guestSession{
+		client: client,
+		vm: vm,
+		auth: auth,
+		files: files,
+		processes: processes,
+		windows: isWindowsGuest(guestFamily, guestID),
+	}, nil
+}
+
+// Uploads the content read from a reader to a file in the guest replacing
+// any existing file. A permissions value of 0 keeps the guest's default.
+// Permissions do not apply to Windows guests.
+func (s *guestSession) upload(ctx context.Context, content io.Reader, size int64, guestPath string, permissions int64) error {
+
+	var attrs types.BaseGuestFileAttributes = &types.GuestPosixFileAttributes{ Permissions: permissions }
+	if s.windows {
+		attrs = &types.GuestWindowsFileAttributes{}
+	}
+
+	transferURL, err := s.files.InitiateFileTransferToGuest(ctx, s.auth, guestPath, attrs, size, true)
+	if err != nil {
+		return err
+	}
+	u, err := s.client.Client.ParseURL(transferURL)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[DEBUG] Uploading %d bytes to guest file '%s'", size, guestPath)
+
+	return s.client.Client.Upload(content, u, &soap.Upload{ ContentLength: size })
+}
+
+// Uploads a local file to a path in the guest.
+func (s *guestSession) uploadFile(ctx context.Context, localPath, guestPath string) error {
+
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return s.upload(ctx, f, info.Size(), guestPath, int64(info.Mode().Perm()))
+}
+
+// Returns the content of a file in the guest.
+func (s *guestSession) download(ctx context.Context, guestPath string) ([]byte, error) {
+
+	info, err := s.files.InitiateFileTransferFromGuest(ctx, s.auth, guestPath)
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.client.Client.ParseURL(info.Url)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := ioutil.TempFile("", "vsphere-guest")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	f.Close()
+
+	err = s.client.Client.DownloadFile(f.Name(), u, nil)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(f.Name())
+}
+
+// Returns the details of a file in the guest or nil if it does not exist.
+func (s *guestSession) stat(ctx context.Context, guestPath string) (*types.GuestFileInfo, error) {
+
+	list, err := s.files.ListFiles(ctx, s.auth, guestPath, 0, 1, "")
+	if err != nil {
+		if isFileNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(list.Files) == 0 {
+		return nil, nil
+	}
+	return &list.Files[0], nil
+}
+
+// Deletes a file in the guest ignoring files that no longer exist.
+func (s *guestSession) remove(ctx context.Context, guestPath string) error {
+
+	log.Printf("[DEBUG] Deleting guest file '%s'", guestPath)
+
+	err := s.files.DeleteFile(ctx, s.auth, guestPath)
+	if err != nil && !isFileNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// Runs a command with the shell of the guest and waits for it to exit. The
+// output of the command is captured in a temporary guest file which is
+// passed to the output function as it grows. The shell defaults to cmd.exe
+// for Windows guests and /bin/sh for others.
+func (s *guestSession) run(ctx context.Context, shell, command, workingDirectory string, output func(string)) error {
+
+	if shell == "" {
+		shell = "/bin/sh"
+		if s.windows {
+			shell = `C:\Windows\System32\cmd.exe`
+		}
+	}
+
+	outputFile, err := s.files.CreateTemporaryFile(ctx, s.auth, "terraform-", ".out")
+	if err != nil {
+		return err
+	}
+	defer s.files.DeleteFile(context.Background(), s.auth, outputFile)
+
+	spec := &types.GuestProgramSpec{
+		ProgramPath: shell,
+		Arguments: getGuestCommandArguments(command, outputFile, s.windows),
+		WorkingDirectory: workingDirectory,
+	}
+
+	log.Printf("[DEBUG] Running command in guest of VM '%s': %s", s.vm.Reference().Value, command)
+
+	pid, err := s.processes.StartProgram(ctx, s.auth, spec)
+	if err != nil {
+		return err
+	}
+
+	// Only complete lines are passed on until the process has exited
+	var written int
+	streamOutput := func(exited bool) {
+		content, err := s.download(ctx, outputFile)
+		if err != nil {
+			log.Printf("[DEBUG] Unable to read output of guest process %d: %s", pid, err.Error())
+			return
+		}
+		end := len(content)
+		if !exited {
+			end = bytes.LastIndex(content, []byte("\n")) + 1
+		}
+		if end > written {
+			for _, line := range strings.SplitAfter(string(content[written:end]), "\n") {
+				if len(line) > 0 {
+					output(strings.TrimRight(line, "\r\n"))
+				}
+			}
+			written = end
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("[DEBUG] Terminating guest process %d", pid)
+			s.processes.TerminateProcess(context.Background(), s.auth, pid)
+			return fmt.Errorf("command '%s' did not complete: %s", command, ctx.Err().Error())
+		case <-time.After(guestProcessPollInterval):
+		}
+
+		procs, err := s.processes.ListProcesses(ctx, s.auth, []int64{pid})
+		if err != nil {
+			return err
+		}
+		if len(procs) == 0 {
+			return fmt.Errorf("guest process %d of command '%s' was not found", pid, command)
+		}
+
+		// Output written between the last poll and the process exiting is
+		// picked up by reading the output after the end time is set
+		exited := procs[0].EndTime != nil
+		streamOutput(exited)
+
+		if exited {
+			if procs[0].ExitCode != 0 {
+				return fmt.Errorf("command '%s' exited with code %d", command, procs[0].ExitCode)
+			}
+			return nil
+		}
+	}
+}
+
+// Returns the shell arguments that run a command with its standard output
+// and error redirected to a file. With /S cmd.exe only strips the outer
+// quotes so the command is passed on as it is.
+func getGuestCommandArguments(command, outputFile string, windows bool) string {
+	if windows {
+		return fmt.Sprintf(`/S /C "(%s) > "%s" 2>&1"`, command, outputFile)
+	}
+	return fmt.Sprintf("-c %s", shellQuote(fmt.Sprintf("(%s) > %s 2>&1", command, shellQuote(outputFile))))
+}
+
+// Returns whether a guest runs Windows by the guest family reported by
+// VMware Tools or by its configured guest id if Tools do not report it.
+func isWindowsGuest(guestFamily, guestID string) bool {
+	if guestFamily != "" {
+		return guestFamily == string(types.VirtualMachineGuestOsFamilyWindowsGuest)
+	}
+	return strings.HasPrefix(guestID, "win")
+}
+
+// Returns the VM with the given object_id, inventory path or name. VMs
+// given by name are searched for in all folders and vApps of the datacenter.
+func findGuestVM(ctx context.Context, client *govmomi.Client, finder *find.Finder, datacenter *object.Datacenter, id string) (*object.VirtualMachine, error) {
+
+	ref, err := findObjectReference(ctx, client.Client, "VirtualMachine", id)
+	if err != nil {
+		return nil, err
+	}
+	if ref != nil {
+		return object.NewVirtualMachine(client.Client, *ref), nil
+	}
+	if strings.Contains(id, "/") {
+		return finder.VirtualMachine(ctx, id)
+	}
+
+	folders, err := datacenter.Folders(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req := types.CreateContainerView{
+		This: *client.Client.ServiceContent.ViewManager,
+		Container: folders.VmFolder.Reference(),
+		Type: []string{"VirtualMachine"},
+		Recursive: true,
+	}
+	res, err := methods.CreateContainerView(ctx, client.Client, &req)
+	if err != nil {
+		return nil, err
+	}
+	defer methods.DestroyView(context.Background(), client.Client, &types.DestroyView{ This: res.Returnval })
+
+	pc := property.DefaultCollector(client.Client)
+
+	var view mo.ContainerView
+	err = pc.RetrieveOne(ctx, res.Returnval, []string{"view"}, &view)
+	if err != nil {
+		return nil, err
+	}
+
+	var vms []mo.VirtualMachine
+	if len(view.View) > 0 {
+		err = pc.Retrieve(ctx, view.View, []string{"name"}, &vms)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var matches []types.ManagedObjectReference
+	for _, vm := range vms {
+		if vm.Name == id {
+			matches = append(matches, vm.Reference())
+		}
+	}
+	switch len(matches) {
+		case 0:
+			return nil, fmt.Errorf("VM '%s' was not found", id)
+		case 1:
+			return object.NewVirtualMachine(client.Client, matches[0]), nil
+	}
+	return nil, fmt.Errorf("%d VMs are named '%s'. use the VM's inventory path or object_id instead", len(matches), id)
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
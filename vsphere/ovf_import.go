@@ -0,0 +1,491 @@
+package vsphere
+
+import (
+	"archive/tar"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/progress"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// Parameters of an OVF or OVA deployment
+type ovfImportParams struct {
+	Source           string
+	Name             string
+	NetworkMappings  map[string]string
+	DiskProvisioning string
+	Properties       map[string]string
+}
+
+// Provides access to the descriptor and the files referenced by it
+type ovfArchive interface {
+	Open(name string) (io.ReadCloser, int64, error)
+}
+
+// Files of an OVF package in a local folder
+type ovfFileArchive struct {
+	path string
+}
+
+func (a *ovfFileArchive) Open(name string) (io.ReadCloser, int64, error) {
+
+	fpath := name
+	if name != a.path {
+		fpath = filepath.Join(filepath.Dir(a.path), name)
+	}
+
+	s, err := os.Stat(fpath)
+	if err != nil {
+		return nil, 0, err
+	}
+	f, err := os.Open(fpath)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return f, s.Size(), nil
+}
+
+// Files of an OVA which is a tar archive of an OVF package
+type ovfTapeArchive struct {
+	path string
+}
+
+type ovfTapeArchiveEntry struct {
+	io.Reader
+	f *os.File
+}
+
+func (e *ovfTapeArchiveEntry) Close() error {
+	return e.f.Close()
+}
+
+func (a *ovfTapeArchive) Open(name string) (io.ReadCloser, int64, error) {
+
+	f, err := os.Open(a.path)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	r := tar.NewReader(f)
+	for {
+		h, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			f.Close()
+			return nil, 0, err
+		}
+
+		matched, err := filepath.Match(name, h.Name)
+		if err != nil {
+			f.Close()
+			return nil, 0, err
+		}
+		if matched {
+			return &ovfTapeArchiveEntry{r, f}, h.Size, nil
+		}
+	}
+
+	f.Close()
+	return nil, 0, os.ErrNotExist
+}
+
+// Files of an OVF package published on a web server
+type ovfURLArchive struct {
+	url *url.URL
+}
+
+func (a *ovfURLArchive) Open(name string) (io.ReadCloser, int64, error) {
+
+	u, err := a.url.Parse(name)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	res, err := http.Get(u.String())
+	if err != nil {
+		return nil, 0, err
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, 0, fmt.Errorf("unable to download '%s': %s", u.String(), res.Status)
+	}
+
+	return res.Body, res.ContentLength, nil
+}
+
+// Deploys a virtual machine from an OVF or OVA file or URL and returns it powered off.
+func importOvf(ctx context.Context, client *govmomi.Client, finder *find.Finder, params ovfImportParams,
+	resourcePool *object.ResourcePool, datastore *object.Datastore, folder *object.Folder) (*object.VirtualMachine, error) {
+
+	archive, descriptorName, cleanup, err := openOvfSource(params.Source)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	f, _, err := archive.Open(descriptorName)
+	if err != nil {
+		log.Printf("[ERROR] Unable to open OVF descriptor of '%s'", params.Source)
+		return nil, err
+	}
+	descriptor, err := ioutil.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	cisp := types.OvfCreateImportSpecParams{
+		EntityName: params.Name,
+		OvfManagerCommonParams: types.OvfManagerCommonParams{
+			Locale: "US",
+		},
+		DiskProvisioning: params.DiskProvisioning,
+	}
+	if cisp.EntityName == "" {
+		ovf := struct {
+			VirtualSystem struct {
+				Name string
+			}
+		}{}
+		if err := xml.Unmarshal(descriptor, &ovf); err != nil {
+			return nil, fmt.Errorf("failed to parse ovf: %s", err.Error())
+		}
+		cisp.EntityName = ovf.VirtualSystem.Name
+	}
+
+	for ovfNetwork, networkName := range params.NetworkMappings {
+		network, err := finder.Network(ctx, networkName)
+		if err != nil {
+			log.Printf("[ERROR] Unable to find network '%s' to map OVF network '%s' to", networkName, ovfNetwork)
+			return nil, err
+		}
+		ref, ok := network.(object.Reference)
+		if !ok {
+			return nil, fmt.Errorf("network '%s' cannot be referenced in an OVF network mapping", networkName)
+		}
+		cisp.NetworkMapping = append(cisp.NetworkMapping, types.OvfNetworkMapping{
+			Name: ovfNetwork,
+			Network: ref.Reference(),
+		} )
+	}
+	for key, value := range params.Properties {
+		cisp.PropertyMapping = append(cisp.PropertyMapping, types.KeyValue{ Key: key, Value: value })
+	}
+
+	spec, err := object.NewOvfManager(client.Client).CreateImportSpec(ctx, string(descriptor), resourcePool, datastore, cisp)
+	if err != nil {
+		return nil, err
+	}
+	if spec.Error != nil {
+		return nil, errors.New(spec.Error[0].LocalizedMessage)
+	}
+	for _, w := range spec.Warning {
+		log.Printf("[WARN] OVF import of '%s': %s", params.Source, w.LocalizedMessage)
+	}
+
+	// The import spec may have unitNumber==0 which is dropped as the field
+	// is optional in the wsdl but is required for certain devices
+	if importSpec, ok := spec.ImportSpec.(*types.VirtualMachineImportSpec); ok {
+		for _, d := range importSpec.ConfigSpec.DeviceChange {
+			n := &d.GetVirtualDeviceConfigSpec().Device.GetVirtualDevice().UnitNumber
+			if *n == 0 {
+				*n = -1
+			}
+		}
+	}
+
+	log.Printf("[DEBUG] Importing '%s' as '%s'", params.Source, cisp.EntityName)
+
+	lease, err := resourcePool.ImportVApp(ctx, spec.ImportSpec, folder, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// The lease is aborted with a context of its own as the import's
+	// context may have been cancelled
+	abort := func(err error) (*object.VirtualMachine, error) {
+		if abortErr := lease.HttpNfcLeaseAbort(context.Background(), nil); abortErr != nil {
+			log.Printf("[ERROR] Unable to abort the import of '%s': %s", params.Source, abortErr.Error())
+		}
+		if ctx.Err() != nil {
+			return nil, getStopError(fmt.Sprintf("import of '%s'", params.Source), longTaskTimeout)
+		}
+		return nil, err
+	}
+
+	info, err := lease.Wait(ctx)
+	if err != nil {
+		return abort(err)
+	}
+
+	var items []*ovfUploadItem
+
+	for _, device := range info.DeviceUrl {
+		for _, item := range spec.FileItem {
+			if device.ImportKey != item.DeviceId {
+				continue
+			}
+
+			u, err := client.Client.ParseURL(device.Url)
+			if err != nil {
+				return abort(err)
+			}
+
+			items = append(items, &ovfUploadItem{
+				url: u,
+				item: item,
+				ch: make(chan progress.Report),
+			} )
+		}
+	}
+
+	updater := newOvfLeaseUpdater(lease, items)
+
+	for _, item := range items {
+		err = uploadOvfItem(ctx, client, archive, item)
+		if err != nil {
+			updater.Done()
+			log.Printf("[ERROR] Unable to upload '%s' of '%s'", item.item.Path, params.Source)
+			return abort(err)
+		}
+	}
+	updater.Done()
+
+	if ctx.Err() != nil {
+		return abort(ctx.Err())
+	}
+	err = lease.HttpNfcLeaseComplete(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return object.NewVirtualMachine(client.Client, info.Entity), nil
+}
+
+// Returns the archive of the given source and the name of the OVF descriptor
+// in it. OVAs on a web server are downloaded to a temporary file first.
+func openOvfSource(source string) (ovfArchive, string, func(), error) {
+
+	cleanup := func() {}
+
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+
+		u, err := url.Parse(source)
+		if err != nil {
+			return nil, "", cleanup, err
+		}
+
+		if strings.ToLower(path.Ext(u.Path)) != ".ova" {
+			return &ovfURLArchive{u}, path.Base(u.Path), cleanup, nil
+		}
+
+		log.Printf("[DEBUG] Downloading OVA '%s'", source)
+
+		res, err := http.Get(source)
+		if err != nil {
+			return nil, "", cleanup, err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return nil, "", cleanup, fmt.Errorf("unable to download '%s': %s", source, res.Status)
+		}
+
+		f, err := ioutil.TempFile("", "terraform-vsphere-ova")
+		if err != nil {
+			return nil, "", cleanup, err
+		}
+		cleanup = func() {
+			os.Remove(f.Name())
+		}
+
+		_, err = io.Copy(f, res.Body)
+		f.Close()
+		if err != nil {
+			cleanup()
+			return nil, "", func() {}, err
+		}
+
+		return &ovfTapeArchive{f.Name()}, ovaDescriptorPattern(u.Path), cleanup, nil
+	}
+
+	if strings.ToLower(filepath.Ext(source)) == ".ova" {
+		return &ovfTapeArchive{source}, ovaDescriptorPattern(source), cleanup, nil
+	}
+	return &ovfFileArchive{source}, source, cleanup, nil
+}
+
+func ovaDescriptorPattern(source string) string {
+	return strings.TrimSuffix(path.Base(source), path.Ext(source)) + "*.ovf"
+}
+
+func uploadOvfItem(ctx context.Context, client *govmomi.Client, archive ovfArchive, item *ovfUploadItem) error {
+
+	f, size, err := archive.Open(item.item.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	opts := soap.Upload{
+		ContentLength: size,
+		Progress: item,
+	}
+
+	// Non-disk files such as ISOs use the PUT method
+	if item.item.Create {
+		opts.Method = "PUT"
+		opts.Headers = map[string]string{
+			"Overwrite": "t",
+		}
+	} else {
+		opts.Method = "POST"
+		opts.Type = "application/x-vnd.vmware-streamVmdk"
+	}
+
+	log.Printf("[DEBUG] Uploading '%s' (%d bytes)", item.item.Path, size)
+
+	// This client's uploads take no context so reading stops instead
+	return client.Client.Upload(&ovfContextReader{ctx, f}, item.url, &opts)
+}
+
+// Reads a file being uploaded until its context is done
+type ovfContextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *ovfContextReader) Read(p []byte) (int, error) {
+
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// A file of the OVF package that is uploaded to the lease's device url
+type ovfUploadItem struct {
+	url  *url.URL
+	item types.OvfFileItem
+	ch   chan progress.Report
+}
+
+func (i *ovfUploadItem) Sink() chan<- progress.Report {
+	return i.ch
+}
+
+// Keeps the lease alive while the files are uploaded
+// by periodically reporting the overall progress.
+type ovfLeaseUpdater struct {
+	lease *object.HttpNfcLease
+
+	pos   int64 // Number of bytes uploaded
+	total int64 // Total number of bytes
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newOvfLeaseUpdater(lease *object.HttpNfcLease, items []*ovfUploadItem) *ovfLeaseUpdater {
+
+	u := ovfLeaseUpdater{
+		lease: lease,
+		done: make(chan struct{}),
+	}
+
+	for _, item := range items {
+		u.total += item.item.Size
+		go u.waitForProgress(item)
+	}
+
+	u.wg.Add(1)
+	go u.run()
+
+	return &u
+}
+
+func (u *ovfLeaseUpdater) waitForProgress(item *ovfUploadItem) {
+
+	var (
+		pos int64
+		logged int
+	)
+
+	total := item.item.Size
+
+	for {
+		select {
+			case <-u.done:
+				return
+			case p, ok := <-item.ch:
+				if ok && p.Error() != nil {
+					return
+				}
+				if !ok {
+					atomic.AddInt64(&u.pos, total-pos)
+					log.Printf("[DEBUG] Uploaded '%s'", item.item.Path)
+					return
+				}
+
+				x := int64(float32(total) * (p.Percentage() / 100.0))
+				atomic.AddInt64(&u.pos, x-pos)
+				pos = x
+
+				if percent := int(p.Percentage()); percent >= logged+10 {
+					logged = percent - percent%10
+					log.Printf("[DEBUG] Uploading '%s': %d%%", item.item.Path, percent)
+				}
+		}
+	}
+}
+
+func (u *ovfLeaseUpdater) run() {
+
+	defer u.wg.Done()
+
+	tick := time.NewTicker(2 * time.Second)
+	defer tick.Stop()
+
+	for {
+		select {
+			case <-u.done:
+				return
+			case <-tick.C:
+				// Reporting the progress also renews the lease
+				var percent int
+				if u.total > 0 {
+					percent = int(float32(100*atomic.LoadInt64(&u.pos)) / float32(u.total))
+				}
+				if err := u.lease.HttpNfcLeaseProgress(context.Background(), percent); err != nil {
+					log.Printf("[WARN] Unable to update progress of OVF import lease: %s", err.Error())
+				}
+		}
+	}
+}
+
+func (u *ovfLeaseUpdater) Done() {
+	close(u.done)
+	u.wg.Wait()
+}
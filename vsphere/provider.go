@@ -10,6 +10,12 @@ import (
 	"github.com/vmware/govmomi"
 	"github.com/vmware/govmomi/find"
 	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/task"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
 )
 
 func Provider() terraform.ResourceProvider {
@@ -40,6 +46,21 @@ func Provider() terraform.ResourceProvider {
 			"vsphere_folder": resourceVsphereFolder(),
 			"vsphere_datastore": resourceVsphereDatastore(),
 			"vsphere_vm": resourceVsphereVM(),
+			"vsphere_drs_group": resourceVsphereDrsGroup(),
+			"vsphere_drs_vm_rule": resourceVsphereDrsVMRule(),
+			"vsphere_drs_vm_host_rule": resourceVsphereDrsVMHostRule(),
+			"vsphere_cluster_vm_override": resourceVsphereClusterVMOverride(),
+			"vsphere_vapp_container": resourceVsphereVAppContainer(),
+			"vsphere_vapp_entity": resourceVsphereVAppEntity(),
+			"vsphere_file": resourceVsphereFile(),
+			"vsphere_virtual_disk": resourceVsphereVirtualDisk(),
+			"vsphere_vm_guest_file": resourceVsphereVMGuestFile(),
+			"vsphere_custom_attribute": resourceVsphereCustomAttribute(),
+			"vsphere_role": resourceVsphereRole(),
+			"vsphere_entity_permission": resourceVsphereEntityPermission(),
+			"vsphere_alarm": resourceVsphereAlarm(),
+			"vsphere_datastore_cluster": resourceVsphereDatastoreCluster(),
+			"vsphere_template": resourceVsphereTemplate(),
 		},
 		ConfigureFunc: providerConfigure,
 	}
@@ -47,6 +68,8 @@ func Provider() terraform.ResourceProvider {
 
 func providerConfigure(d *schema.ResourceData) (interface{}, error) {
 	
+	handleStop()
+	
 	config := Config{
 		Host: d.Get("host").(string),
 		Username: d.Get("username").(string),
@@ -81,3 +104,161 @@ func getFinder(d *schema.ResourceData, meta interface{}) (*find.Finder, *object.
 	
 	return finder, datacenter, nil
 }
+
+func getObjectName(ctx context.Context, client *vim25.Client, ref types.ManagedObjectReference) (string, error) {
+	
+	req := types.RetrieveProperties{
+		SpecSet: []types.PropertyFilterSpec{
+			types.PropertyFilterSpec{
+				ObjectSet: []types.ObjectSpec{
+					types.ObjectSpec{ Obj: ref },
+				},
+				PropSet: []types.PropertySpec{
+					types.PropertySpec{ Type: ref.Type, PathSet: []string{"name"} },
+				},
+			},
+		},
+	}
+	
+	res, err := property.DefaultCollector(client).RetrieveProperties(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	for _, oc := range res.Returnval {
+		for _, p := range oc.PropSet {
+			if p.Name == "name" {
+				return p.Val.(string), nil
+			}
+		}
+	}
+	
+	return "", fmt.Errorf("name of managed object '%s' not found", ref.Value)
+}
+
+func getObjectNames(ctx context.Context, client *vim25.Client, refs []types.ManagedObjectReference) ([]interface{}, error) {
+	
+	names := []interface{}{}
+	
+	for _, ref := range refs {
+		name, err := getObjectName(ctx, client, ref)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	
+	return names, nil
+}
+
+
+// Returns the reference of the managed object of the given type with the
+// given id or nil if no such managed object exists.
+func findObjectReference(ctx context.Context, client *vim25.Client, refType string, id string) (*types.ManagedObjectReference, error) {
+	
+	ref := types.ManagedObjectReference{ Type: refType, Value: id }
+	
+	_, err := getObjectName(ctx, client, ref)
+	if err != nil {
+		if isManagedObjectNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	
+	return &ref, nil
+}
+
+// Types of the managed entities that can be referenced by their object_id
+var managedEntityTypes = []string{
+	"Folder",
+	"Datacenter",
+	"ClusterComputeResource",
+	"ComputeResource",
+	"HostSystem",
+	"ResourcePool",
+	"VirtualApp",
+	"VirtualMachine",
+	"Datastore",
+	"StoragePod",
+	"Network",
+	"DistributedVirtualPortgroup",
+	"VmwareDistributedVirtualSwitch",
+}
+
+// Returns the reference of the managed entity of any type with the given
+// id or nil if no such entity exists.
+func findEntityReference(ctx context.Context, client *vim25.Client, id string) (*types.ManagedObjectReference, error) {
+	
+	for _, refType := range managedEntityTypes {
+		ref, err := findObjectReference(ctx, client, refType, id)
+		if err != nil || ref != nil {
+			return ref, err
+		}
+	}
+	return nil, nil
+}
+
+func isManagedObjectNotFound(err error) bool {
+	
+	switch getVimFault(err).(type) {
+		case types.ManagedObjectNotFound, *types.ManagedObjectNotFound:
+			return true
+	}
+	return false
+}
+
+func isNotFound(err error) bool {
+	
+	switch getVimFault(err).(type) {
+		case types.NotFound, *types.NotFound:
+			return true
+	}
+	return false
+}
+
+func isFileAlreadyExists(err error) bool {
+	
+	switch getVimFault(err).(type) {
+		case types.FileAlreadyExists, *types.FileAlreadyExists:
+			return true
+	}
+	return false
+}
+
+func isFileNotFound(err error) bool {
+	
+	switch getVimFault(err).(type) {
+		case types.FileNotFound, *types.FileNotFound:
+			return true
+	}
+	return false
+}
+
+// Returns the vim fault carried by a SOAP or task error if any.
+func getVimFault(err error) types.AnyType {
+	
+	if taskErr, ok := err.(task.Error); ok {
+		return taskErr.Fault()
+	} else if soap.IsSoapFault(err) {
+		return soap.ToSoapFault(err).VimFault()
+	} else if soap.IsVimFault(err) {
+		return soap.ToVimFault(err)
+	}
+	return nil
+}
+
+func renameObject(ctx context.Context, client *vim25.Client, ref types.ManagedObjectReference, name string) error {
+	
+	req := types.Rename_Task{
+		This: ref,
+		NewName: name,
+	}
+	
+	res, err := methods.Rename_Task(ctx, client, &req)
+	if err != nil {
+		return err
+	}
+	
+	_, err = waitForTask(client, object.NewTask(client, res.Returnval), fmt.Sprintf("rename '%s' to '%s'", ref.Value, name), defaultTaskTimeout)
+	return err
+}
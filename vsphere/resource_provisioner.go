@@ -0,0 +1,187 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/mitchellh/mapstructure"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+)
+
+// Provisioner returns the vsphere_guest provisioner which uploads files to
+// and runs commands in the guest of a VM through VMware Tools. Unlike the
+// ssh and winrm based provisioners it does not need network access to the
+// guest.
+func Provisioner() terraform.ResourceProvisioner {
+	return &guestProvisioner{}
+}
+
+type guestProvisioner struct{}
+
+// Configuration of the vsphere_guest provisioner. The vCenter credentials
+// default to the same environment variables as the provider's.
+type guestProvisionerConfig struct {
+	Host string `mapstructure:"host"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	DatacenterID string `mapstructure:"datacenter_id"`
+	VMName string `mapstructure:"vm_name"` // Defaults to the ID of the vsphere_vm resource
+	Upload []guestProvisionerUpload `mapstructure:"upload"`
+	Inline []string `mapstructure:"inline"`
+	Shell string `mapstructure:"shell"` // Defaults to cmd.exe for Windows guests and /bin/sh for others
+	WorkingDirectory string `mapstructure:"working_directory"`
+	Timeout int `mapstructure:"timeout"` // Seconds allowed for each command, 0 for no limit
+}
+
+type guestProvisionerUpload struct {
+	Source string `mapstructure:"source"` // Local path
+	Destination string `mapstructure:"destination"` // Path in the guest
+}
+
+func (p *guestProvisioner) Validate(c *terraform.ResourceConfig) ([]string, []error) {
+
+	config, err := decodeGuestProvisionerConfig(c)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	var errs []error
+
+	for i, u := range config.Upload {
+		if u.Source == "" || u.Destination == "" {
+			errs = append(errs, fmt.Errorf("upload.%d requires both a source and a destination", i))
+		}
+	}
+	if len(config.Upload) == 0 && len(config.Inline) == 0 {
+		errs = append(errs, fmt.Errorf("at least one upload or inline command must be given"))
+	}
+	if config.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("timeout cannot be negative"))
+	}
+
+	return nil, errs
+}
+
+func (p *guestProvisioner) Apply(o terraform.UIOutput, s *terraform.InstanceState, c *terraform.ResourceConfig) error {
+
+	config, err := decodeGuestProvisionerConfig(c)
+	if err != nil {
+		return err
+	}
+	if config.VMName == "" {
+		config.VMName = s.ID
+	}
+
+	var username, password string
+	if s.Ephemeral.ConnInfo != nil {
+		username = s.Ephemeral.ConnInfo["user"]
+		password = s.Ephemeral.ConnInfo["password"]
+	}
+
+	client, err := (&Config{
+		Host: config.Host,
+		Username: config.Username,
+		Password: config.Password,
+	}).Client()
+	if err != nil {
+		return err
+	}
+	defer client.Logout(context.Background())
+
+	vm, err := findGuestProvisionerVM(client, config)
+	if err != nil {
+		return err
+	}
+
+	session, err := newGuestSession(context.Background(), client, vm, username, password)
+	if err != nil {
+		return err
+	}
+
+	for _, u := range config.Upload {
+
+		o.Output(fmt.Sprintf("Uploading %s to %s", u.Source, u.Destination))
+
+		err = session.uploadFile(context.Background(), u.Source, u.Destination)
+		if err != nil {
+			log.Printf("[ERROR] Unable to upload '%s' to the guest of VM '%s'", u.Source, config.VMName)
+			return err
+		}
+	}
+
+	for _, command := range config.Inline {
+
+		o.Output(fmt.Sprintf("Running: %s", command))
+
+		var (
+			ctx context.Context
+			cancel context.CancelFunc
+		)
+		if config.Timeout > 0 {
+			ctx, cancel = context.WithTimeout(context.Background(), time.Duration(config.Timeout) * time.Second)
+		} else {
+			ctx, cancel = context.WithCancel(context.Background())
+		}
+		err = session.run(ctx, config.Shell, command, config.WorkingDirectory, o.Output)
+		cancel()
+
+		if err != nil {
+			log.Printf("[ERROR] Command failed in the guest of VM '%s'", config.VMName)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func decodeGuestProvisionerConfig(c *terraform.ResourceConfig) (*guestProvisionerConfig, error) {
+
+	config := &guestProvisionerConfig{
+		Host: os.Getenv("VSPHERE_HOST"),
+		Username: os.Getenv("VSPHERE_USERNAME"),
+		Password: os.Getenv("VSPHERE_PASSWORD"),
+		Timeout: 300,
+	}
+
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		ErrorUnused: true,
+		WeaklyTypedInput: true,
+		Result: config,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := decoder.Decode(c.Config); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+func findGuestProvisionerVM(client *govmomi.Client, config *guestProvisionerConfig) (*object.VirtualMachine, error) {
+
+	var (
+		datacenter *object.Datacenter
+		err error
+	)
+
+	finder := find.NewFinder(client.Client, false)
+	if config.DatacenterID != "" {
+		datacenter, err = finder.Datacenter(context.Background(), config.DatacenterID)
+	} else {
+		datacenter, err = finder.DefaultDatacenter(context.Background())
+	}
+	if err != nil {
+		return nil, err
+	}
+	finder.SetDatacenter(datacenter)
+
+	return findGuestVM(context.Background(), client, finder, datacenter, config.VMName)
+}
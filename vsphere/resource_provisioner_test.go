@@ -0,0 +1,95 @@
+package vsphere
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestProvisioner_impl(t *testing.T) {
+	var _ terraform.ResourceProvisioner = Provisioner()
+}
+
+func TestProvisionerValidate(t *testing.T) {
+
+	c := testProvisionerConfig(t, map[string]interface{}{
+		"vm_name": "vm1",
+		"upload": []map[string]interface{}{
+			map[string]interface{}{ "source": "setup.sh", "destination": "/tmp/setup.sh" },
+		},
+		"inline": []interface{}{ "chmod +x /tmp/setup.sh", "/tmp/setup.sh" },
+		"timeout": "60",
+	})
+	warn, errs := Provisioner().Validate(c)
+	if len(warn) > 0 {
+		t.Fatalf("unexpected warnings: %v", warn)
+	}
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	c = testProvisionerConfig(t, map[string]interface{}{
+		"upload": []map[string]interface{}{
+			map[string]interface{}{ "source": "setup.sh" },
+		},
+	})
+	if _, errs = Provisioner().Validate(c); len(errs) != 1 {
+		t.Fatalf("expected an error for an upload without a destination but got: %v", errs)
+	}
+
+	c = testProvisionerConfig(t, map[string]interface{}{ "vm_name": "vm1" })
+	if _, errs = Provisioner().Validate(c); len(errs) != 1 {
+		t.Fatalf("expected an error for nothing to provision but got: %v", errs)
+	}
+
+	c = testProvisionerConfig(t, map[string]interface{}{ "inline": []interface{}{ "ls" }, "script": "ls" })
+	if _, errs = Provisioner().Validate(c); len(errs) != 1 {
+		t.Fatalf("expected an error for an unknown argument but got: %v", errs)
+	}
+}
+
+func TestGuestCommandArguments(t *testing.T) {
+
+	args := getGuestCommandArguments("echo 'hello' && ls", "/tmp/terraform-1.out", false)
+	expected := `-c '(echo '\''hello'\'' && ls) > '\''/tmp/terraform-1.out'\'' 2>&1'`
+
+	if args != expected {
+		t.Fatalf("expected arguments %s but got %s", expected, args)
+	}
+
+	args = getGuestCommandArguments(`echo "hello" && dir`, `C:\Temp\terraform-1.out`, true)
+	expected = `/S /C "(echo "hello" && dir) > "C:\Temp\terraform-1.out" 2>&1"`
+
+	if args != expected {
+		t.Fatalf("expected arguments %s but got %s", expected, args)
+	}
+}
+
+func TestWindowsGuest(t *testing.T) {
+
+	tests := []struct {
+		guestFamily, guestID string
+		expected bool
+	}{
+		{ "windowsGuest", "otherGuest64", true },
+		{ "linuxGuest", "windows8Server64Guest", false },
+		{ "", "windows8Server64Guest", true },
+		{ "", "ubuntu64Guest", false },
+	}
+
+	for i, test := range tests {
+		if actual := isWindowsGuest(test.guestFamily, test.guestID); actual != test.expected {
+			t.Fatalf("test %d: expected %v but got %v", i, test.expected, actual)
+		}
+	}
+}
+
+func testProvisionerConfig(t *testing.T, c map[string]interface{}) *terraform.ResourceConfig {
+
+	r, err := config.NewRawConfig(c)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	return terraform.NewResourceConfig(r)
+}
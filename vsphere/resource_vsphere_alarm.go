@@ -0,0 +1,774 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func resourceVsphereAlarm() *schema.Resource {
+
+	return &schema.Resource{
+
+		Create: resourceVsphereAlarmCreate,
+		Read:   resourceVsphereAlarmRead,
+		Update: resourceVsphereAlarmUpdate,
+		Delete: resourceVsphereAlarmDelete,
+
+		Schema: map[string]*schema.Schema{
+
+			"name": &schema.Schema{
+				Type: schema.TypeString,
+				Required: true,
+			},
+			"description": &schema.Schema{
+				Type: schema.TypeString,
+				Optional: true,
+			},
+			"entity_id": &schema.Schema{
+				Type: schema.TypeString, // object_id of the datacenter, cluster, host, folder or other entity the alarm is defined on
+				Required: true,
+				ForceNew: true,
+			},
+			"enabled": &schema.Schema{
+				Type: schema.TypeBool,
+				Optional: true,
+				Default: true,
+			},
+			"expression_operator": &schema.Schema{
+				Type: schema.TypeString, // One of 'or' or 'and'. How the expressions below are combined to trigger the alarm.
+				Optional: true,
+				Default: "or",
+			},
+			"metric_expression": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"object_type": &schema.Schema{
+							Type: schema.TypeString, // Type of the monitored objects such as VirtualMachine, HostSystem or Datastore
+							Required: true,
+						},
+						"metric": &schema.Schema{
+							Type: schema.TypeString, // Performance counter as group.name.rollup such as cpu.ready.summation or the counter id
+							Required: true,
+						},
+						"instance": &schema.Schema{
+							Type: schema.TypeString, // Instance of the counter. Empty for the aggregate.
+							Optional: true,
+							Default: "",
+						},
+						"operator": &schema.Schema{
+							Type: schema.TypeString, // One of isAbove or isBelow
+							Optional: true,
+							Default: "isAbove",
+						},
+						"yellow": &schema.Schema{
+							Type: schema.TypeInt, // Warning threshold. Percentages are given in hundredths of a percent.
+							Optional: true,
+						},
+						"yellow_interval": &schema.Schema{
+							Type: schema.TypeInt, // Seconds the warning threshold must be exceeded
+							Optional: true,
+						},
+						"red": &schema.Schema{
+							Type: schema.TypeInt, // Alert threshold. Percentages are given in hundredths of a percent.
+							Optional: true,
+						},
+						"red_interval": &schema.Schema{
+							Type: schema.TypeInt, // Seconds the alert threshold must be exceeded
+							Optional: true,
+						},
+					},
+				},
+			},
+			"event_expression": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"event_type": &schema.Schema{
+							Type: schema.TypeString, // Event class such as HostConnectionLostEvent or EventEx
+							Required: true,
+						},
+						"event_type_id": &schema.Schema{
+							Type: schema.TypeString, // Id of an EventEx or ExtendedEvent such as esx.problem.scsi.device.state.off
+							Optional: true,
+						},
+						"object_type": &schema.Schema{
+							Type: schema.TypeString, // Type of the object the event is raised on such as HostSystem
+							Optional: true,
+						},
+						"status": &schema.Schema{
+							Type: schema.TypeString, // Alarm status the event triggers. One of green, yellow or red.
+							Optional: true,
+							Default: "red",
+						},
+						"comparison": &schema.Schema{
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"attribute": &schema.Schema{
+										Type: schema.TypeString,
+										Required: true,
+									},
+									"operator": &schema.Schema{
+										Type: schema.TypeString, // One of equals, notEqualTo, startsWith, doesNotStartWith, endsWith or doesNotEndWith
+										Optional: true,
+										Default: "equals",
+									},
+									"value": &schema.Schema{
+										Type: schema.TypeString,
+										Required: true,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"state_expression": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"object_type": &schema.Schema{
+							Type: schema.TypeString, // Type of the monitored objects such as HostSystem or VirtualMachine
+							Required: true,
+						},
+						"state_path": &schema.Schema{
+							Type: schema.TypeString, // Property path such as runtime.connectionState or runtime.powerState
+							Required: true,
+						},
+						"operator": &schema.Schema{
+							Type: schema.TypeString, // One of isEqual or isUnequal
+							Optional: true,
+							Default: "isEqual",
+						},
+						"yellow": &schema.Schema{
+							Type: schema.TypeString,
+							Optional: true,
+						},
+						"red": &schema.Schema{
+							Type: schema.TypeString,
+							Optional: true,
+						},
+					},
+				},
+			},
+			"email_action": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: alarmActionSchema(map[string]*schema.Schema{
+						"to": &schema.Schema{
+							Type: schema.TypeString, // Comma separated list of addresses
+							Required: true,
+						},
+						"cc": &schema.Schema{
+							Type: schema.TypeString,
+							Optional: true,
+						},
+						"subject": &schema.Schema{
+							Type: schema.TypeString,
+							Optional: true,
+						},
+						"body": &schema.Schema{
+							Type: schema.TypeString,
+							Optional: true,
+						},
+					}),
+				},
+			},
+			"snmp_action": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: alarmActionSchema(map[string]*schema.Schema{}),
+				},
+			},
+			"script_action": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: alarmActionSchema(map[string]*schema.Schema{
+						"script": &schema.Schema{
+							Type: schema.TypeString, // Command run on the vCenter server
+							Required: true,
+						},
+					}),
+				},
+			},
+			"action_frequency": &schema.Schema{
+				Type: schema.TypeInt, // Seconds between repeated actions
+				Optional: true,
+			},
+			"tolerance_range": &schema.Schema{
+				Type: schema.TypeInt, // Hundredths of a percent a metric must fall below the threshold to clear the alarm
+				Optional: true,
+			},
+			"reporting_frequency": &schema.Schema{
+				Type: schema.TypeInt, // Minimum seconds between alarm status changes
+				Optional: true,
+			},
+			"keep": &schema.Schema{
+				Type: schema.TypeBool,
+				Optional: true,
+			},
+		},
+	}
+}
+
+// Adds the state transitions that fire an alarm action to the schema of the
+// action.
+func alarmActionSchema(action map[string]*schema.Schema) map[string]*schema.Schema {
+
+	action["green_to_yellow"] = &schema.Schema{
+		Type: schema.TypeBool,
+		Optional: true,
+		Default: false,
+	}
+	action["yellow_to_red"] = &schema.Schema{
+		Type: schema.TypeBool,
+		Optional: true,
+		Default: true,
+	}
+	action["red_to_yellow"] = &schema.Schema{
+		Type: schema.TypeBool,
+		Optional: true,
+		Default: false,
+	}
+	action["yellow_to_green"] = &schema.Schema{
+		Type: schema.TypeBool,
+		Optional: true,
+		Default: false,
+	}
+	action["repeat"] = &schema.Schema{
+		Type: schema.TypeBool, // Repeat the action every action_frequency seconds while the alarm stays yellow or red
+		Optional: true,
+		Default: false,
+	}
+	return action
+}
+
+func resourceVsphereAlarmCreate(d *schema.ResourceData, meta interface{}) error {
+
+	client := meta.(*govmomi.Client)
+
+	entity, err := findEntityReference(context.Background(), client.Client, d.Get("entity_id").(string))
+	if err != nil {
+		return err
+	}
+	if entity == nil {
+		return fmt.Errorf("entity '%s' was not found", d.Get("entity_id").(string))
+	}
+
+	spec, err := getAlarmSpec(d, meta)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[DEBUG] Creating alarm '%s' on '%s'", spec.Name, entity.Value)
+
+	req := types.CreateAlarm{
+		This: *client.Client.ServiceContent.AlarmManager,
+		Entity: *entity,
+		Spec: spec,
+	}
+	res, err := methods.CreateAlarm(context.Background(), client.Client, &req)
+	if err != nil {
+		log.Printf("[ERROR] Unable to create alarm '%s'", spec.Name)
+		return err
+	}
+
+	d.SetId(res.Returnval.Value)
+	return resourceVsphereAlarmRead(d, meta)
+}
+
+func resourceVsphereAlarmRead(d *schema.ResourceData, meta interface{}) error {
+
+	client := meta.(*govmomi.Client)
+
+	var alarm mo.Alarm
+
+	err := property.DefaultCollector(client.Client).RetrieveOne(context.Background(), alarmReference(d), []string{"info"}, &alarm)
+	if err != nil {
+		if isManagedObjectNotFound(err) {
+			log.Printf("[DEBUG] Alarm '%s' no longer exists", d.Id())
+			d.SetId("")
+			return nil
+		}
+		return err
+	}
+	info := alarm.Info
+
+	counters, err := getPerfCounterNames(context.Background(), client.Client)
+	if err != nil {
+		log.Printf("[ERROR] Unable to read performance counters for alarm '%s'", d.Id())
+		return err
+	}
+
+	operator, metrics, events, states := putAlarmExpressions(info.Expression, counters)
+	emails, snmps, scripts := putAlarmActions(info.Action)
+
+	d.Set("name", info.Name)
+	d.Set("description", info.Description)
+	d.Set("enabled", info.Enabled)
+	d.Set("expression_operator", operator)
+	d.Set("metric_expression", metrics)
+	d.Set("event_expression", events)
+	d.Set("state_expression", states)
+	d.Set("email_action", emails)
+	d.Set("snmp_action", snmps)
+	d.Set("script_action", scripts)
+	d.Set("action_frequency", info.ActionFrequency)
+
+	if info.Setting != nil {
+		d.Set("tolerance_range", info.Setting.ToleranceRange)
+		d.Set("reporting_frequency", info.Setting.ReportingFrequency)
+	} else {
+		d.Set("tolerance_range", 0)
+		d.Set("reporting_frequency", 0)
+	}
+	return nil
+}
+
+func resourceVsphereAlarmUpdate(d *schema.ResourceData, meta interface{}) error {
+
+	client := meta.(*govmomi.Client)
+
+	spec, err := getAlarmSpec(d, meta)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[DEBUG] Reconfiguring alarm: %s", d.Id())
+
+	req := types.ReconfigureAlarm{
+		This: alarmReference(d),
+		Spec: spec,
+	}
+	_, err = methods.ReconfigureAlarm(context.Background(), client.Client, &req)
+	if err != nil {
+		log.Printf("[ERROR] Unable to reconfigure alarm '%s'", d.Id())
+		return err
+	}
+
+	return resourceVsphereAlarmRead(d, meta)
+}
+
+func resourceVsphereAlarmDelete(d *schema.ResourceData, meta interface{}) error {
+
+	if keep, ok := d.GetOk("keep"); !ok || !keep.(bool) {
+
+		client := meta.(*govmomi.Client)
+
+		log.Printf("[DEBUG] Deleting alarm: %s", d.Id())
+
+		req := types.RemoveAlarm{
+			This: alarmReference(d),
+		}
+		_, err := methods.RemoveAlarm(context.Background(), client.Client, &req)
+		if err != nil && !isManagedObjectNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func alarmReference(d *schema.ResourceData) types.ManagedObjectReference {
+	return types.ManagedObjectReference{ Type: "Alarm", Value: d.Id() }
+}
+
+func getAlarmSpec(d *schema.ResourceData, meta interface{}) (*types.AlarmSpec, error) {
+
+	counters, err := getPerfCounterIds(context.Background(), meta.(*govmomi.Client).Client)
+	if err != nil {
+		return nil, err
+	}
+
+	expression, err := getAlarmExpression(
+		d.Get("expression_operator").(string),
+		d.Get("metric_expression").([]interface{}),
+		d.Get("event_expression").([]interface{}),
+		d.Get("state_expression").([]interface{}),
+		counters)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := &types.AlarmSpec{
+		Name: d.Get("name").(string),
+		Description: d.Get("description").(string),
+		Enabled: d.Get("enabled").(bool),
+		Expression: expression,
+		Action: getAlarmAction(
+			d.Get("email_action").([]interface{}),
+			d.Get("snmp_action").([]interface{}),
+			d.Get("script_action").([]interface{})),
+		ActionFrequency: d.Get("action_frequency").(int),
+	}
+
+	toleranceRange := d.Get("tolerance_range").(int)
+	reportingFrequency := d.Get("reporting_frequency").(int)
+	if toleranceRange > 0 || reportingFrequency > 0 {
+		spec.Setting = &types.AlarmSetting{
+			ToleranceRange: toleranceRange,
+			ReportingFrequency: reportingFrequency,
+		}
+	}
+	return spec, nil
+}
+
+// Returns the configured expressions combined with the given operator.
+func getAlarmExpression(operator string, metrics, events, states []interface{}, counters map[string]int) (types.BaseAlarmExpression, error) {
+
+	var expressions []types.BaseAlarmExpression
+
+	for _, m := range metrics {
+		metric := m.(map[string]interface{})
+
+		name := metric["metric"].(string)
+		counterID, ok := counters[name]
+		if !ok {
+			id, err := strconv.Atoi(name)
+			if err != nil {
+				return nil, fmt.Errorf("performance counter '%s' was not found", name)
+			}
+			counterID = id
+		}
+
+		expressions = append(expressions, &types.MetricAlarmExpression{
+			Operator: types.MetricAlarmOperator(metric["operator"].(string)),
+			Type: metric["object_type"].(string),
+			Metric: types.PerfMetricId{
+				CounterId: counterID,
+				Instance: metric["instance"].(string),
+			},
+			Yellow: metric["yellow"].(int),
+			YellowInterval: metric["yellow_interval"].(int),
+			Red: metric["red"].(int),
+			RedInterval: metric["red_interval"].(int),
+		})
+	}
+
+	for _, e := range events {
+		event := e.(map[string]interface{})
+
+		var comparisons []types.EventAlarmExpressionComparison
+		for _, c := range event["comparison"].([]interface{}) {
+			comparison := c.(map[string]interface{})
+			comparisons = append(comparisons, types.EventAlarmExpressionComparison{
+				AttributeName: comparison["attribute"].(string),
+				Operator: comparison["operator"].(string),
+				Value: comparison["value"].(string),
+			})
+		}
+
+		expressions = append(expressions, &types.EventAlarmExpression{
+			Comparisons: comparisons,
+			EventType: event["event_type"].(string),
+			EventTypeId: event["event_type_id"].(string),
+			ObjectType: event["object_type"].(string),
+			Status: types.ManagedEntityStatus(event["status"].(string)),
+		})
+	}
+
+	for _, s := range states {
+		state := s.(map[string]interface{})
+
+		expressions = append(expressions, &types.StateAlarmExpression{
+			Operator: types.StateAlarmOperator(state["operator"].(string)),
+			Type: state["object_type"].(string),
+			StatePath: state["state_path"].(string),
+			Yellow: state["yellow"].(string),
+			Red: state["red"].(string),
+		})
+	}
+
+	if len(expressions) == 0 {
+		return nil, fmt.Errorf("an alarm requires at least one metric, event or state expression")
+	}
+
+	switch operator {
+		case "or":
+			return &types.OrAlarmExpression{ Expression: expressions }, nil
+		case "and":
+			return &types.AndAlarmExpression{ Expression: expressions }, nil
+	}
+	return nil, fmt.Errorf("invalid expression operator '%s'. It must be one of 'or' or 'and'", operator)
+}
+
+// Returns the expressions of an alarm as they are stored in the state.
+// Expressions nested deeper than the top level or/and expression, which the
+// vSphere client can create, are not supported and are left out.
+func putAlarmExpressions(expression types.BaseAlarmExpression, counters map[int]string) (string, []map[string]interface{}, []map[string]interface{}, []map[string]interface{}) {
+
+	operator := "or"
+	expressions := []types.BaseAlarmExpression{ expression }
+
+	switch e := expression.(type) {
+		case *types.OrAlarmExpression:
+			expressions = e.Expression
+		case *types.AndAlarmExpression:
+			operator = "and"
+			expressions = e.Expression
+	}
+
+	metrics := make([]map[string]interface{}, 0)
+	events := make([]map[string]interface{}, 0)
+	states := make([]map[string]interface{}, 0)
+
+	for _, expression := range expressions {
+
+		switch e := expression.(type) {
+
+			case *types.MetricAlarmExpression:
+				metric, ok := counters[e.Metric.CounterId]
+				if !ok {
+					metric = strconv.Itoa(e.Metric.CounterId)
+				}
+				metrics = append(metrics, map[string]interface{}{
+					"object_type": e.Type,
+					"metric": metric,
+					"instance": e.Metric.Instance,
+					"operator": string(e.Operator),
+					"yellow": e.Yellow,
+					"yellow_interval": e.YellowInterval,
+					"red": e.Red,
+					"red_interval": e.RedInterval,
+				})
+
+			case *types.EventAlarmExpression:
+				comparisons := make([]map[string]interface{}, 0, len(e.Comparisons))
+				for _, c := range e.Comparisons {
+					comparisons = append(comparisons, map[string]interface{}{
+						"attribute": c.AttributeName,
+						"operator": c.Operator,
+						"value": c.Value,
+					})
+				}
+				events = append(events, map[string]interface{}{
+					"event_type": e.EventType,
+					"event_type_id": e.EventTypeId,
+					"object_type": e.ObjectType,
+					"status": string(e.Status),
+					"comparison": comparisons,
+				})
+
+			case *types.StateAlarmExpression:
+				states = append(states, map[string]interface{}{
+					"object_type": e.Type,
+					"state_path": e.StatePath,
+					"operator": string(e.Operator),
+					"yellow": e.Yellow,
+					"red": e.Red,
+				})
+
+			default:
+				log.Printf("[DEBUG] Ignoring unsupported alarm expression: %T", expression)
+		}
+	}
+
+	return operator, metrics, events, states
+}
+
+// Returns the configured actions as a group of triggering actions or nil if
+// no actions are configured.
+func getAlarmAction(emails, snmps, scripts []interface{}) types.BaseAlarmAction {
+
+	var actions []types.BaseAlarmAction
+
+	for _, e := range emails {
+		email := e.(map[string]interface{})
+		actions = append(actions, getAlarmTriggeringAction(email, &types.SendEmailAction{
+			ToList: email["to"].(string),
+			CcList: email["cc"].(string),
+			Subject: email["subject"].(string),
+			Body: email["body"].(string),
+		}))
+	}
+	for _, s := range snmps {
+		actions = append(actions, getAlarmTriggeringAction(s.(map[string]interface{}), &types.SendSNMPAction{}))
+	}
+	for _, s := range scripts {
+		script := s.(map[string]interface{})
+		actions = append(actions, getAlarmTriggeringAction(script, &types.RunScriptAction{
+			Script: script["script"].(string),
+		}))
+	}
+
+	if len(actions) == 0 {
+		return nil
+	}
+	return &types.GroupAlarmAction{ Action: actions }
+}
+
+// Alarm state transitions in the order of the action schema
+var alarmTransitions = []struct {
+	name string
+	start types.ManagedEntityStatus
+	final types.ManagedEntityStatus
+}{
+	{ "green_to_yellow", types.ManagedEntityStatusGreen, types.ManagedEntityStatusYellow },
+	{ "yellow_to_red", types.ManagedEntityStatusYellow, types.ManagedEntityStatusRed },
+	{ "red_to_yellow", types.ManagedEntityStatusRed, types.ManagedEntityStatusYellow },
+	{ "yellow_to_green", types.ManagedEntityStatusYellow, types.ManagedEntityStatusGreen },
+}
+
+func getAlarmTriggeringAction(config map[string]interface{}, action types.BaseAction) types.BaseAlarmAction {
+
+	repeat := config["repeat"].(bool)
+
+	var specs []types.AlarmTriggeringActionTransitionSpec
+	for _, t := range alarmTransitions {
+		if config[t.name].(bool) {
+			specs = append(specs, types.AlarmTriggeringActionTransitionSpec{
+				StartState: t.start,
+				FinalState: t.final,
+				// vCenter only repeats actions while an alarm is in a warning or alert state
+				Repeats: repeat && t.final != types.ManagedEntityStatusGreen,
+			})
+		}
+	}
+
+	return &types.AlarmTriggeringAction{
+		Action: action,
+		TransitionSpecs: specs,
+	}
+}
+
+// Returns the email, SNMP and script actions of an alarm as they are stored
+// in the state.
+func putAlarmActions(action types.BaseAlarmAction) ([]map[string]interface{}, []map[string]interface{}, []map[string]interface{}) {
+
+	emails := make([]map[string]interface{}, 0)
+	snmps := make([]map[string]interface{}, 0)
+	scripts := make([]map[string]interface{}, 0)
+
+	var actions []types.BaseAlarmAction
+	switch a := action.(type) {
+		case *types.GroupAlarmAction:
+			actions = a.Action
+		case nil:
+		default:
+			actions = []types.BaseAlarmAction{ action }
+	}
+
+	for _, a := range actions {
+
+		triggering, ok := a.(*types.AlarmTriggeringAction)
+		if !ok {
+			log.Printf("[DEBUG] Ignoring unsupported alarm action: %T", a)
+			continue
+		}
+
+		config := putAlarmTransitions(triggering)
+
+		switch t := triggering.Action.(type) {
+			case *types.SendEmailAction:
+				config["to"] = t.ToList
+				config["cc"] = t.CcList
+				config["subject"] = t.Subject
+				config["body"] = t.Body
+				emails = append(emails, config)
+			case *types.SendSNMPAction:
+				snmps = append(snmps, config)
+			case *types.RunScriptAction:
+				config["script"] = t.Script
+				scripts = append(scripts, config)
+			default:
+				log.Printf("[DEBUG] Ignoring unsupported alarm action: %T", triggering.Action)
+		}
+	}
+
+	return emails, snmps, scripts
+}
+
+func putAlarmTransitions(action *types.AlarmTriggeringAction) map[string]interface{} {
+
+	config := map[string]interface{}{
+		"green_to_yellow": action.Green2yellow,
+		"yellow_to_red": action.Yellow2red,
+		"red_to_yellow": action.Red2yellow,
+		"yellow_to_green": action.Yellow2green,
+		"repeat": false,
+	}
+
+	// Alarms created by older clients only set the deprecated transition flags
+	if len(action.TransitionSpecs) > 0 {
+		for _, t := range alarmTransitions {
+			config[t.name] = false
+		}
+		for _, spec := range action.TransitionSpecs {
+			for _, t := range alarmTransitions {
+				if spec.StartState == t.start && spec.FinalState == t.final {
+					config[t.name] = true
+				}
+			}
+			if spec.Repeats {
+				config["repeat"] = true
+			}
+		}
+	}
+	return config
+}
+
+func getPerfCounters(ctx context.Context, client *vim25.Client) ([]types.PerfCounterInfo, error) {
+
+	var m mo.PerformanceManager
+
+	err := property.DefaultCollector(client).RetrieveOne(ctx, *client.ServiceContent.PerfManager, []string{"perfCounter"}, &m)
+	if err != nil {
+		return nil, err
+	}
+	return m.PerfCounter, nil
+}
+
+func getPerfCounterName(counter types.PerfCounterInfo) string {
+	return fmt.Sprintf("%s.%s.%s",
+		counter.GroupInfo.GetElementDescription().Key,
+		counter.NameInfo.GetElementDescription().Key,
+		counter.RollupType)
+}
+
+// Returns the ids of the performance counters by their group.name.rollup name.
+func getPerfCounterIds(ctx context.Context, client *vim25.Client) (map[string]int, error) {
+
+	counters, err := getPerfCounters(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]int)
+	for _, counter := range counters {
+		ids[getPerfCounterName(counter)] = counter.Key
+	}
+	return ids, nil
+}
+
+// Returns the group.name.rollup names of the performance counters by their id.
+func getPerfCounterNames(ctx context.Context, client *vim25.Client) (map[int]string, error) {
+
+	counters, err := getPerfCounters(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[int]string)
+	for _, counter := range counters {
+		names[counter.Key] = getPerfCounterName(counter)
+	}
+	return names, nil
+}
@@ -0,0 +1,249 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/kr/pretty"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestAccVsphereAlarm_normal(t *testing.T) {
+
+	_, filename, _, _ := runtime.Caller(0)
+	ut := os.Getenv("UNIT_TEST")
+	if ut == "" || ut == filepath.Base(filename) {
+
+		resource.Test( t,
+			resource.TestCase {
+				PreCheck: func() { testAccPreCheck(t) },
+				Providers: testAccProviders,
+				CheckDestroy: testAccCheckAlarmDestroy,
+				Steps: []resource.TestStep {
+					resource.TestStep {
+						Config: fmt.Sprintf(testAccAlarmConfig, "true", 7500),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckAlarmExists("vsphere_alarm.alarm1", true),
+							resource.TestCheckResourceAttr("vsphere_alarm.alarm1", "metric_expression.0.metric", "cpu.ready.summation"),
+							resource.TestCheckResourceAttr("vsphere_alarm.alarm1", "metric_expression.0.yellow", "7500"),
+							resource.TestCheckResourceAttr("vsphere_alarm.alarm1", "event_expression.0.event_type", "HostConnectionLostEvent"),
+							resource.TestCheckResourceAttr("vsphere_alarm.alarm1", "email_action.0.repeat", "true"),
+						),
+					},
+					resource.TestStep {
+						Config: fmt.Sprintf(testAccAlarmConfig, "false", 8000),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckAlarmExists("vsphere_alarm.alarm1", false),
+							resource.TestCheckResourceAttr("vsphere_alarm.alarm1", "metric_expression.0.yellow", "8000"),
+						),
+					},
+				},
+			} )
+	}
+}
+
+func testAccCheckAlarmExists(resource string, enabled bool) resource.TestCheckFunc {
+
+	return func(s *terraform.State) error {
+
+		rs, ok := s.RootModule().Resources[resource]
+		if !ok {
+			return fmt.Errorf("alarm '%s' not found in terraform state", resource)
+		}
+
+		log.Printf("[DEBUG] Terraform alarm: %# v", pretty.Formatter(rs))
+
+		client := testAccProvider.Meta().(*govmomi.Client)
+
+		var alarm mo.Alarm
+		ref := types.ManagedObjectReference{ Type: "Alarm", Value: rs.Primary.ID }
+
+		err := property.DefaultCollector(client.Client).RetrieveOne(context.Background(), ref, []string{"info"}, &alarm)
+		if err != nil {
+			return err
+		}
+		if alarm.Info.Entity.Value != rs.Primary.Attributes["entity_id"] {
+			return fmt.Errorf("alarm '%s' is defined on '%s' but expected '%s'", rs.Primary.ID, alarm.Info.Entity.Value, rs.Primary.Attributes["entity_id"])
+		}
+		if alarm.Info.Enabled != enabled {
+			return fmt.Errorf("alarm '%s' has enabled %t but expected %t", rs.Primary.ID, alarm.Info.Enabled, enabled)
+		}
+		return nil
+	}
+}
+
+func testAccCheckAlarmDestroy(s *terraform.State) error {
+
+	const alarm1 = "vsphere_alarm.alarm1"
+
+	_, ok := s.RootModule().Resources[alarm1]
+	if ok {
+		return fmt.Errorf("alarm '%s' still exists in the terraform state", alarm1)
+	}
+	return nil
+}
+
+func TestAlarmExpressions(t *testing.T) {
+
+	metrics := []interface{}{
+		map[string]interface{}{
+			"object_type": "VirtualMachine", "metric": "cpu.ready.summation", "instance": "", "operator": "isAbove",
+			"yellow": 2000, "yellow_interval": 300, "red": 4000, "red_interval": 300,
+		},
+	}
+	events := []interface{}{
+		map[string]interface{}{
+			"event_type": "EventEx", "event_type_id": "esx.problem.vmfs.heartbeat.timedout", "object_type": "HostSystem", "status": "red",
+			"comparison": []interface{}{
+				map[string]interface{}{ "attribute": "datastore", "operator": "equals", "value": "datastore1" },
+			},
+		},
+	}
+	states := []interface{}{
+		map[string]interface{}{
+			"object_type": "HostSystem", "state_path": "runtime.connectionState", "operator": "isEqual", "yellow": "", "red": "notResponding",
+		},
+	}
+
+	expression, err := getAlarmExpression("and", metrics, events, states, map[string]int{ "cpu.ready.summation": 12 })
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	and, ok := expression.(*types.AndAlarmExpression)
+	if !ok || len(and.Expression) != 3 {
+		t.Fatalf("expected an and expression of three expressions but got: %# v", pretty.Formatter(expression))
+	}
+	if metric := and.Expression[0].(*types.MetricAlarmExpression); metric.Metric.CounterId != 12 {
+		t.Fatalf("expected the metric expression to use counter 12 but got %d", metric.Metric.CounterId)
+	}
+
+	operator, m, e, s := putAlarmExpressions(expression, map[int]string{ 12: "cpu.ready.summation" })
+	if operator != "and" || len(m) != 1 || len(e) != 1 || len(s) != 1 {
+		t.Fatalf("expected the expressions to be read back but got: %s %v %v %v", operator, m, e, s)
+	}
+	if m[0]["metric"] != "cpu.ready.summation" || m[0]["red"] != 4000 {
+		t.Fatalf("unexpected metric expression: %v", m[0])
+	}
+	if e[0]["event_type_id"] != "esx.problem.vmfs.heartbeat.timedout" || len(e[0]["comparison"].([]map[string]interface{})) != 1 {
+		t.Fatalf("unexpected event expression: %v", e[0])
+	}
+	if s[0]["red"] != "notResponding" {
+		t.Fatalf("unexpected state expression: %v", s[0])
+	}
+
+	_, err = getAlarmExpression("or", []interface{}{}, []interface{}{}, []interface{}{}, nil)
+	if err == nil {
+		t.Fatalf("expected an error for an alarm without expressions")
+	}
+	metrics[0].(map[string]interface{})["metric"] = "cpu.unknown.average"
+	_, err = getAlarmExpression("or", metrics, nil, nil, map[string]int{})
+	if err == nil {
+		t.Fatalf("expected an error for an unknown performance counter")
+	}
+
+	operator, m, _, _ = putAlarmExpressions(&types.MetricAlarmExpression{ Metric: types.PerfMetricId{ CounterId: 6 } }, map[int]string{})
+	if operator != "or" || len(m) != 1 || m[0]["metric"] != "6" {
+		t.Fatalf("expected a single metric expression identified by its counter id but got: %s %v", operator, m)
+	}
+}
+
+func TestAlarmActions(t *testing.T) {
+
+	emails := []interface{}{
+		map[string]interface{}{
+			"to": "ops@example.com", "cc": "", "subject": "alarm", "body": "",
+			"green_to_yellow": true, "yellow_to_red": true, "red_to_yellow": false, "yellow_to_green": true, "repeat": true,
+		},
+	}
+	scripts := []interface{}{
+		map[string]interface{}{
+			"script": "/usr/local/bin/page",
+			"green_to_yellow": false, "yellow_to_red": true, "red_to_yellow": false, "yellow_to_green": false, "repeat": false,
+		},
+	}
+
+	action := getAlarmAction(emails, nil, scripts)
+
+	group, ok := action.(*types.GroupAlarmAction)
+	if !ok || len(group.Action) != 2 {
+		t.Fatalf("expected a group of two actions but got: %# v", pretty.Formatter(action))
+	}
+	specs := group.Action[0].(*types.AlarmTriggeringAction).TransitionSpecs
+	if len(specs) != 3 || !specs[0].Repeats || !specs[1].Repeats || specs[2].Repeats {
+		t.Fatalf("expected actions to be repeated on transitions to yellow and red only but got: %# v", pretty.Formatter(specs))
+	}
+
+	e, n, s := putAlarmActions(action)
+	if len(e) != 1 || len(n) != 0 || len(s) != 1 {
+		t.Fatalf("expected the actions to be read back but got: %v %v %v", e, n, s)
+	}
+	if e[0]["to"] != "ops@example.com" || e[0]["repeat"] != true || e[0]["yellow_to_green"] != true || e[0]["red_to_yellow"] != false {
+		t.Fatalf("unexpected email action: %v", e[0])
+	}
+	if s[0]["script"] != "/usr/local/bin/page" || s[0]["repeat"] != false {
+		t.Fatalf("unexpected script action: %v", s[0])
+	}
+
+	if getAlarmAction(nil, nil, nil) != nil {
+		t.Fatalf("expected no action when no actions are configured")
+	}
+
+	// Actions created by older clients only set the deprecated transition flags
+	e, _, _ = putAlarmActions(&types.AlarmTriggeringAction{ Action: &types.SendEmailAction{ ToList: "ops@example.com" }, Red2yellow: true })
+	if len(e) != 1 || e[0]["red_to_yellow"] != true || e[0]["yellow_to_red"] != false {
+		t.Fatalf("unexpected email action: %v", e)
+	}
+}
+
+const testAccAlarmConfig = `
+
+resource "vsphere_datacenter" "dc30" {
+	name = "datacenter30"
+}
+
+resource "vsphere_alarm" "alarm1" {
+	name = "terraform-vm-cpu-ready"
+	description = "VM CPU ready time or lost host connections"
+	entity_id = "${vsphere_datacenter.dc30.object_id}"
+	enabled = %s
+
+	metric_expression {
+		object_type = "VirtualMachine"
+		metric = "cpu.ready.summation"
+		yellow = %d
+		yellow_interval = 300
+		red = 10000
+		red_interval = 300
+	}
+
+	event_expression {
+		event_type = "HostConnectionLostEvent"
+		object_type = "HostSystem"
+	}
+
+	email_action {
+		to = "ops@example.com"
+		subject = "vCenter alarm"
+		green_to_yellow = true
+		repeat = true
+	}
+
+	snmp_action {
+		yellow_to_red = true
+	}
+
+	action_frequency = 600
+}
+`
@@ -8,7 +8,10 @@ import (
 	"golang.org/x/net/context"
 	
 	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/find"
 	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/methods"
 	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/types"
 )
@@ -76,8 +79,9 @@ func resourceVsphereCluster() *schema.Resource {
 							Default: true,
 						},
 						"admissionControlPolicy": &schema.Schema{
-							Type:     schema.TypeList,
+							Type:     schema.TypeList, // Name/value pairs of one of failoverLevel or cpuFailoverResourcesPercent and memoryFailoverResourcesPercent
 							Optional: true,
+							Computed: true,
 							Elem: &schema.Resource{
 								Schema: map[string]*schema.Schema{
 									"name": &schema.Schema{
@@ -91,9 +95,55 @@ func resourceVsphereCluster() *schema.Resource {
 								},
 							},
 						},
+						"isolation_response": &schema.Schema{
+							Type: schema.TypeString, // One of none, powerOff or shutdown
+							Optional: true,
+							Default: "none",
+						},
+						"restart_priority": &schema.Schema{
+							Type: schema.TypeString, // One of disabled, low, medium or high
+							Optional: true,
+							Default: "medium",
+						},
+						"heartbeat_datastore_policy": &schema.Schema{
+							Type: schema.TypeString, // One of userSelectedDs, allFeasibleDs or allFeasibleDsWithUserPreference
+							Optional: true,
+							Default: "allFeasibleDsWithUserPreference",
+						},
+						"heartbeat_datastores": &schema.Schema{
+							Type: schema.TypeList,
+							Optional: true,
+							Elem: &schema.Schema{Type: schema.TypeString},
+						},
 					},
 				},
 			},
+			"vsan": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"enabled": &schema.Schema{
+							Type: schema.TypeBool,
+							Optional: true,
+							Default: true,
+						},
+						"auto_claim_disks": &schema.Schema{
+							Type: schema.TypeBool,
+							Optional: true,
+							Default: false,
+						},
+					},
+				},
+			},
+			"evc_mode": &schema.Schema{
+				Type: schema.TypeString, // EVC mode key such as intel-sandybridge or amd-rev-e. EVC is disabled if not set.
+				Optional: true,
+			},
+			"custom_attributes": &schema.Schema{
+				Type: schema.TypeMap, // Values of custom attributes keyed by attribute name
+				Optional: true,
+			},
 			"keep": &schema.Schema{
 				Type: schema.TypeBool,
 				Optional: true,
@@ -134,7 +184,7 @@ func resourceVsphereClusterCreate(d *schema.ResourceData, meta interface{}) erro
 		}
 	}
 	
-	d.SetId(d.Get("name").(string))
+	d.SetId(cluster.Reference().Value)
 	d.Set("object_id", cluster.Reference().Value)
 	return resourceVsphereClusterUpdate(d, meta)
 }
@@ -148,26 +198,82 @@ func resourceVsphereClusterRead(d *schema.ResourceData, meta interface{}) error
 		return err
 	}
 	
-	config, err := getConfiguration(context.Background(), cluster)
+	name, err := getObjectName(context.Background(), meta.(*govmomi.Client).Client, cluster.Reference())
+	if err != nil {
+		log.Printf("[ERROR] Unable read name of cluster: '%s'", d.Id())
+		return err
+	}
+	
+	// Clusters created by earlier versions were identified by their name
+	d.SetId(cluster.Reference().Value)
+	d.Set("name", name)
+	
+	config, err := getConfigurationEx(context.Background(), cluster)
 	if err != nil {
 		log.Printf("[ERROR] Unable read cluster configuration: '%s'", d.Id())
 		return err
 	}
 	
-	if *config.DrsConfig.Enabled {
+	drsList := make([]map[string]interface{}, 0, 1)
+	if config.DrsConfig.Enabled != nil && *config.DrsConfig.Enabled {
 		drs := make(map[string]interface{})
-		drs["enable_vm_automation_override"] = strconv.FormatBool(*config.DrsConfig.EnableVmBehaviorOverrides)
-		drs["migration_threshold"] = strconv.Itoa(config.DrsConfig.VmotionRate)
-		drs["default_automation_level"] = string(config.DrsConfig.DefaultVmBehavior)		
-		d.Set("drs", append(make([]map[string]interface{}, 0, 1), drs))
+		drs["enable_vm_automation_override"] = config.DrsConfig.EnableVmBehaviorOverrides != nil && *config.DrsConfig.EnableVmBehaviorOverrides
+		drs["migration_threshold"] = config.DrsConfig.VmotionRate
+		drs["default_automation_level"] = string(config.DrsConfig.DefaultVmBehavior)
+		drsList = append(drsList, drs)
 	}
-	if *config.DasConfig.Enabled {
+	d.Set("drs", drsList)
+	
+	haList := make([]map[string]interface{}, 0, 1)
+	if config.DasConfig.Enabled != nil && *config.DasConfig.Enabled {
 		ha := make(map[string]interface{})	
 		ha["vm_monitoring"] = config.DasConfig.VmMonitoring
 		ha["host_monitoring"] = config.DasConfig.HostMonitoring
-		ha["admissionControlEnabled"] = strconv.FormatBool(*config.DasConfig.AdmissionControlEnabled)
-		d.Set("ha", append(make([]map[string]interface{}, 0, 1), ha))
- 	}
+		ha["admissionControlEnabled"] = config.DasConfig.AdmissionControlEnabled != nil && *config.DasConfig.AdmissionControlEnabled
+		ha["admissionControlPolicy"] = putClusterAdmissionControlPolicy(config.DasConfig.AdmissionControlPolicy,
+			d.Get("ha.0.admissionControlPolicy").([]interface{}))
+		ha["heartbeat_datastore_policy"] = config.DasConfig.HBDatastoreCandidatePolicy
+		
+		if config.DasConfig.DefaultVmSettings != nil {
+			ha["isolation_response"] = config.DasConfig.DefaultVmSettings.IsolationResponse
+			ha["restart_priority"] = config.DasConfig.DefaultVmSettings.RestartPriority
+		}
+		
+		heartbeatDatastores, err := getObjectNames(context.Background(), meta.(*govmomi.Client).Client, config.DasConfig.HeartbeatDatastore)
+		if err != nil {
+			log.Printf("[ERROR] Unable to read heartbeat datastores of cluster: '%s'", d.Id())
+			return err
+		}
+		ha["heartbeat_datastores"] = heartbeatDatastores
+		
+		haList = append(haList, ha)
+	}
+	d.Set("ha", haList)
+	
+	vsanList := make([]map[string]interface{}, 0, 1)
+	if config.VsanConfigInfo != nil && 
+		((config.VsanConfigInfo.Enabled != nil && *config.VsanConfigInfo.Enabled) || d.Get("vsan.#").(int) > 0) {
+		
+		vsan := make(map[string]interface{})
+		vsan["enabled"] = config.VsanConfigInfo.Enabled != nil && *config.VsanConfigInfo.Enabled
+		vsan["auto_claim_disks"] = config.VsanConfigInfo.DefaultConfig != nil && 
+			config.VsanConfigInfo.DefaultConfig.AutoClaimStorage != nil && *config.VsanConfigInfo.DefaultConfig.AutoClaimStorage
+		vsanList = append(vsanList, vsan)
+	}
+	d.Set("vsan", vsanList)
+	
+	evcMode, err := getCurrentEvcMode(context.Background(), cluster)
+	if err != nil {
+		log.Printf("[ERROR] Unable read EVC mode of cluster: '%s'", d.Id())
+		return err
+	}
+	d.Set("evc_mode", evcMode)
+	
+	err = readCustomAttributes(d, meta, cluster.Reference())
+	if err != nil {
+		log.Printf("[ERROR] Unable read custom attributes of cluster: '%s'", d.Id())
+		return err
+	}
 		
 	d.Set("object_id", cluster.Reference().Value) 
 	return nil
@@ -175,34 +281,79 @@ func resourceVsphereClusterRead(d *schema.ResourceData, meta interface{}) error
 
 func resourceVsphereClusterUpdate(d *schema.ResourceData, meta interface{}) error {
 	
+	finder, _, err := getFinder(d, meta)
+	if err != nil {
+		log.Printf("[ERROR] Unable to create finder for operations on cluster: '%s'", d.Get("name").(string))
+		return err
+	}
+	
 	cluster, err := findCluster(d, meta)
 	if err != nil {
 		return err
 	}
 	
-	spec := types.ClusterConfigSpec {}
+	client := meta.(*govmomi.Client)
+	
+	name, err := getObjectName(context.Background(), client.Client, cluster.Reference())
+	if err != nil {
+		return err
+	}
+	if name != d.Get("name").(string) {
+		
+		log.Printf("[DEBUG] Renaming cluster '%s' to '%s'", name, d.Get("name").(string))
+		
+		err = renameObject(context.Background(), client.Client, cluster.Reference(), d.Get("name").(string))
+		if err != nil {
+			log.Printf("[ERROR] Unable to rename cluster '%s'", name)
+			return err
+		}
+	}
+	
+	evcMode := d.Get("evc_mode").(string)
+	if d.HasChange("evc_mode") && evcMode != "" {
+		err = validateEvcMode(context.Background(), client, cluster, evcMode)
+		if err != nil {
+			return err
+		}
+	}
+	
+	spec := types.ClusterConfigSpecEx {}
 	spec.DrsConfig, err = getClusterDrsConfigInfo(d) 
 	if err != nil {
 		return err
 	}
-	spec.DasConfig, err = getClusterDasConfigInfo(d)
+	spec.DasConfig, err = getClusterDasConfigInfo(d, finder)
+	if err != nil {
+		return err
+	}
+	spec.VsanConfig, err = getClusterVsanConfigInfo(d)
 	if err != nil {
 		return err
 	}
 	
 	log.Printf("[DEBUG] Updating cluster: %s", d.Id())
 	
-	var task *object.Task
-	task, err = cluster.ReconfigureCluster(context.Background(), spec)
+	err = reconfigureComputeResource(context.Background(), client, cluster, spec)
 	if err != nil {
 		return err
-	}	
-	err = task.Wait(context.Background())
+	}
+	
+	if d.HasChange("evc_mode") {
+		
+		log.Printf("[DEBUG] Setting EVC mode of cluster '%s' to '%s'", d.Id(), evcMode)
+		
+		err = configureEvcMode(context.Background(), client, cluster, evcMode)
+		if err != nil {
+			return err
+		}
+	}
+	
+	err = updateCustomAttributes(d, meta, cluster.Reference())
 	if err != nil {
 		return err
 	}
 	
-	return nil
+	return resourceVsphereClusterRead(d, meta)
 }
 
 func resourceVsphereClusterDelete(d *schema.ResourceData, meta interface{}) error {
@@ -220,7 +371,7 @@ func resourceVsphereClusterDelete(d *schema.ResourceData, meta interface{}) erro
 		if err != nil {
 			return err
 		}
-	  	err = task.Wait(context.Background())
+	  	_, err = waitForTask(meta.(*govmomi.Client).Client, task, fmt.Sprintf("delete cluster '%s'", d.Id()), defaultTaskTimeout)
 		if err != nil {
 			return err
 		}
@@ -237,9 +388,29 @@ func findCluster(d *schema.ResourceData, meta interface{}) (*object.ClusterCompu
 		return nil, err
 	}
 	
-	cluster, err := finder.ClusterComputeResource(context.Background(), d.Id())
+	cluster, err := getCluster(d.Id(), finder, meta.(*govmomi.Client))
+	if err != nil {
+		return nil, err
+	}
+	
+	return cluster, nil
+}
+
+// Finds the cluster by its managed object reference and falls back to
+// looking it up by its path so clusters identified by name still resolve.
+func getCluster(clusterID string, finder *find.Finder, client *govmomi.Client) (*object.ClusterComputeResource, error) {
+	
+	ref, err := findObjectReference(context.Background(), client.Client, "ClusterComputeResource", clusterID)
+	if err != nil {
+		return nil, err
+	}
+	if ref != nil {
+		return object.NewClusterComputeResource(client.Client, *ref), nil
+	}
+	
+	cluster, err := finder.ClusterComputeResource(context.Background(), clusterID)
 	if err != nil {
-		log.Printf("[ERROR] Unable find cluster: '%s'", d.Get("name").(string))
+		log.Printf("[ERROR] Unable find cluster: '%s'", clusterID)
 		return nil, err
 	}
 	
@@ -252,17 +423,17 @@ func getClusterDrsConfigInfo(d *schema.ResourceData) (*types.ClusterDrsConfigInf
 	if drsCount > 1 {
 		return nil, fmt.Errorf("only 1 drs configuration section permitted")
 	}
-	if drsCount == 1 {
-		
-		drsConfigEnabled := true
+	
+	drsConfigEnabled := (drsCount == 1)
+	
+	drsConfig := &types.ClusterDrsConfigInfo {}
+	drsConfig.Enabled = &drsConfigEnabled
+	
+	if drsConfigEnabled {
 		
-		drsConfig := &types.ClusterDrsConfigInfo {}
-		drsConfig.Enabled = &drsConfigEnabled
+		enableVmBehaviorOverrides := d.Get("drs.0.enable_vm_automation_override").(bool)
+		drsConfig.EnableVmBehaviorOverrides = &enableVmBehaviorOverrides
 		
-		if v, ok := d.GetOk("drs.0.enable_vm_automation_override"); ok {
-			enableVmBehaviorOverrides := v.(bool)
-			drsConfig.EnableVmBehaviorOverrides = &enableVmBehaviorOverrides
-		}
 		if v, ok := d.GetOk("drs.0.migration_threshold"); ok {
 			drsConfig.VmotionRate = v.(int)
 		}
@@ -275,24 +446,24 @@ func getClusterDrsConfigInfo(d *schema.ResourceData) (*types.ClusterDrsConfigInf
 			}
 			drsConfig.DefaultVmBehavior = defaultVmBehavior
 		}
-		return drsConfig, nil
 	}
 	
-	return nil, nil
+	return drsConfig, nil
 }
 
-func getClusterDasConfigInfo(d *schema.ResourceData) (*types.ClusterDasConfigInfo, error) {
+func getClusterDasConfigInfo(d *schema.ResourceData, finder *find.Finder) (*types.ClusterDasConfigInfo, error) {
 	
 	haCount := d.Get("ha.#").(int)
 	if haCount > 1 {
 		return nil, fmt.Errorf("only 1 ha configuration section permitted")
 	}
-	if haCount == 1 {
-		
-		dasConfigEnabled := true
-		
-		dasConfig := &types.ClusterDasConfigInfo {}
-		dasConfig.Enabled = &dasConfigEnabled
+	
+	dasConfigEnabled := (haCount == 1)
+	
+	dasConfig := &types.ClusterDasConfigInfo {}
+	dasConfig.Enabled = &dasConfigEnabled
+	
+	if dasConfigEnabled {
 		
 		if v, ok := d.GetOk("ha.0.vm_monitoring"); ok {
 			vmMonitoring := v.(string)
@@ -311,31 +482,179 @@ func getClusterDasConfigInfo(d *schema.ResourceData) (*types.ClusterDasConfigInf
 			}
 			dasConfig.HostMonitoring = hostMonitoring
 		}
-		if v, ok := d.GetOk("ha.0.admissionControlEnabled"); ok {
-			admissionControlEnabled := v.(bool)
-			dasConfig.AdmissionControlEnabled = &admissionControlEnabled
+		
+		admissionControlEnabled := d.Get("ha.0.admissionControlEnabled").(bool)
+		dasConfig.AdmissionControlEnabled = &admissionControlEnabled
+		
+		if v, ok := d.GetOk("ha.0.admissionControlPolicy"); ok {
+			policy, err := getClusterAdmissionControlPolicy(v.([]interface{}))
+			if err != nil {
+				return nil, err
+			}
+			dasConfig.AdmissionControlPolicy = policy
 		}
-		if d.Get("ha.0.admissionControlPolicy.#").(int) > 0 {
-			var props []types.DynamicProperty
-			v, ok := d.GetOk("ha.0.admissionControlPolicy")
-			if ok {
-				for _, p := range v.([]map[string]interface{}) {
-					if p != nil {
-						props = append(props, types.DynamicProperty {
-								Name: p["name"].(string),
-								Val: p["value"].(string),
-							} )
-					}
-				}
+		
+		dasVmSettings := &types.ClusterDasVmSettings {}
+		if v, ok := d.GetOk("ha.0.isolation_response"); ok {
+			isolationResponse := types.ClusterDasVmSettingsIsolationResponse(v.(string))
+			if isolationResponse != types.ClusterDasVmSettingsIsolationResponseNone &&
+				isolationResponse != types.ClusterDasVmSettingsIsolationResponsePowerOff &&
+				isolationResponse != types.ClusterDasVmSettingsIsolationResponseShutdown {
+				return nil, fmt.Errorf("invalid isolation response value. it should be one of none, powerOff or shutdown")
+			}
+			dasVmSettings.IsolationResponse = string(isolationResponse)
+		}
+		if v, ok := d.GetOk("ha.0.restart_priority"); ok {
+			restartPriority := types.ClusterDasVmSettingsRestartPriority(v.(string))
+			if restartPriority != types.ClusterDasVmSettingsRestartPriorityDisabled &&
+				restartPriority != types.ClusterDasVmSettingsRestartPriorityLow &&
+				restartPriority != types.ClusterDasVmSettingsRestartPriorityMedium &&
+				restartPriority != types.ClusterDasVmSettingsRestartPriorityHigh {
+				return nil, fmt.Errorf("invalid restart priority value. it should be one of disabled, low, medium or high")
 			}
+			dasVmSettings.RestartPriority = string(restartPriority)
+		}
+		dasConfig.DefaultVmSettings = dasVmSettings
+		
+		if v, ok := d.GetOk("ha.0.heartbeat_datastore_policy"); ok {
+			policy := types.ClusterDasConfigInfoHBDatastoreCandidate(v.(string))
+			if policy != types.ClusterDasConfigInfoHBDatastoreCandidateUserSelectedDs &&
+				policy != types.ClusterDasConfigInfoHBDatastoreCandidateAllFeasibleDs &&
+				policy != types.ClusterDasConfigInfoHBDatastoreCandidateAllFeasibleDsWithUserPreference {
+				return nil, fmt.Errorf("invalid heartbeat datastore policy. it should be one of userSelectedDs, allFeasibleDs or allFeasibleDsWithUserPreference")
+			}
+			dasConfig.HBDatastoreCandidatePolicy = string(policy)
+		}
+		
+		heartbeatDatastores := []types.ManagedObjectReference{}
+		for _, v := range d.Get("ha.0.heartbeat_datastores").([]interface{}) {
+			datastore, err := finder.Datastore(context.Background(), v.(string))
+			if err != nil {
+				log.Printf("[ERROR] Unable find heartbeat datastore: '%s'", v.(string))
+				return nil, err
+			}
+			heartbeatDatastores = append(heartbeatDatastores, datastore.Reference())
+		}
+		dasConfig.HeartbeatDatastore = heartbeatDatastores
+	}
+	
+	return dasConfig, nil
+}
+
+func getClusterVsanConfigInfo(d *schema.ResourceData) (*types.VsanClusterConfigInfo, error) {
+	
+	vsanCount := d.Get("vsan.#").(int)
+	if vsanCount > 1 {
+		return nil, fmt.Errorf("only 1 vsan configuration section permitted")
+	}
+	if vsanCount == 0 && !d.HasChange("vsan") {
+		// Leave vSAN untouched on clusters where it has never been managed
+		return nil, nil
+	}
+	
+	vsanEnabled := vsanCount == 1 && d.Get("vsan.0.enabled").(bool)
+	autoClaimStorage := vsanCount == 1 && d.Get("vsan.0.auto_claim_disks").(bool)
+	
+	return &types.VsanClusterConfigInfo {
+		Enabled: &vsanEnabled,
+		DefaultConfig: &types.VsanClusterConfigInfoHostDefaultInfo {
+			AutoClaimStorage: &autoClaimStorage,
+		},
+	}, nil
+}
+
+func getClusterAdmissionControlPolicy(policyList []interface{}) (types.BaseClusterDasAdmissionControlPolicy, error) {
+	
+	var (
+		failoverLevelPolicy *types.ClusterFailoverLevelAdmissionControlPolicy
+		failoverResourcesPolicy *types.ClusterFailoverResourcesAdmissionControlPolicy
+	)
+	
+	for _, p := range policyList {
+		
+		property := p.(map[string]interface{})
+		name := property["name"].(string)
+		
+		value, err := strconv.Atoi(property["value"].(string))
+		if err != nil {
+			return nil, fmt.Errorf("admission control policy value for '%s' must be an integer: %s", name, err.Error())
 		}
 		
-		return dasConfig, nil
+		switch name {
+			case "failoverLevel":
+				failoverLevelPolicy = &types.ClusterFailoverLevelAdmissionControlPolicy {
+					FailoverLevel: value,
+				}
+			case "cpuFailoverResourcesPercent":
+				if failoverResourcesPolicy == nil {
+					failoverResourcesPolicy = &types.ClusterFailoverResourcesAdmissionControlPolicy {}
+				}
+				failoverResourcesPolicy.CpuFailoverResourcesPercent = value
+			case "memoryFailoverResourcesPercent":
+				if failoverResourcesPolicy == nil {
+					failoverResourcesPolicy = &types.ClusterFailoverResourcesAdmissionControlPolicy {}
+				}
+				failoverResourcesPolicy.MemoryFailoverResourcesPercent = value
+			default:
+				return nil, fmt.Errorf("invalid admission control policy '%s'. it should be one of failoverLevel, cpuFailoverResourcesPercent or memoryFailoverResourcesPercent", name)
+		}
 	}
 	
+	if failoverLevelPolicy != nil && failoverResourcesPolicy != nil {
+		return nil, fmt.Errorf("admission control policy failoverLevel cannot be combined with failover resource percentages")
+	}
+	if failoverLevelPolicy != nil {
+		return failoverLevelPolicy, nil
+	}
+	if failoverResourcesPolicy != nil {
+		return failoverResourcesPolicy, nil
+	}
 	return nil, nil
 }
 
+// Returns the admission control policy in the form of the admissionControlPolicy
+// list. Failover resource percentages are only returned when they are configured
+// or when none of them is as vCenter always reports both.
+func putClusterAdmissionControlPolicy(policy types.BaseClusterDasAdmissionControlPolicy, configured []interface{}) []interface{} {
+	
+	policyList := []interface{}{}
+	
+	switch p := policy.(type) {
+		case *types.ClusterFailoverLevelAdmissionControlPolicy:
+			policyList = append(policyList, map[string]interface{}{
+					"name": "failoverLevel",
+					"value": strconv.Itoa(p.FailoverLevel),
+				} )
+		case *types.ClusterFailoverResourcesAdmissionControlPolicy:
+			cpu := isAdmissionControlPolicyConfigured(configured, "cpuFailoverResourcesPercent")
+			memory := isAdmissionControlPolicyConfigured(configured, "memoryFailoverResourcesPercent")
+			if cpu || !memory {
+				policyList = append(policyList, map[string]interface{}{
+						"name": "cpuFailoverResourcesPercent",
+						"value": strconv.Itoa(p.CpuFailoverResourcesPercent),
+					} )
+			}
+			if memory || !cpu {
+				policyList = append(policyList, map[string]interface{}{
+						"name": "memoryFailoverResourcesPercent",
+						"value": strconv.Itoa(p.MemoryFailoverResourcesPercent),
+					} )
+			}
+	}
+	
+	return policyList
+}
+
+func isAdmissionControlPolicyConfigured(configured []interface{}, name string) bool {
+	
+	for _, p := range configured {
+		if p.(map[string]interface{})["name"].(string) == name {
+			return true
+		}
+	}
+	return false
+}
+
 func getConfiguration(ctx context.Context, cluster *object.ClusterComputeResource) (*types.ClusterConfigInfo, error) {	
 	var mccr mo.ClusterComputeResource
 	
@@ -347,3 +666,36 @@ func getConfiguration(ctx context.Context, cluster *object.ClusterComputeResourc
 	
 	return &mccr.Configuration, nil
 }
+
+func getConfigurationEx(ctx context.Context, cluster *object.ClusterComputeResource) (*types.ClusterConfigInfoEx, error) {	
+	var mccr mo.ClusterComputeResource
+	
+	ps := []string{"configurationEx"}
+	err := cluster.Properties(ctx, cluster.Reference(), ps, &mccr)
+	if err != nil {
+		return nil, err
+	}
+	
+	config, ok := mccr.ConfigurationEx.(*types.ClusterConfigInfoEx)
+	if !ok {
+		return nil, fmt.Errorf("unexpected configuration type '%T' for cluster", mccr.ConfigurationEx)
+	}
+	return config, nil
+}
+
+func reconfigureComputeResource(ctx context.Context, client *govmomi.Client, cluster *object.ClusterComputeResource, spec types.ClusterConfigSpecEx) error {
+	
+	req := types.ReconfigureComputeResource_Task{
+		This: cluster.Reference(),
+		Spec: &spec,
+		Modify: true,
+	}
+	
+	res, err := methods.ReconfigureComputeResource_Task(ctx, client.Client, &req)
+	if err != nil {
+		return err
+	}
+	
+	_, err = waitForTask(client.Client, object.NewTask(client.Client, res.Returnval), fmt.Sprintf("reconfigure cluster '%s'", cluster.Reference().Value), defaultTaskTimeout)
+	return err
+}
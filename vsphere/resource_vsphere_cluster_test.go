@@ -15,6 +15,7 @@ import (
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/kr/pretty"
 	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
 )
 
 var keepClusters bool // keep must have the same value for both test clusters. otherwise the last value wins.
@@ -60,6 +61,27 @@ func TestAccVsphereCluster_normal(t *testing.T) {
 								"vsphere_cluster.c2", "ha.0.host_monitoring", "enabled"),
 							resource.TestCheckResourceAttr(
 								"vsphere_cluster.c2", "ha.0.vm_monitoring", "vmAndAppMonitoring"),
+							resource.TestCheckResourceAttr(
+								"vsphere_cluster.c2", "ha.0.isolation_response", "powerOff"),
+							resource.TestCheckResourceAttr(
+								"vsphere_cluster.c2", "ha.0.restart_priority", "high"),
+						),
+					},
+					resource.TestStep {
+						Config: testAccClusterConfigDisabled,
+						Check: resource.ComposeTestCheckFunc(
+							
+							testAccCheckClusterExists("vsphere_cluster.c1"),
+							resource.TestCheckResourceAttr(
+								"vsphere_cluster.c1", "drs.#", "1"),
+							resource.TestCheckResourceAttr(
+								"vsphere_cluster.c1", "ha.#", "0"),
+							
+							testAccCheckClusterExists("vsphere_cluster.c2"),
+							resource.TestCheckResourceAttr(
+								"vsphere_cluster.c2", "drs.#", "0"),
+							resource.TestCheckResourceAttr(
+								"vsphere_cluster.c2", "ha.#", "0"),
 						),
 					},
 				},
@@ -80,13 +102,16 @@ func testAccCheckClusterExists(resource string) resource.TestCheckFunc {
 
 		attributes := rs.Primary.Attributes
 
-		clusterName := rs.Primary.ID
+		clusterName := attributes["name"]
 		datacenterName := attributes["datacenter_id"]
 
 		cluster, err := findTestCluster(datacenterName, clusterName)
 		if err != nil {
 			return err
 		}		
+		if cluster.Reference().Value != rs.Primary.ID {
+			return fmt.Errorf("cluster id mismatch. expected '%s' but got '%s'", cluster.Reference().Value, rs.Primary.ID)
+		}
 		config, err := getConfiguration(context.Background(), cluster)
 		if err != nil {
 			log.Printf("[ERROR] Unable read configuration for cluster '%s'.", clusterName)
@@ -96,26 +121,49 @@ func testAccCheckClusterExists(resource string) resource.TestCheckFunc {
 		log.Printf("[DEBUG] Cluster state: %# v", pretty.Formatter(rs.Primary))
 		log.Printf("[DEBUG] Cluster config from read via VMOMI: %# v", pretty.Formatter(config))
 		
-		if v, _ := strconv.Atoi(attributes["drs.#"]); !*config.DrsConfig.Enabled || v != 1 {
-			return fmt.Errorf("dynamic resource scheduler enabled but not reflected in terraform state")
+		if v, _ := strconv.Atoi(attributes["drs.#"]); *config.DrsConfig.Enabled != (v == 1) {
+			return fmt.Errorf("dynamic resource scheduler enabled state not reflected in terraform state")
 		}
-		if string(config.DrsConfig.DefaultVmBehavior) != attributes["drs.0.default_automation_level"] {
-			return fmt.Errorf("high-availability vm monitoring attribute mis-match")
+		if *config.DrsConfig.Enabled {
+			if string(config.DrsConfig.DefaultVmBehavior) != attributes["drs.0.default_automation_level"] {
+				return fmt.Errorf("dynamic resource scheduler automation level attribute mis-match")
+			}
+			if strconv.Itoa(config.DrsConfig.VmotionRate) != attributes["drs.0.migration_threshold"] {
+				return fmt.Errorf("dynamic resource scheduler migration threshold attribute mis-match")
+			}
+			if strconv.FormatBool(*config.DrsConfig.EnableVmBehaviorOverrides) != attributes["drs.0.enable_vm_automation_override"] {
+				return fmt.Errorf("dynamic resource scheduler vm automation override attribute mis-match")
+			}
 		}
-		if strconv.Itoa(config.DrsConfig.VmotionRate) != attributes["drs.0.migration_threshold"] {
-			return fmt.Errorf("high-availability vm monitoring attribute mis-match")
+		if v, _ := strconv.Atoi(attributes["ha.#"]); *config.DasConfig.Enabled != (v == 1) {
+			return fmt.Errorf("high-availability enabled state not reflected in terraform state")
 		}
-		if v, _ := strconv.Atoi(attributes["ha.#"]); !*config.DasConfig.Enabled || v != 1 {
-			return fmt.Errorf("high-availability enabled but not reflected in terraform state")
+		if *config.DasConfig.Enabled {
+			if config.DasConfig.VmMonitoring != attributes["ha.0.vm_monitoring"] {
+				return fmt.Errorf("high-availability vm monitoring attribute mis-match")
+			}
+			if config.DasConfig.HostMonitoring != attributes["ha.0.host_monitoring"] {
+				return fmt.Errorf("high-availability host monitoring attribute mis-match")
+			}
+			if strconv.FormatBool(*config.DasConfig.AdmissionControlEnabled) != attributes["ha.0.admissionControlEnabled"] {
+				return fmt.Errorf("high-availability adminission control attribute mis-match")
+			}
+			if config.DasConfig.DefaultVmSettings.IsolationResponse != attributes["ha.0.isolation_response"] {
+				return fmt.Errorf("high-availability isolation response attribute mis-match")
+			}
+			if config.DasConfig.DefaultVmSettings.RestartPriority != attributes["ha.0.restart_priority"] {
+				return fmt.Errorf("high-availability restart priority attribute mis-match")
+			}
+			if config.DasConfig.HBDatastoreCandidatePolicy != attributes["ha.0.heartbeat_datastore_policy"] {
+				return fmt.Errorf("high-availability heartbeat datastore policy attribute mis-match")
+			}
 		}
-		if config.DasConfig.VmMonitoring != attributes["ha.0.vm_monitoring"] {
-			return fmt.Errorf("high-availability vm monitoring attribute mis-match")
-		}
-		if config.DasConfig.HostMonitoring != attributes["ha.0.host_monitoring"] {
-			return fmt.Errorf("high-availability host monitoring attribute mis-match")
+		evcMode, err := getCurrentEvcMode(context.Background(), cluster)
+		if err != nil {
+			return err
 		}
-		if strconv.FormatBool(*config.DasConfig.AdmissionControlEnabled) != attributes["ha.0.admissionControlEnabled"] {
-			return fmt.Errorf("high-availability adminission control attribute mis-match")
+		if evcMode != attributes["evc_mode"] {
+			return fmt.Errorf("cluster evc mode mis-match. expected '%s' but got '%s'", evcMode, attributes["evc_mode"])
 		}
 		if cluster.Reference().Value != attributes["object_id"] {
 			return fmt.Errorf("cluster object id mismatch. expected '%s' but go '%s'", cluster.Reference().Value, attributes["object_id"])
@@ -185,6 +233,26 @@ func findTestCluster(datacenterName string, clusterName string) (*object.Cluster
 	return cluster, nil
 }
 
+func TestClusterAdmissionControlPolicy(t *testing.T) {
+
+	policy := &types.ClusterFailoverResourcesAdmissionControlPolicy{
+		CpuFailoverResourcesPercent: 25,
+		MemoryFailoverResourcesPercent: 50,
+	}
+
+	policyList := putClusterAdmissionControlPolicy(policy, []interface{}{
+		map[string]interface{}{ "name": "cpuFailoverResourcesPercent", "value": "25" },
+	})
+	if len(policyList) != 1 || policyList[0].(map[string]interface{})["name"] != "cpuFailoverResourcesPercent" {
+		t.Fatalf("expected only the configured cpu percentage but got: %# v", pretty.Formatter(policyList))
+	}
+
+	policyList = putClusterAdmissionControlPolicy(policy, nil)
+	if len(policyList) != 2 {
+		t.Fatalf("expected both percentages when none is configured but got: %# v", pretty.Formatter(policyList))
+	}
+}
+
 const testAccClusterConfig = `
 
 resource "vsphere_datacenter" "dc2" {
@@ -220,8 +288,38 @@ resource "vsphere_cluster" "c2" {
 	ha {
 		host_monitoring = "enabled"
 		vm_monitoring = "vmAndAppMonitoring"
+		isolation_response = "powerOff"
+		restart_priority = "high"
 	}
 
 #	keep = true
 }
 `
+
+const testAccClusterConfigDisabled = `
+
+resource "vsphere_datacenter" "dc2" {
+	name = "datacenter2"
+
+#	keep = true
+}
+
+resource "vsphere_cluster" "c1" {
+	name = "cluster1"
+	datacenter_id = "${vsphere_datacenter.dc2.id}"
+  
+	drs {
+		default_automation_level = "manual"
+		migration_threshold = 2
+	}
+
+#	keep = true
+}
+
+resource "vsphere_cluster" "c2" {
+	name = "cluster2"
+	datacenter_id = "${vsphere_datacenter.dc2.id}"
+
+#	keep = true
+}
+`
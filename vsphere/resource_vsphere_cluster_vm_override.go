@@ -0,0 +1,340 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func resourceVsphereClusterVMOverride() *schema.Resource {
+
+	return &schema.Resource{
+
+		Create: resourceVsphereClusterVMOverrideCreate,
+		Read:   resourceVsphereClusterVMOverrideRead,
+		Update: resourceVsphereClusterVMOverrideUpdate,
+		Delete: resourceVsphereClusterVMOverrideDelete,
+
+		Schema: map[string]*schema.Schema{
+
+			"datacenter_id": &schema.Schema{
+				Type: schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"cluster_id": &schema.Schema{
+				Type: schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"vm_name": &schema.Schema{
+				Type: schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"drs_enabled": &schema.Schema{
+				Type: schema.TypeBool,
+				Optional: true,
+				Default: true,
+			},
+			"drs_automation_level": &schema.Schema{
+				Type: schema.TypeString, // One of manual, partiallyAutomated or fullyAutomated. If not set the cluster default is used.
+				Optional: true,
+			},
+			"ha_restart_priority": &schema.Schema{
+				Type: schema.TypeString, // One of disabled, low, medium, high or clusterRestartPriority
+				Optional: true,
+				Default: "clusterRestartPriority",
+			},
+			"ha_isolation_response": &schema.Schema{
+				Type: schema.TypeString, // One of none, powerOff, shutdown or clusterIsolationResponse
+				Optional: true,
+				Default: "clusterIsolationResponse",
+			},
+			"ha_vm_monitoring": &schema.Schema{
+				Type: schema.TypeString, // One of vmMonitoringDisabled, vmMonitoringOnly or vmAndAppMonitoring. If not set the cluster settings are used.
+				Optional: true,
+			},
+		},
+	}
+}
+
+func resourceVsphereClusterVMOverrideCreate(d *schema.ResourceData, meta interface{}) error {
+
+	log.Printf("[DEBUG] Creating overrides for virtual machine '%s' in cluster '%s'", d.Get("vm_name").(string), d.Get("cluster_id").(string))
+
+	d.SetId(d.Get("vm_name").(string))
+
+	err := reconfigureClusterVMOverride(d, meta, false)
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	return resourceVsphereClusterVMOverrideRead(d, meta)
+}
+
+func resourceVsphereClusterVMOverrideRead(d *schema.ResourceData, meta interface{}) error {
+
+	cluster, vm, err := findClusterVMOverrideTarget(d, meta)
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	config, err := getConfigurationEx(context.Background(), cluster)
+	if err != nil {
+		log.Printf("[ERROR] Unable read configuration of cluster: '%s'", d.Get("cluster_id").(string))
+		return err
+	}
+
+	drsVMConfig, dasVMConfig := getClusterVMConfig(config, vm)
+	if drsVMConfig == nil && dasVMConfig == nil {
+		log.Printf("[DEBUG] Overrides for virtual machine '%s' no longer exist in cluster '%s'", d.Id(), d.Get("cluster_id").(string))
+		d.SetId("")
+		return nil
+	}
+
+	if drsVMConfig != nil {
+		d.Set("drs_enabled", drsVMConfig.Enabled == nil || *drsVMConfig.Enabled)
+		d.Set("drs_automation_level", string(drsVMConfig.Behavior))
+	} else {
+		d.Set("drs_enabled", true)
+		d.Set("drs_automation_level", "")
+	}
+
+	if dasVMConfig != nil && dasVMConfig.DasSettings != nil {
+		d.Set("ha_restart_priority", dasVMConfig.DasSettings.RestartPriority)
+		d.Set("ha_isolation_response", dasVMConfig.DasSettings.IsolationResponse)
+
+		monitoring := dasVMConfig.DasSettings.VmToolsMonitoringSettings
+		if monitoring != nil && (monitoring.ClusterSettings == nil || !*monitoring.ClusterSettings) {
+			d.Set("ha_vm_monitoring", monitoring.VmMonitoring)
+		} else {
+			d.Set("ha_vm_monitoring", "")
+		}
+	} else {
+		d.Set("ha_restart_priority", string(types.ClusterDasVmSettingsRestartPriorityClusterRestartPriority))
+		d.Set("ha_isolation_response", string(types.ClusterDasVmSettingsIsolationResponseClusterIsolationResponse))
+		d.Set("ha_vm_monitoring", "")
+	}
+
+	return nil
+}
+
+func resourceVsphereClusterVMOverrideUpdate(d *schema.ResourceData, meta interface{}) error {
+
+	log.Printf("[DEBUG] Updating overrides for virtual machine: %s", d.Id())
+
+	err := reconfigureClusterVMOverride(d, meta, false)
+	if err != nil {
+		return err
+	}
+
+	return resourceVsphereClusterVMOverrideRead(d, meta)
+}
+
+func resourceVsphereClusterVMOverrideDelete(d *schema.ResourceData, meta interface{}) error {
+
+	log.Printf("[DEBUG] Removing overrides for virtual machine: %s", d.Id())
+
+	return reconfigureClusterVMOverride(d, meta, true)
+}
+
+func findClusterVMOverrideTarget(d *schema.ResourceData, meta interface{}) (*object.ClusterComputeResource, *object.VirtualMachine, error) {
+
+	finder, _, err := getFinder(d, meta)
+	if err != nil {
+		log.Printf("[ERROR] Unable to create finder for operations on virtual machine overrides: '%s'", d.Get("vm_name").(string))
+		return nil, nil, err
+	}
+
+	cluster, err := getCluster(d.Get("cluster_id").(string), finder, meta.(*govmomi.Client))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vm, err := finder.VirtualMachine(context.Background(), d.Get("vm_name").(string))
+	if err != nil {
+		log.Printf("[ERROR] Unable find virtual machine: '%s'", d.Get("vm_name").(string))
+		return nil, nil, err
+	}
+
+	return cluster, vm, nil
+}
+
+func getClusterVMConfig(config *types.ClusterConfigInfoEx, vm *object.VirtualMachine) (*types.ClusterDrsVmConfigInfo, *types.ClusterDasVmConfigInfo) {
+
+	var (
+		drsVMConfig *types.ClusterDrsVmConfigInfo
+		dasVMConfig *types.ClusterDasVmConfigInfo
+	)
+
+	for i, c := range config.DrsVmConfig {
+		if c.Key == vm.Reference() {
+			drsVMConfig = &config.DrsVmConfig[i]
+			break
+		}
+	}
+	for i, c := range config.DasVmConfig {
+		if c.Key == vm.Reference() {
+			dasVMConfig = &config.DasVmConfig[i]
+			break
+		}
+	}
+
+	return drsVMConfig, dasVMConfig
+}
+
+// Adds, edits or removes the DRS and HA overrides of the virtual machine
+// depending on what is currently configured on the cluster.
+func reconfigureClusterVMOverride(d *schema.ResourceData, meta interface{}, remove bool) error {
+
+	cluster, vm, err := findClusterVMOverrideTarget(d, meta)
+	if err != nil {
+		return err
+	}
+
+	config, err := getConfigurationEx(context.Background(), cluster)
+	if err != nil {
+		log.Printf("[ERROR] Unable read configuration of cluster: '%s'", d.Get("cluster_id").(string))
+		return err
+	}
+	currentDrsVMConfig, currentDasVMConfig := getClusterVMConfig(config, vm)
+
+	spec := types.ClusterConfigSpecEx {}
+
+	drsVMConfig, err := getClusterDrsVMConfigInfo(d, vm)
+	if err != nil {
+		return err
+	}
+	if remove {
+		drsVMConfig = nil
+	}
+	if drsVMConfig != nil {
+		operation := types.ArrayUpdateOperationAdd
+		if currentDrsVMConfig != nil {
+			operation = types.ArrayUpdateOperationEdit
+		}
+		spec.DrsVmConfigSpec = []types.ClusterDrsVmConfigSpec{
+			types.ClusterDrsVmConfigSpec {
+				ArrayUpdateSpec: types.ArrayUpdateSpec{ Operation: operation },
+				Info: drsVMConfig,
+			},
+		}
+	} else if currentDrsVMConfig != nil {
+		spec.DrsVmConfigSpec = []types.ClusterDrsVmConfigSpec{
+			types.ClusterDrsVmConfigSpec {
+				ArrayUpdateSpec: types.ArrayUpdateSpec{
+					Operation: types.ArrayUpdateOperationRemove,
+					RemoveKey: vm.Reference(),
+				},
+			},
+		}
+	}
+
+	if !remove {
+		dasVMConfig, err := getClusterDasVMConfigInfo(d, vm)
+		if err != nil {
+			return err
+		}
+		operation := types.ArrayUpdateOperationAdd
+		if currentDasVMConfig != nil {
+			operation = types.ArrayUpdateOperationEdit
+		}
+		spec.DasVmConfigSpec = []types.ClusterDasVmConfigSpec{
+			types.ClusterDasVmConfigSpec {
+				ArrayUpdateSpec: types.ArrayUpdateSpec{ Operation: operation },
+				Info: dasVMConfig,
+			},
+		}
+	} else if currentDasVMConfig != nil {
+		spec.DasVmConfigSpec = []types.ClusterDasVmConfigSpec{
+			types.ClusterDasVmConfigSpec {
+				ArrayUpdateSpec: types.ArrayUpdateSpec{
+					Operation: types.ArrayUpdateOperationRemove,
+					RemoveKey: vm.Reference(),
+				},
+			},
+		}
+	}
+
+	if len(spec.DrsVmConfigSpec) == 0 && len(spec.DasVmConfigSpec) == 0 {
+		return nil
+	}
+	return reconfigureComputeResource(context.Background(), meta.(*govmomi.Client), cluster, spec)
+}
+
+func getClusterDrsVMConfigInfo(d *schema.ResourceData, vm *object.VirtualMachine) (*types.ClusterDrsVmConfigInfo, error) {
+
+	enabled := d.Get("drs_enabled").(bool)
+	behavior := types.DrsBehavior(d.Get("drs_automation_level").(string))
+
+	if enabled && behavior == "" {
+		// Nothing to override so the cluster defaults apply
+		return nil, nil
+	}
+	if behavior != "" &&
+		behavior != types.DrsBehaviorManual &&
+		behavior != types.DrsBehaviorPartiallyAutomated &&
+		behavior != types.DrsBehaviorFullyAutomated {
+		return nil, fmt.Errorf("invalid automation level. it should be one of manual, partiallyAutomated or fullyAutomated")
+	}
+
+	return &types.ClusterDrsVmConfigInfo {
+		Key: vm.Reference(),
+		Enabled: &enabled,
+		Behavior: behavior,
+	}, nil
+}
+
+func getClusterDasVMConfigInfo(d *schema.ResourceData, vm *object.VirtualMachine) (*types.ClusterDasVmConfigInfo, error) {
+
+	restartPriority := types.ClusterDasVmSettingsRestartPriority(d.Get("ha_restart_priority").(string))
+	if restartPriority != types.ClusterDasVmSettingsRestartPriorityDisabled &&
+		restartPriority != types.ClusterDasVmSettingsRestartPriorityLow &&
+		restartPriority != types.ClusterDasVmSettingsRestartPriorityMedium &&
+		restartPriority != types.ClusterDasVmSettingsRestartPriorityHigh &&
+		restartPriority != types.ClusterDasVmSettingsRestartPriorityClusterRestartPriority {
+		return nil, fmt.Errorf("invalid restart priority value. it should be one of disabled, low, medium, high or clusterRestartPriority")
+	}
+
+	isolationResponse := types.ClusterDasVmSettingsIsolationResponse(d.Get("ha_isolation_response").(string))
+	if isolationResponse != types.ClusterDasVmSettingsIsolationResponseNone &&
+		isolationResponse != types.ClusterDasVmSettingsIsolationResponsePowerOff &&
+		isolationResponse != types.ClusterDasVmSettingsIsolationResponseShutdown &&
+		isolationResponse != types.ClusterDasVmSettingsIsolationResponseClusterIsolationResponse {
+		return nil, fmt.Errorf("invalid isolation response value. it should be one of none, powerOff, shutdown or clusterIsolationResponse")
+	}
+
+	monitoring := &types.ClusterVmToolsMonitoringSettings {}
+	if v, ok := d.GetOk("ha_vm_monitoring"); ok {
+		vmMonitoring := types.ClusterDasConfigInfoVmMonitoringState(v.(string))
+		if vmMonitoring != types.ClusterDasConfigInfoVmMonitoringStateVmMonitoringDisabled &&
+			vmMonitoring != types.ClusterDasConfigInfoVmMonitoringStateVmMonitoringOnly &&
+			vmMonitoring != types.ClusterDasConfigInfoVmMonitoringStateVmAndAppMonitoring {
+			return nil, fmt.Errorf("invalid vm monitoring value. it should be one of vmAndAppMonitoring, vmMonitoringOnly or vmMonitoringDisabled")
+		}
+		clusterSettings := false
+		monitoring.ClusterSettings = &clusterSettings
+		monitoring.VmMonitoring = string(vmMonitoring)
+	} else {
+		clusterSettings := true
+		monitoring.ClusterSettings = &clusterSettings
+	}
+
+	return &types.ClusterDasVmConfigInfo {
+		Key: vm.Reference(),
+		DasSettings: &types.ClusterDasVmSettings {
+			RestartPriority: string(restartPriority),
+			IsolationResponse: string(isolationResponse),
+			VmToolsMonitoringSettings: monitoring,
+		},
+	}, nil
+}
@@ -0,0 +1,155 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/kr/pretty"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestAccVsphereClusterVMOverride_normal(t *testing.T) {
+
+	datacenter := os.Getenv("VM_OVERRIDE_DATACENTER")
+	cluster := os.Getenv("VM_OVERRIDE_CLUSTER")
+	vm := os.Getenv("VM_OVERRIDE_VM")
+	if datacenter == "" || cluster == "" || vm == "" {
+		t.Skip("VM_OVERRIDE_DATACENTER, VM_OVERRIDE_CLUSTER and VM_OVERRIDE_VM must be set to a VM of a cluster for the cluster VM override acceptance test")
+	}
+
+	_, filename, _, _ := runtime.Caller(0)
+	ut := os.Getenv("UNIT_TEST")
+	if ut == "" || ut == filepath.Base(filename) {
+
+		resource.Test( t,
+			resource.TestCase {
+				PreCheck: func() { testAccPreCheck(t) },
+				Providers: testAccProviders,
+				CheckDestroy: testAccCheckClusterVMOverrideDestroy(datacenter, cluster, vm),
+				Steps: []resource.TestStep {
+					resource.TestStep {
+						Config: fmt.Sprintf(testAccClusterVMOverrideConfig, datacenter, cluster, vm, "true", "manual", "high", "clusterIsolationResponse"),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckClusterVMOverrideExists("vsphere_cluster_vm_override.o8"),
+							resource.TestCheckResourceAttr("vsphere_cluster_vm_override.o8", "drs_automation_level", "manual"),
+							resource.TestCheckResourceAttr("vsphere_cluster_vm_override.o8", "ha_restart_priority", "high"),
+						),
+					},
+					resource.TestStep {
+						Config: fmt.Sprintf(testAccClusterVMOverrideConfig, datacenter, cluster, vm, "false", "fullyAutomated", "low", "powerOff"),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckClusterVMOverrideExists("vsphere_cluster_vm_override.o8"),
+							resource.TestCheckResourceAttr("vsphere_cluster_vm_override.o8", "drs_enabled", "false"),
+							resource.TestCheckResourceAttr("vsphere_cluster_vm_override.o8", "ha_isolation_response", "powerOff"),
+						),
+					},
+				},
+			} )
+	}
+}
+
+func testAccCheckClusterVMOverrideExists(resource string) resource.TestCheckFunc {
+
+	return func(s *terraform.State) error {
+
+		rs, ok := s.RootModule().Resources[resource]
+		if !ok {
+			return fmt.Errorf("cluster VM override '%s' not found in terraform state", resource)
+		}
+
+		log.Printf("[DEBUG] Terraform cluster VM override: %# v", pretty.Formatter(rs))
+
+		attributes := rs.Primary.Attributes
+
+		drsVMConfig, dasVMConfig, err := findTestClusterVMConfig(attributes["datacenter_id"], attributes["cluster_id"], attributes["vm_name"])
+		if err != nil {
+			return err
+		}
+
+		if drsVMConfig == nil {
+			return fmt.Errorf("DRS override of VM '%s' not found in cluster '%s'", attributes["vm_name"], attributes["cluster_id"])
+		}
+		if strconv.FormatBool(drsVMConfig.Enabled == nil || *drsVMConfig.Enabled) != attributes["drs_enabled"] {
+			return fmt.Errorf("DRS override enabled flag mis-match")
+		}
+		if string(drsVMConfig.Behavior) != attributes["drs_automation_level"] {
+			return fmt.Errorf("DRS override automation level mis-match")
+		}
+
+		if dasVMConfig == nil || dasVMConfig.DasSettings == nil {
+			return fmt.Errorf("HA override of VM '%s' not found in cluster '%s'", attributes["vm_name"], attributes["cluster_id"])
+		}
+		if dasVMConfig.DasSettings.RestartPriority != attributes["ha_restart_priority"] {
+			return fmt.Errorf("HA override restart priority mis-match")
+		}
+		if dasVMConfig.DasSettings.IsolationResponse != attributes["ha_isolation_response"] {
+			return fmt.Errorf("HA override isolation response mis-match")
+		}
+
+		return nil
+	}
+}
+
+func testAccCheckClusterVMOverrideDestroy(datacenter, cluster, vm string) resource.TestCheckFunc {
+
+	return func(s *terraform.State) error {
+
+		drsVMConfig, dasVMConfig, err := findTestClusterVMConfig(datacenter, cluster, vm)
+		if err != nil {
+			return err
+		}
+		if drsVMConfig != nil || dasVMConfig != nil {
+			return fmt.Errorf("overrides of VM '%s' were not removed from cluster '%s' as expected", vm, cluster)
+		}
+		return nil
+	}
+}
+
+func findTestClusterVMConfig(datacenterName, clusterName, vmName string) (*types.ClusterDrsVmConfigInfo, *types.ClusterDasVmConfigInfo, error) {
+
+	finder, err := getTestFinder(datacenterName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cluster, err := finder.ClusterComputeResource(context.Background(), clusterName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vm, err := finder.VirtualMachine(context.Background(), vmName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	config, err := getConfigurationEx(context.Background(), cluster)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	drsVMConfig, dasVMConfig := getClusterVMConfig(config, vm)
+	return drsVMConfig, dasVMConfig, nil
+}
+
+const testAccClusterVMOverrideConfig = `
+
+resource "vsphere_cluster_vm_override" "o8" {
+	datacenter_id = "%s"
+	cluster_id = "%s"
+	vm_name = "%s"
+
+	drs_enabled = %s
+	drs_automation_level = "%s"
+	ha_restart_priority = "%s"
+	ha_isolation_response = "%s"
+}
+`
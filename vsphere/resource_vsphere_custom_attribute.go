@@ -0,0 +1,153 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func resourceVsphereCustomAttribute() *schema.Resource {
+
+	return &schema.Resource{
+
+		Create: resourceVsphereCustomAttributeCreate,
+		Read:   resourceVsphereCustomAttributeRead,
+		Update: resourceVsphereCustomAttributeUpdate,
+		Delete: resourceVsphereCustomAttributeDelete,
+
+		Schema: map[string]*schema.Schema{
+
+			"name": &schema.Schema{
+				Type: schema.TypeString,
+				Required: true,
+			},
+			"managed_object_type": &schema.Schema{
+				Type: schema.TypeString, // Such as VirtualMachine, HostSystem, ClusterComputeResource, ResourcePool or Datacenter. All types if not given.
+				Optional: true,
+				ForceNew: true,
+			},
+			"keep": &schema.Schema{
+				Type: schema.TypeBool,
+				Optional: true,
+			},
+		},
+	}
+}
+
+func resourceVsphereCustomAttributeCreate(d *schema.ResourceData, meta interface{}) error {
+
+	client := meta.(*govmomi.Client)
+	if client.Client.ServiceContent.CustomFieldsManager == nil {
+		return fmt.Errorf("custom attributes are only supported by vCenter")
+	}
+
+	name := d.Get("name").(string)
+
+	log.Printf("[DEBUG] Creating custom attribute: %s", name)
+
+	req := types.AddCustomFieldDef{
+		This: *client.Client.ServiceContent.CustomFieldsManager,
+		Name: name,
+		MoType: d.Get("managed_object_type").(string),
+	}
+	res, err := methods.AddCustomFieldDef(context.Background(), client.Client, &req)
+	if err != nil {
+		log.Printf("[ERROR] Unable to create custom attribute '%s'", name)
+		return err
+	}
+
+	d.SetId(strconv.Itoa(res.Returnval.Key))
+	return resourceVsphereCustomAttributeRead(d, meta)
+}
+
+func resourceVsphereCustomAttributeRead(d *schema.ResourceData, meta interface{}) error {
+
+	def, err := findCustomAttribute(d, meta)
+	if err != nil {
+		return err
+	}
+	if def == nil {
+		log.Printf("[DEBUG] Custom attribute '%s' no longer exists", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	d.Set("name", def.Name)
+	d.Set("managed_object_type", def.ManagedObjectType)
+	return nil
+}
+
+func resourceVsphereCustomAttributeUpdate(d *schema.ResourceData, meta interface{}) error {
+
+	if d.HasChange("name") {
+
+		client := meta.(*govmomi.Client)
+		key, _ := strconv.Atoi(d.Id())
+
+		log.Printf("[DEBUG] Renaming custom attribute '%s' to '%s'", d.Id(), d.Get("name").(string))
+
+		req := types.RenameCustomFieldDef{
+			This: *client.Client.ServiceContent.CustomFieldsManager,
+			Key: key,
+			Name: d.Get("name").(string),
+		}
+		_, err := methods.RenameCustomFieldDef(context.Background(), client.Client, &req)
+		if err != nil {
+			return err
+		}
+	}
+
+	return resourceVsphereCustomAttributeRead(d, meta)
+}
+
+func resourceVsphereCustomAttributeDelete(d *schema.ResourceData, meta interface{}) error {
+
+	if keep, ok := d.GetOk("keep"); !ok || !keep.(bool) {
+
+		def, err := findCustomAttribute(d, meta)
+		if err != nil || def == nil {
+			return err
+		}
+
+		client := meta.(*govmomi.Client)
+
+		log.Printf("[DEBUG] Deleting custom attribute: %s", def.Name)
+
+		req := types.RemoveCustomFieldDef{
+			This: *client.Client.ServiceContent.CustomFieldsManager,
+			Key: def.Key,
+		}
+		_, err = methods.RemoveCustomFieldDef(context.Background(), client.Client, &req)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func findCustomAttribute(d *schema.ResourceData, meta interface{}) (*types.CustomFieldDef, error) {
+
+	key, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return nil, fmt.Errorf("invalid custom attribute id '%s'", d.Id())
+	}
+
+	defs, err := getCustomFieldDefs(context.Background(), meta.(*govmomi.Client))
+	if err != nil {
+		return nil, err
+	}
+
+	for i, def := range defs {
+		if def.Key == key {
+			return &defs[i], nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,184 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/kr/pretty"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestAccVsphereCustomAttribute_normal(t *testing.T) {
+
+	_, filename, _, _ := runtime.Caller(0)
+	ut := os.Getenv("UNIT_TEST")
+	if ut == "" || ut == filepath.Base(filename) {
+
+		resource.Test( t,
+			resource.TestCase {
+				PreCheck: func() { testAccPreCheck(t) },
+				Providers: testAccProviders,
+				CheckDestroy: testAccCheckCustomAttributeDestroy,
+				Steps: []resource.TestStep {
+					resource.TestStep {
+						Config: fmt.Sprintf(testAccCustomAttributeConfig, "team-a"),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckCustomAttributeExists("vsphere_custom_attribute.ca1", "terraform-owner"),
+							resource.TestCheckResourceAttr("vsphere_datacenter.dc10", "custom_attributes.terraform-owner", "team-a"),
+						),
+					},
+					resource.TestStep {
+						Config: fmt.Sprintf(testAccCustomAttributeConfig, "team-b"),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckCustomAttributeExists("vsphere_custom_attribute.ca1", "terraform-owner"),
+							resource.TestCheckResourceAttr("vsphere_datacenter.dc10", "custom_attributes.terraform-owner", "team-b"),
+						),
+					},
+				},
+			} )
+	}
+}
+
+func testAccCheckCustomAttributeExists(resource, name string) resource.TestCheckFunc {
+
+	return func(s *terraform.State) error {
+
+		rs, ok := s.RootModule().Resources[resource]
+		if !ok {
+			return fmt.Errorf("custom attribute '%s' not found in terraform state", resource)
+		}
+
+		log.Printf("[DEBUG] Terraform custom attribute: %# v", pretty.Formatter(rs))
+
+		def, err := findTestCustomAttribute(rs.Primary.ID)
+		if err != nil {
+			return err
+		}
+		if def == nil {
+			return fmt.Errorf("custom attribute '%s' was not found in vCenter", rs.Primary.ID)
+		}
+		if def.Name != name {
+			return fmt.Errorf("custom attribute '%s' is named '%s' but expected '%s'", rs.Primary.ID, def.Name, name)
+		}
+		return nil
+	}
+}
+
+func testAccCheckCustomAttributeDestroy(s *terraform.State) error {
+
+	const ca1 = "vsphere_custom_attribute.ca1"
+
+	_, ok := s.RootModule().Resources[ca1]
+	if ok {
+		return fmt.Errorf("custom attribute '%s' still exists in the terraform state", ca1)
+	}
+
+	defs, err := getCustomFieldDefs(context.Background(), testAccProvider.Meta().(*govmomi.Client))
+	if err != nil {
+		return err
+	}
+	if findCustomFieldDef(defs, "terraform-owner", "Datacenter") != nil {
+		return fmt.Errorf("custom attribute '%s' was not destroyed as expected", ca1)
+	}
+	return nil
+}
+
+func findTestCustomAttribute(id string) (*types.CustomFieldDef, error) {
+
+	key, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, err
+	}
+
+	defs, err := getCustomFieldDefs(context.Background(), testAccProvider.Meta().(*govmomi.Client))
+	if err != nil {
+		return nil, err
+	}
+	for i, def := range defs {
+		if def.Key == key {
+			return &defs[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func TestCustomAttributeChanges(t *testing.T) {
+
+	defs := []types.CustomFieldDef{
+		types.CustomFieldDef{ Key: 1, Name: "owner", ManagedObjectType: "VirtualMachine" },
+		types.CustomFieldDef{ Key: 2, Name: "cost-center" },
+		types.CustomFieldDef{ Key: 3, Name: "expiry", ManagedObjectType: "VirtualMachine" },
+		types.CustomFieldDef{ Key: 4, Name: "owner", ManagedObjectType: "HostSystem" },
+	}
+
+	old := map[string]interface{}{ "owner": "a", "cost-center": "100", "expiry": "2016-01-01" }
+	new := map[string]interface{}{ "owner": "b", "cost-center": "100" }
+
+	changes, err := getCustomAttributeChanges(defs, "VirtualMachine", old, new)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if len(changes) != 2 || changes[1] != "b" || changes[3] != "" {
+		t.Fatalf("expected owner to be changed and expiry to be cleared but got: %v", changes)
+	}
+
+	changes, err = getCustomAttributeChanges(defs, "HostSystem", nil, new)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if len(changes) != 2 || changes[4] != "b" || changes[2] != "100" {
+		t.Fatalf("expected the host's owner and the global cost center to be set but got: %v", changes)
+	}
+
+	_, err = getCustomAttributeChanges(defs, "Datacenter", nil, map[string]interface{}{ "expiry": "2016-01-01" })
+	if err == nil {
+		t.Fatalf("expected an error for an attribute not defined for datacenters")
+	}
+}
+
+func TestCustomAttributeValues(t *testing.T) {
+
+	defs := []types.CustomFieldDef{
+		types.CustomFieldDef{ Key: 1, Name: "owner" },
+		types.CustomFieldDef{ Key: 2, Name: "cost-center" },
+		types.CustomFieldDef{ Key: 3, Name: "expiry" },
+	}
+	values := []types.BaseCustomFieldValue{
+		&types.CustomFieldStringValue{ CustomFieldValue: types.CustomFieldValue{ Key: 1 }, Value: "b" },
+		&types.CustomFieldStringValue{ CustomFieldValue: types.CustomFieldValue{ Key: 2 }, Value: "100" },
+	}
+	configured := map[string]interface{}{ "owner": "a", "expiry": "2016-01-01" }
+
+	attributes := getCustomAttributeValues(defs, values, configured)
+	if len(attributes) != 1 || attributes["owner"] != "b" {
+		t.Fatalf("expected only the configured owner attribute but got: %v", attributes)
+	}
+}
+
+const testAccCustomAttributeConfig = `
+
+resource "vsphere_custom_attribute" "ca1" {
+	name = "terraform-owner"
+	managed_object_type = "Datacenter"
+}
+
+resource "vsphere_datacenter" "dc10" {
+	depends_on = ["vsphere_custom_attribute.ca1"]
+
+	name = "datacenter10"
+
+	custom_attributes {
+		terraform-owner = "%s"
+	}
+}
+`
@@ -17,6 +17,7 @@ func resourceVsphereDatacenter() *schema.Resource {
 		
 		Create: resourceVsphereDatacenterCreate,
 		Read:   resourceVsphereDatacenterRead,
+		Update: resourceVsphereDatacenterUpdate,
 		Delete: resourceVsphereDatacenterDelete,
 
 		Schema: map[string]*schema.Schema{
@@ -26,6 +27,10 @@ func resourceVsphereDatacenter() *schema.Resource {
 				Required: true,
 				ForceNew: true,
 			},			
+			"custom_attributes": &schema.Schema{
+				Type: schema.TypeMap, // Values of custom attributes keyed by attribute name
+				Optional: true,
+			},
 			"keep": &schema.Schema{
 				Type: schema.TypeBool,
 				Optional: true,
@@ -62,7 +67,7 @@ func resourceVsphereDatacenterCreate(d *schema.ResourceData, meta interface{}) e
 	}
 	
 	d.SetId(d.Get("name").(string))
-	return resourceVsphereDatacenterRead(d, meta)
+	return resourceVsphereDatacenterUpdate(d, meta)
 }
 
 func resourceVsphereDatacenterRead(d *schema.ResourceData, meta interface{}) error {
@@ -73,10 +78,30 @@ func resourceVsphereDatacenterRead(d *schema.ResourceData, meta interface{}) err
 		return fmt.Errorf("datacenter '%s' not found", d.Id())
 	}
 	
+	err = readCustomAttributes(d, meta, datacenter.Reference())
+	if err != nil {
+		return err
+	}
+	
 	d.Set("object_id", datacenter.Reference().Value)
 	return nil
 }
 
+func resourceVsphereDatacenterUpdate(d *schema.ResourceData, meta interface{}) error {
+	
+	datacenter, err := findDatacenter(d, meta)
+	if err != nil {
+		return fmt.Errorf("datacenter '%s' not found", d.Id())
+	}
+	
+	err = updateCustomAttributes(d, meta, datacenter.Reference())
+	if err != nil {
+		return err
+	}
+	
+	return resourceVsphereDatacenterRead(d, meta)
+}
+
 func resourceVsphereDatacenterDelete(d *schema.ResourceData, meta interface{}) error {
 
 	if keep, ok := d.GetOk("keep"); !ok || !keep.(bool) {
@@ -92,7 +117,7 @@ func resourceVsphereDatacenterDelete(d *schema.ResourceData, meta interface{}) e
 		if err != nil {
 			return err
 		}
-	  	err = task.Wait(context.Background())
+	  	_, err = waitForTask(meta.(*govmomi.Client).Client, task, fmt.Sprintf("delete datacenter '%s'", d.Id()), defaultTaskTimeout)
 		if err != nil {
 			return err
 		}
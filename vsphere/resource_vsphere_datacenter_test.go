@@ -61,7 +61,7 @@ func testAccCheckDatacenterExists(resource string) resource.TestCheckFunc {
 		
 		client := testAccProvider.Meta().(*govmomi.Client)
 		if client == nil {
-			fmt.Errorf("client is nil")
+			return fmt.Errorf("client is nil")
 		}
 		
 		finder := find.NewFinder(client.Client, false)
@@ -91,7 +91,7 @@ func testAccCheckDatacenterDestroy(s *terraform.State) error {
 	
 	client := testAccProvider.Meta().(*govmomi.Client)
 	if client == nil {
-		fmt.Errorf("client is nil")
+		return fmt.Errorf("client is nil")
 	}
 	
 	finder := find.NewFinder(client.Client, false)
@@ -0,0 +1,583 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func resourceVsphereDatastoreCluster() *schema.Resource {
+
+	return &schema.Resource{
+
+		Create: resourceVsphereDatastoreClusterCreate,
+		Read:   resourceVsphereDatastoreClusterRead,
+		Update: resourceVsphereDatastoreClusterUpdate,
+		Delete: resourceVsphereDatastoreClusterDelete,
+
+		Schema: map[string]*schema.Schema{
+
+			"name": &schema.Schema{
+				Type: schema.TypeString,
+				Required: true,
+			},
+			"datacenter_id": &schema.Schema{
+				Type: schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"datastores": &schema.Schema{
+				Type: schema.TypeList, // Names or ids of the member datastores
+				Optional: true,
+				Elem: &schema.Schema{ Type: schema.TypeString },
+			},
+			"sdrs_enabled": &schema.Schema{
+				Type: schema.TypeBool,
+				Optional: true,
+				Default: true,
+			},
+			"sdrs_automation_level": &schema.Schema{
+				Type: schema.TypeString, // One of manual or automated
+				Optional: true,
+				Default: "manual",
+			},
+			"io_load_balance_enabled": &schema.Schema{
+				Type: schema.TypeBool,
+				Optional: true,
+				Default: true,
+			},
+			"load_balance_interval": &schema.Schema{
+				Type: schema.TypeInt, // Minutes between load balancing runs
+				Optional: true,
+				Default: 480,
+			},
+			"space_utilization_threshold": &schema.Schema{
+				Type: schema.TypeInt, // Percentage of used space above which datastores are balanced
+				Optional: true,
+				Default: 80,
+			},
+			"min_space_utilization_difference": &schema.Schema{
+				Type: schema.TypeInt, // Minimum percentage difference in used space between source and destination
+				Optional: true,
+				Default: 5,
+			},
+			"io_latency_threshold": &schema.Schema{
+				Type: schema.TypeInt, // Milliseconds of I/O latency above which datastores are balanced
+				Optional: true,
+				Default: 15,
+			},
+			"io_load_imbalance_threshold": &schema.Schema{
+				Type: schema.TypeInt, // Aggressiveness of I/O load balancing from 1 to 100
+				Optional: true,
+				Default: 5,
+			},
+			"default_intra_vm_affinity": &schema.Schema{
+				Type: schema.TypeBool, // Keep the disks of a VM on the same datastore
+				Optional: true,
+				Default: true,
+			},
+			"vm_anti_affinity_rule": &schema.Schema{
+				Type:     schema.TypeList, // VMs whose disks are kept on different datastores
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": &schema.Schema{
+							Type: schema.TypeString,
+							Required: true,
+						},
+						"virtual_machines": &schema.Schema{
+							Type: schema.TypeList,
+							Required: true,
+							Elem: &schema.Schema{ Type: schema.TypeString },
+						},
+						"enabled": &schema.Schema{
+							Type: schema.TypeBool,
+							Optional: true,
+							Default: true,
+						},
+					},
+				},
+			},
+			"keep": &schema.Schema{
+				Type: schema.TypeBool,
+				Optional: true,
+			},
+			"object_id": &schema.Schema{
+				Type: schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func resourceVsphereDatastoreClusterCreate(d *schema.ResourceData, meta interface{}) error {
+
+	client := meta.(*govmomi.Client)
+	name := d.Get("name").(string)
+
+	_, datacenter, err := getFinder(d, meta)
+	if err != nil {
+		log.Printf("[ERROR] Unable to create finder for operations on datastore cluster: '%s'", name)
+		return err
+	}
+
+	folders, err := datacenter.Folders(context.Background())
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[DEBUG] Creating datastore cluster: %s", name)
+
+	req := types.CreateStoragePod{
+		This: folders.DatastoreFolder.Reference(),
+		Name: name,
+	}
+	res, err := methods.CreateStoragePod(context.Background(), client.Client, &req)
+	if err != nil {
+		log.Printf("[ERROR] Unable to create datastore cluster '%s'", name)
+		return err
+	}
+
+	d.SetId(res.Returnval.Value)
+	d.Set("object_id", res.Returnval.Value)
+	return resourceVsphereDatastoreClusterUpdate(d, meta)
+}
+
+func resourceVsphereDatastoreClusterRead(d *schema.ResourceData, meta interface{}) error {
+
+	client := meta.(*govmomi.Client)
+
+	var pod mo.StoragePod
+
+	err := property.DefaultCollector(client.Client).RetrieveOne(context.Background(), datastoreClusterReference(d),
+		[]string{"name", "childEntity", "podStorageDrsEntry"}, &pod)
+	if err != nil {
+		if isManagedObjectNotFound(err) {
+			log.Printf("[DEBUG] Datastore cluster '%s' no longer exists", d.Id())
+			d.SetId("")
+			return nil
+		}
+		return err
+	}
+
+	d.Set("name", pod.Name)
+
+	names, err := getObjectNames(context.Background(), client.Client, pod.ChildEntity)
+	if err != nil {
+		log.Printf("[ERROR] Unable to read datastores of datastore cluster: '%s'", d.Id())
+		return err
+	}
+	d.Set("datastores", getDatastoreClusterMembers(pod.ChildEntity, names, d.Get("datastores").([]interface{})))
+
+	if pod.PodStorageDrsEntry != nil {
+
+		config := pod.PodStorageDrsEntry.StorageDrsConfig.PodConfig
+
+		d.Set("sdrs_enabled", config.Enabled)
+		d.Set("sdrs_automation_level", config.DefaultVmBehavior)
+		d.Set("io_load_balance_enabled", config.IoLoadBalanceEnabled)
+		d.Set("load_balance_interval", config.LoadBalanceInterval)
+		d.Set("default_intra_vm_affinity", config.DefaultIntraVmAffinity == nil || *config.DefaultIntraVmAffinity)
+
+		if config.SpaceLoadBalanceConfig != nil {
+			d.Set("space_utilization_threshold", config.SpaceLoadBalanceConfig.SpaceUtilizationThreshold)
+			d.Set("min_space_utilization_difference", config.SpaceLoadBalanceConfig.MinSpaceUtilizationDifference)
+		}
+		if config.IoLoadBalanceConfig != nil {
+			d.Set("io_latency_threshold", config.IoLoadBalanceConfig.IoLatencyThreshold)
+			d.Set("io_load_imbalance_threshold", config.IoLoadBalanceConfig.IoLoadImbalanceThreshold)
+		}
+
+		rules := make([]map[string]interface{}, 0)
+		for _, r := range config.Rule {
+			rule, ok := r.(*types.ClusterAntiAffinityRuleSpec)
+			if !ok {
+				continue
+			}
+			vms, err := getObjectNames(context.Background(), client.Client, rule.Vm)
+			if err != nil {
+				log.Printf("[ERROR] Unable to read VMs of rule '%s' of datastore cluster: '%s'", rule.Name, d.Id())
+				return err
+			}
+			rules = append(rules, map[string]interface{}{
+				"name": rule.Name,
+				"virtual_machines": vms,
+				"enabled": rule.Enabled == nil || *rule.Enabled,
+			})
+		}
+		d.Set("vm_anti_affinity_rule", rules)
+	}
+
+	d.Set("object_id", d.Id())
+	return nil
+}
+
+func resourceVsphereDatastoreClusterUpdate(d *schema.ResourceData, meta interface{}) error {
+
+	client := meta.(*govmomi.Client)
+	ref := datastoreClusterReference(d)
+
+	finder, datacenter, err := getFinder(d, meta)
+	if err != nil {
+		log.Printf("[ERROR] Unable to create finder for operations on datastore cluster: '%s'", d.Get("name").(string))
+		return err
+	}
+
+	var pod mo.StoragePod
+
+	err = property.DefaultCollector(client.Client).RetrieveOne(context.Background(), ref, []string{"name", "childEntity", "podStorageDrsEntry"}, &pod)
+	if err != nil {
+		return err
+	}
+
+	if name := d.Get("name").(string); name != pod.Name {
+		err = renameObject(context.Background(), client.Client, ref, name)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = updateDatastoreClusterMembers(d, meta, finder, datacenter, pod.ChildEntity)
+	if err != nil {
+		return err
+	}
+
+	var rules []types.BaseClusterRuleInfo
+	if pod.PodStorageDrsEntry != nil {
+		rules = pod.PodStorageDrsEntry.StorageDrsConfig.PodConfig.Rule
+	}
+
+	spec, err := getStorageDrsPodConfigSpec(d, finder, rules)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[DEBUG] Configuring storage DRS of datastore cluster: %s", d.Id())
+
+	sr := object.NewStorageResourceManager(client.Client)
+	task, err := sr.ConfigureStorageDrsForPod(context.Background(), &object.StoragePod{ Folder: object.NewFolder(client.Client, ref) },
+		types.StorageDrsConfigSpec{ PodConfigSpec: spec }, true)
+	if err != nil {
+		log.Printf("[ERROR] Unable to configure storage DRS of datastore cluster '%s'", d.Id())
+		return err
+	}
+	_, err = waitForTask(client.Client, task, fmt.Sprintf("configure storage DRS of datastore cluster '%s'", d.Id()), defaultTaskTimeout)
+	if err != nil {
+		return err
+	}
+
+	return resourceVsphereDatastoreClusterRead(d, meta)
+}
+
+func resourceVsphereDatastoreClusterDelete(d *schema.ResourceData, meta interface{}) error {
+
+	if keep, ok := d.GetOk("keep"); !ok || !keep.(bool) {
+
+		client := meta.(*govmomi.Client)
+		ref := datastoreClusterReference(d)
+
+		var pod mo.StoragePod
+
+		err := property.DefaultCollector(client.Client).RetrieveOne(context.Background(), ref, []string{"childEntity"}, &pod)
+		if err != nil {
+			if isManagedObjectNotFound(err) {
+				return nil
+			}
+			return err
+		}
+
+		_, datacenter, err := getFinder(d, meta)
+		if err != nil {
+			return err
+		}
+
+		// Datastores are moved out so that they outlive the datastore cluster
+		if len(pod.ChildEntity) > 0 {
+			err = moveIntoDatastoreFolder(context.Background(), client, datacenter, pod.ChildEntity)
+			if err != nil {
+				return err
+			}
+		}
+
+		log.Printf("[DEBUG] Deleting datastore cluster: %s", d.Id())
+
+		res, err := methods.Destroy_Task(context.Background(), client.Client, &types.Destroy_Task{ This: ref })
+		if err != nil {
+			return err
+		}
+		_, err = waitForTask(client.Client, object.NewTask(client.Client, res.Returnval), fmt.Sprintf("delete datastore cluster '%s'", d.Id()), defaultTaskTimeout)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func datastoreClusterReference(d *schema.ResourceData) types.ManagedObjectReference {
+	return types.ManagedObjectReference{ Type: "StoragePod", Value: d.Id() }
+}
+
+// Returns the datastore cluster with the given id or name or nil if no such
+// datastore cluster exists.
+func getDatastoreCluster(ctx context.Context, client *govmomi.Client, datacenter *object.Datacenter, id string) (*object.StoragePod, error) {
+
+	ref, err := findObjectReference(ctx, client.Client, "StoragePod", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if ref == nil {
+
+		folders, err := datacenter.Folders(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		child, err := object.NewSearchIndex(client.Client).FindChild(ctx, folders.DatastoreFolder, id)
+		if err != nil {
+			return nil, err
+		}
+		if child == nil || child.Reference().Type != "StoragePod" {
+			return nil, nil
+		}
+		r := child.Reference()
+		ref = &r
+	}
+
+	return &object.StoragePod{ Folder: object.NewFolder(client.Client, *ref) }, nil
+}
+
+// Returns the datastore with the given name. Datastores that are members of
+// a datastore cluster are found by their name too.
+func findDatastore(ctx context.Context, finder *find.Finder, name string) (*object.Datastore, error) {
+
+	datastore, err := finder.Datastore(ctx, name)
+	if _, ok := err.(*find.NotFoundError); ok {
+		if member, e := finder.Datastore(ctx, fmt.Sprintf("*/%s", name)); e == nil {
+			return member, nil
+		}
+	}
+	return datastore, err
+}
+
+// Moves datastores into or out of the datastore cluster so that its
+// members are the configured datastores.
+func updateDatastoreClusterMembers(d *schema.ResourceData, meta interface{}, finder *find.Finder, datacenter *object.Datacenter, current []types.ManagedObjectReference) error {
+
+	client := meta.(*govmomi.Client)
+
+	var configured []types.ManagedObjectReference
+	for _, v := range d.Get("datastores").([]interface{}) {
+
+		ref, err := findObjectReference(context.Background(), client.Client, "Datastore", v.(string))
+		if err != nil {
+			return err
+		}
+		if ref == nil {
+			datastore, err := findDatastore(context.Background(), finder, v.(string))
+			if err != nil {
+				return err
+			}
+			r := datastore.Reference()
+			ref = &r
+		}
+		configured = append(configured, *ref)
+	}
+
+	add, remove := getDatastoreClusterMemberChanges(current, configured)
+
+	if len(add) > 0 {
+
+		log.Printf("[DEBUG] Adding %d datastores to datastore cluster: %s", len(add), d.Id())
+
+		res, err := methods.MoveIntoFolder_Task(context.Background(), client.Client,
+			&types.MoveIntoFolder_Task{ This: datastoreClusterReference(d), List: add })
+		if err != nil {
+			return err
+		}
+		_, err = waitForTask(client.Client, object.NewTask(client.Client, res.Returnval), fmt.Sprintf("add datastores to datastore cluster '%s'", d.Id()), defaultTaskTimeout)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(remove) > 0 {
+
+		log.Printf("[DEBUG] Removing %d datastores from datastore cluster: %s", len(remove), d.Id())
+
+		err := moveIntoDatastoreFolder(context.Background(), client, datacenter, remove)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func moveIntoDatastoreFolder(ctx context.Context, client *govmomi.Client, datacenter *object.Datacenter, datastores []types.ManagedObjectReference) error {
+
+	folders, err := datacenter.Folders(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, err := methods.MoveIntoFolder_Task(ctx, client.Client,
+		&types.MoveIntoFolder_Task{ This: folders.DatastoreFolder.Reference(), List: datastores })
+	if err != nil {
+		return err
+	}
+	_, err = waitForTask(client.Client, object.NewTask(client.Client, res.Returnval), "move datastores out of datastore cluster", defaultTaskTimeout)
+	return err
+}
+
+// Returns the datastores to add to and remove from a datastore cluster.
+func getDatastoreClusterMemberChanges(current, configured []types.ManagedObjectReference) ([]types.ManagedObjectReference, []types.ManagedObjectReference) {
+
+	var add, remove []types.ManagedObjectReference
+
+	members := make(map[types.ManagedObjectReference]bool)
+	for _, ref := range current {
+		members[ref] = true
+	}
+	wanted := make(map[types.ManagedObjectReference]bool)
+	for _, ref := range configured {
+		if !members[ref] && !wanted[ref] {
+			add = append(add, ref)
+		}
+		wanted[ref] = true
+	}
+	for _, ref := range current {
+		if !wanted[ref] {
+			remove = append(remove, ref)
+		}
+	}
+	return add, remove
+}
+
+// Returns the members of a datastore cluster as they are stored in the
+// state. Configured members are kept as given by name or id and members
+// added outside of terraform are appended by name.
+func getDatastoreClusterMembers(members []types.ManagedObjectReference, names []interface{}, configured []interface{}) []interface{} {
+
+	found := make(map[int]bool)
+	datastores := []interface{}{}
+
+	for _, c := range configured {
+		for i, ref := range members {
+			if !found[i] && (ref.Value == c.(string) || names[i] == c) {
+				found[i] = true
+				datastores = append(datastores, c)
+				break
+			}
+		}
+	}
+	for i := range members {
+		if !found[i] {
+			datastores = append(datastores, names[i])
+		}
+	}
+	return datastores
+}
+
+func getStorageDrsPodConfigSpec(d *schema.ResourceData, finder *find.Finder, existing []types.BaseClusterRuleInfo) (*types.StorageDrsPodConfigSpec, error) {
+
+	enabled := d.Get("sdrs_enabled").(bool)
+	ioLoadBalanceEnabled := d.Get("io_load_balance_enabled").(bool)
+	defaultIntraVmAffinity := d.Get("default_intra_vm_affinity").(bool)
+
+	var rules []*types.ClusterAntiAffinityRuleSpec
+	for _, r := range d.Get("vm_anti_affinity_rule").([]interface{}) {
+
+		rule := r.(map[string]interface{})
+
+		var names []string
+		for _, vm := range rule["virtual_machines"].([]interface{}) {
+			names = append(names, vm.(string))
+		}
+		if len(names) < 2 {
+			return nil, fmt.Errorf("anti-affinity rule '%s' requires at least 2 virtual machines", rule["name"].(string))
+		}
+
+		vms, err := getVirtualMachineReferences(names, finder)
+		if err != nil {
+			return nil, err
+		}
+
+		ruleEnabled := rule["enabled"].(bool)
+		rules = append(rules, &types.ClusterAntiAffinityRuleSpec{
+			ClusterRuleInfo: types.ClusterRuleInfo{
+				Name: rule["name"].(string),
+				Enabled: &ruleEnabled,
+			},
+			Vm: vms,
+		})
+	}
+
+	return &types.StorageDrsPodConfigSpec{
+		Enabled: &enabled,
+		IoLoadBalanceEnabled: &ioLoadBalanceEnabled,
+		DefaultVmBehavior: d.Get("sdrs_automation_level").(string),
+		LoadBalanceInterval: d.Get("load_balance_interval").(int),
+		DefaultIntraVmAffinity: &defaultIntraVmAffinity,
+		SpaceLoadBalanceConfig: &types.StorageDrsSpaceLoadBalanceConfig{
+			SpaceUtilizationThreshold: d.Get("space_utilization_threshold").(int),
+			MinSpaceUtilizationDifference: d.Get("min_space_utilization_difference").(int),
+		},
+		IoLoadBalanceConfig: &types.StorageDrsIoLoadBalanceConfig{
+			IoLatencyThreshold: d.Get("io_latency_threshold").(int),
+			IoLoadImbalanceThreshold: d.Get("io_load_imbalance_threshold").(int),
+		},
+		Rule: getStorageDrsRuleSpecs(existing, rules),
+	}, nil
+}
+
+// Returns the changes that turn the existing VM anti-affinity rules of a
+// datastore cluster into the configured ones. Rules are matched by name.
+func getStorageDrsRuleSpecs(existing []types.BaseClusterRuleInfo, configured []*types.ClusterAntiAffinityRuleSpec) []types.ClusterRuleSpec {
+
+	var specs []types.ClusterRuleSpec
+
+	keys := make(map[string]int)
+	for _, r := range existing {
+		if rule, ok := r.(*types.ClusterAntiAffinityRuleSpec); ok {
+			keys[rule.Name] = rule.Key
+		}
+	}
+
+	wanted := make(map[string]bool)
+	for _, rule := range configured {
+
+		wanted[rule.Name] = true
+
+		operation := types.ArrayUpdateOperationAdd
+		if key, ok := keys[rule.Name]; ok {
+			operation = types.ArrayUpdateOperationEdit
+			rule.Key = key
+		}
+		specs = append(specs, types.ClusterRuleSpec{
+			ArrayUpdateSpec: types.ArrayUpdateSpec{ Operation: operation },
+			Info: rule,
+		})
+	}
+
+	for _, r := range existing {
+		if rule, ok := r.(*types.ClusterAntiAffinityRuleSpec); ok && !wanted[rule.Name] {
+			specs = append(specs, types.ClusterRuleSpec{
+				ArrayUpdateSpec: types.ArrayUpdateSpec{
+					Operation: types.ArrayUpdateOperationRemove,
+					RemoveKey: rule.Key,
+				},
+			})
+		}
+	}
+	return specs
+}
@@ -0,0 +1,188 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/kr/pretty"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestAccVsphereDatastoreCluster_normal(t *testing.T) {
+
+	_, filename, _, _ := runtime.Caller(0)
+	ut := os.Getenv("UNIT_TEST")
+	if ut == "" || ut == filepath.Base(filename) {
+
+		resource.Test( t,
+			resource.TestCase {
+				PreCheck: func() { testAccPreCheck(t) },
+				Providers: testAccProviders,
+				CheckDestroy: testAccCheckDatastoreClusterDestroy,
+				Steps: []resource.TestStep {
+					resource.TestStep {
+						Config: fmt.Sprintf( testAccDatastoreClusterConfig,
+							testEsxHost.IP,
+							testEsxHost.User,
+							testEsxHost.Password,
+							testEsxHost.License,
+							"manual",
+							80,
+						),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckDatastoreClusterExists("vsphere_datastore_cluster.dsc11", 1),
+							resource.TestCheckResourceAttr("vsphere_datastore_cluster.dsc11", "datastores.0", testFileDatastore),
+							resource.TestCheckResourceAttr("vsphere_datastore_cluster.dsc11", "sdrs_automation_level", "manual"),
+						),
+					},
+					resource.TestStep {
+						Config: fmt.Sprintf( testAccDatastoreClusterConfig,
+							testEsxHost.IP,
+							testEsxHost.User,
+							testEsxHost.Password,
+							testEsxHost.License,
+							"automated",
+							70,
+						),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckDatastoreClusterExists("vsphere_datastore_cluster.dsc11", 1),
+							resource.TestCheckResourceAttr("vsphere_datastore_cluster.dsc11", "sdrs_automation_level", "automated"),
+							resource.TestCheckResourceAttr("vsphere_datastore_cluster.dsc11", "space_utilization_threshold", "70"),
+						),
+					},
+				},
+			} )
+	}
+}
+
+func testAccCheckDatastoreClusterExists(resource string, members int) resource.TestCheckFunc {
+
+	return func(s *terraform.State) error {
+
+		rs, ok := s.RootModule().Resources[resource]
+		if !ok {
+			return fmt.Errorf("datastore cluster '%s' not found in terraform state", resource)
+		}
+
+		log.Printf("[DEBUG] Terraform datastore cluster: %# v", pretty.Formatter(rs))
+
+		client := testAccProvider.Meta().(*govmomi.Client)
+
+		var pod mo.StoragePod
+		ref := types.ManagedObjectReference{ Type: "StoragePod", Value: rs.Primary.ID }
+
+		err := property.DefaultCollector(client.Client).RetrieveOne(context.Background(), ref, []string{"name", "childEntity"}, &pod)
+		if err != nil {
+			return err
+		}
+		if pod.Name != rs.Primary.Attributes["name"] {
+			return fmt.Errorf("datastore cluster '%s' is named '%s' but expected '%s'", rs.Primary.ID, pod.Name, rs.Primary.Attributes["name"])
+		}
+		if len(pod.ChildEntity) != members {
+			return fmt.Errorf("datastore cluster '%s' has %d datastores but expected %d", rs.Primary.ID, len(pod.ChildEntity), members)
+		}
+		return nil
+	}
+}
+
+func testAccCheckDatastoreClusterDestroy(s *terraform.State) error {
+
+	const dsc11 = "vsphere_datastore_cluster.dsc11"
+
+	_, ok := s.RootModule().Resources[dsc11]
+	if ok {
+		return fmt.Errorf("datastore cluster '%s' still exists in the terraform state", dsc11)
+	}
+	return nil
+}
+
+func TestDatastoreClusterMembers(t *testing.T) {
+
+	ds1 := types.ManagedObjectReference{ Type: "Datastore", Value: "datastore-1" }
+	ds2 := types.ManagedObjectReference{ Type: "Datastore", Value: "datastore-2" }
+	ds3 := types.ManagedObjectReference{ Type: "Datastore", Value: "datastore-3" }
+
+	add, remove := getDatastoreClusterMemberChanges(
+		[]types.ManagedObjectReference{ ds1, ds2 },
+		[]types.ManagedObjectReference{ ds2, ds3, ds3 })
+	if len(add) != 1 || add[0] != ds3 || len(remove) != 1 || remove[0] != ds1 {
+		t.Fatalf("expected datastore-3 to be added and datastore-1 to be removed but got: %v %v", add, remove)
+	}
+
+	members := getDatastoreClusterMembers(
+		[]types.ManagedObjectReference{ ds1, ds2, ds3 },
+		[]interface{}{ "ds1", "ds2", "ds3" },
+		[]interface{}{ "datastore-3", "ds1" })
+	if len(members) != 3 || members[0] != "datastore-3" || members[1] != "ds1" || members[2] != "ds2" {
+		t.Fatalf("expected the configured members followed by the others by name but got: %v", members)
+	}
+}
+
+func TestStorageDrsRuleSpecs(t *testing.T) {
+
+	existing := []types.BaseClusterRuleInfo{
+		&types.ClusterAntiAffinityRuleSpec{ ClusterRuleInfo: types.ClusterRuleInfo{ Key: 1, Name: "db" } },
+		&types.ClusterAntiAffinityRuleSpec{ ClusterRuleInfo: types.ClusterRuleInfo{ Key: 2, Name: "web" } },
+	}
+	configured := []*types.ClusterAntiAffinityRuleSpec{
+		&types.ClusterAntiAffinityRuleSpec{ ClusterRuleInfo: types.ClusterRuleInfo{ Name: "web" } },
+		&types.ClusterAntiAffinityRuleSpec{ ClusterRuleInfo: types.ClusterRuleInfo{ Name: "cache" } },
+	}
+
+	specs := getStorageDrsRuleSpecs(existing, configured)
+	if len(specs) != 3 {
+		t.Fatalf("expected 3 rule changes but got: %# v", pretty.Formatter(specs))
+	}
+	if specs[0].Operation != types.ArrayUpdateOperationEdit || specs[0].Info.GetClusterRuleInfo().Key != 2 {
+		t.Fatalf("expected the web rule to be edited: %# v", pretty.Formatter(specs[0]))
+	}
+	if specs[1].Operation != types.ArrayUpdateOperationAdd || specs[1].Info.GetClusterRuleInfo().Name != "cache" {
+		t.Fatalf("expected the cache rule to be added: %# v", pretty.Formatter(specs[1]))
+	}
+	if specs[2].Operation != types.ArrayUpdateOperationRemove || specs[2].RemoveKey != 1 {
+		t.Fatalf("expected the db rule to be removed: %# v", pretty.Formatter(specs[2]))
+	}
+}
+
+const testAccDatastoreClusterConfig = `
+
+resource "vsphere_datacenter" "dc11" {
+	name = "datacenter11"
+
+#	keep = true
+}
+
+resource "vsphere_host" "h11" {
+	host = "%s"
+	datacenter_id = "${vsphere_datacenter.dc11.id}"
+
+	user = "%s"
+	password = "%s"
+	license = "%s"
+
+	ssl_no_verify = true
+#	keep = true
+}
+
+resource "vsphere_datastore_cluster" "dsc11" {
+	depends_on = ["vsphere_host.h11"]
+
+	name = "datastore-cluster11"
+	datacenter_id = "${vsphere_datacenter.dc11.id}"
+	datastores = [ "` + testFileDatastore + `" ]
+
+	sdrs_automation_level = "%s"
+	space_utilization_threshold = %d
+}
+`
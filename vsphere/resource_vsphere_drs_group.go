@@ -0,0 +1,256 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func resourceVsphereDrsGroup() *schema.Resource {
+
+	return &schema.Resource{
+
+		Create: resourceVsphereDrsGroupCreate,
+		Read:   resourceVsphereDrsGroupRead,
+		Update: resourceVsphereDrsGroupUpdate,
+		Delete: resourceVsphereDrsGroupDelete,
+
+		Schema: map[string]*schema.Schema{
+
+			"name": &schema.Schema{
+				Type: schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"datacenter_id": &schema.Schema{
+				Type: schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"cluster_id": &schema.Schema{
+				Type: schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"type": &schema.Schema{
+				Type: schema.TypeString, // One of vm or host
+				Required: true,
+				ForceNew: true,
+			},
+			"members": &schema.Schema{
+				Type: schema.TypeList, // Names of the virtual machines or hosts in the group
+				Optional: true,
+				Elem: &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+func resourceVsphereDrsGroupCreate(d *schema.ResourceData, meta interface{}) error {
+
+	groupInfo, err := getClusterGroupInfo(d, meta)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[DEBUG] Creating DRS %s group '%s' in cluster '%s'", d.Get("type").(string), d.Get("name").(string), d.Get("cluster_id").(string))
+
+	err = reconfigureClusterGroup(d, meta, types.ClusterGroupSpec {
+			ArrayUpdateSpec: types.ArrayUpdateSpec {
+				Operation: types.ArrayUpdateOperationAdd,
+			},
+			Info: groupInfo,
+		} )
+	if err != nil {
+		log.Printf("[ERROR] Unable to create DRS group '%s'", d.Get("name").(string))
+		return err
+	}
+
+	d.SetId(d.Get("name").(string))
+	return resourceVsphereDrsGroupRead(d, meta)
+}
+
+func resourceVsphereDrsGroupRead(d *schema.ResourceData, meta interface{}) error {
+
+	finder, _, err := getFinder(d, meta)
+	if err != nil {
+		log.Printf("[ERROR] Unable to create finder for operations on DRS group: '%s'", d.Id())
+		return err
+	}
+
+	cluster, err := getCluster(d.Get("cluster_id").(string), finder, meta.(*govmomi.Client))
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	config, err := getConfigurationEx(context.Background(), cluster)
+	if err != nil {
+		log.Printf("[ERROR] Unable read configuration of cluster: '%s'", d.Get("cluster_id").(string))
+		return err
+	}
+
+	client := meta.(*govmomi.Client).Client
+
+	for _, g := range config.Group {
+
+		if g.GetClusterGroupInfo().Name != d.Id() {
+			continue
+		}
+
+		var members []interface{}
+
+		switch group := g.(type) {
+			case *types.ClusterVmGroup:
+				d.Set("type", "vm")
+				members, err = getObjectNames(context.Background(), client, group.Vm)
+			case *types.ClusterHostGroup:
+				d.Set("type", "host")
+				members, err = getObjectNames(context.Background(), client, group.Host)
+			default:
+				return fmt.Errorf("DRS group '%s' has an unsupported type '%T'", d.Id(), g)
+		}
+		if err != nil {
+			log.Printf("[ERROR] Unable to read members of DRS group: '%s'", d.Id())
+			return err
+		}
+
+		d.Set("members", members)
+		return nil
+	}
+
+	log.Printf("[DEBUG] DRS group '%s' no longer exists in cluster '%s'", d.Id(), d.Get("cluster_id").(string))
+	d.SetId("")
+	return nil
+}
+
+func resourceVsphereDrsGroupUpdate(d *schema.ResourceData, meta interface{}) error {
+
+	groupInfo, err := getClusterGroupInfo(d, meta)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[DEBUG] Updating DRS group: %s", d.Id())
+
+	err = reconfigureClusterGroup(d, meta, types.ClusterGroupSpec {
+			ArrayUpdateSpec: types.ArrayUpdateSpec {
+				Operation: types.ArrayUpdateOperationEdit,
+			},
+			Info: groupInfo,
+		} )
+	if err != nil {
+		log.Printf("[ERROR] Unable to update DRS group '%s'", d.Id())
+		return err
+	}
+
+	return resourceVsphereDrsGroupRead(d, meta)
+}
+
+func resourceVsphereDrsGroupDelete(d *schema.ResourceData, meta interface{}) error {
+
+	log.Printf("[DEBUG] Deleting DRS group: %s", d.Id())
+
+	return reconfigureClusterGroup(d, meta, types.ClusterGroupSpec {
+			ArrayUpdateSpec: types.ArrayUpdateSpec {
+				Operation: types.ArrayUpdateOperationRemove,
+				RemoveKey: d.Id(),
+			},
+		} )
+}
+
+func reconfigureClusterGroup(d *schema.ResourceData, meta interface{}, groupSpec types.ClusterGroupSpec) error {
+
+	finder, _, err := getFinder(d, meta)
+	if err != nil {
+		log.Printf("[ERROR] Unable to create finder for operations on DRS group: '%s'", d.Get("name").(string))
+		return err
+	}
+
+	cluster, err := getCluster(d.Get("cluster_id").(string), finder, meta.(*govmomi.Client))
+	if err != nil {
+		return err
+	}
+
+	spec := types.ClusterConfigSpecEx {
+		GroupSpec: []types.ClusterGroupSpec{ groupSpec },
+	}
+	return reconfigureComputeResource(context.Background(), meta.(*govmomi.Client), cluster, spec)
+}
+
+func getClusterGroupInfo(d *schema.ResourceData, meta interface{}) (types.BaseClusterGroupInfo, error) {
+
+	finder, _, err := getFinder(d, meta)
+	if err != nil {
+		log.Printf("[ERROR] Unable to create finder for operations on DRS group: '%s'", d.Get("name").(string))
+		return nil, err
+	}
+
+	name := d.Get("name").(string)
+
+	var members []string
+	for _, v := range d.Get("members").([]interface{}) {
+		members = append(members, v.(string))
+	}
+
+	switch d.Get("type").(string) {
+		case "vm":
+			vms, err := getVirtualMachineReferences(members, finder)
+			if err != nil {
+				return nil, err
+			}
+			return &types.ClusterVmGroup {
+				ClusterGroupInfo: types.ClusterGroupInfo{ Name: name },
+				Vm: vms,
+			}, nil
+		case "host":
+			hosts, err := getHostReferences(members, finder)
+			if err != nil {
+				return nil, err
+			}
+			return &types.ClusterHostGroup {
+				ClusterGroupInfo: types.ClusterGroupInfo{ Name: name },
+				Host: hosts,
+			}, nil
+	}
+
+	return nil, fmt.Errorf("invalid DRS group type '%s'. it should be one of vm or host", d.Get("type").(string))
+}
+
+func getVirtualMachineReferences(names []string, finder *find.Finder) ([]types.ManagedObjectReference, error) {
+
+	refs := []types.ManagedObjectReference{}
+
+	for _, name := range names {
+		vm, err := finder.VirtualMachine(context.Background(), name)
+		if err != nil {
+			log.Printf("[ERROR] Unable find virtual machine: '%s'", name)
+			return nil, err
+		}
+		refs = append(refs, vm.Reference())
+	}
+
+	return refs, nil
+}
+
+func getHostReferences(names []string, finder *find.Finder) ([]types.ManagedObjectReference, error) {
+
+	refs := []types.ManagedObjectReference{}
+
+	for _, name := range names {
+		host, err := finder.HostSystem(context.Background(), fmt.Sprintf("*/%s", name))
+		if err != nil {
+			log.Printf("[ERROR] Unable find host: '%s'", name)
+			return nil, err
+		}
+		refs = append(refs, host.Reference())
+	}
+
+	return refs, nil
+}
@@ -0,0 +1,176 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/kr/pretty"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestAccVsphereDrsGroup_normal(t *testing.T) {
+	
+	_, filename, _, _ := runtime.Caller(0)
+	ut := os.Getenv("UNIT_TEST")
+	if ut == "" || ut == filepath.Base(filename) {
+		
+		resource.Test( t, 
+			resource.TestCase {
+				PreCheck: func() { testAccPreCheck(t) },
+				Providers: testAccProviders,
+				CheckDestroy: testAccCheckDrsGroupDestroy,
+				Steps: []resource.TestStep {
+					resource.TestStep {
+						Config: fmt.Sprintf( testAccDrsGroupConfig, 
+							testEsxHost.IP,
+							testEsxHost.User,
+							testEsxHost.Password,
+							testEsxHost.License,
+							testEsxHost.IP,
+						),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckDrsGroupExists("vsphere_drs_group.hg5"),
+							resource.TestCheckResourceAttr(
+								"vsphere_drs_group.hg5", "type", "host"),
+							resource.TestCheckResourceAttr(
+								"vsphere_drs_group.hg5", "members.#", "1"),
+							resource.TestCheckResourceAttr(
+								"vsphere_drs_group.hg5", "members.0", testEsxHost.IP),
+							
+							testAccCheckDrsGroupExists("vsphere_drs_group.vg5"),
+							resource.TestCheckResourceAttr(
+								"vsphere_drs_group.vg5", "type", "vm"),
+							resource.TestCheckResourceAttr(
+								"vsphere_drs_group.vg5", "members.#", "0"),
+						),
+					},
+				},
+			} )
+	}
+}
+
+func testAccCheckDrsGroupExists(resource string) resource.TestCheckFunc {
+	
+	return func(s *terraform.State) error {
+		
+		rs, ok := s.RootModule().Resources[resource]
+		if !ok {
+			return fmt.Errorf("DRS group '%s' not found in terraform state", resource)
+		}
+		
+		log.Printf("[DEBUG] Terraform DRS group: %# v", pretty.Formatter(rs))
+		
+		attributes := rs.Primary.Attributes
+		
+		group, err := findTestDrsGroup(attributes["datacenter_id"], attributes["cluster_id"], rs.Primary.ID)
+		if err != nil {
+			return err
+		}
+		if group == nil {
+			return fmt.Errorf("DRS group '%s' not found in cluster '%s'", rs.Primary.ID, attributes["cluster_id"])
+		}
+		
+		switch group.(type) {
+			case *types.ClusterVmGroup:
+				if attributes["type"] != "vm" {
+					return fmt.Errorf("DRS group '%s' type mis-match. expected 'vm' but got '%s'", rs.Primary.ID, attributes["type"])
+				}
+			case *types.ClusterHostGroup:
+				if attributes["type"] != "host" {
+					return fmt.Errorf("DRS group '%s' type mis-match. expected 'host' but got '%s'", rs.Primary.ID, attributes["type"])
+				}
+		}
+		
+		return nil
+	}
+}
+
+func testAccCheckDrsGroupDestroy(s *terraform.State) error {
+	
+	const datacenter5 = "datacenter5"
+	const cluster5 = "cluster5"
+	
+	for _, name := range []string{"host_group5", "vm_group5"} {
+		
+		group, err := findTestDrsGroup(datacenter5, cluster5, name)
+		if err != nil {
+			log.Printf("[DEBUG] Cluster '%s' of DRS group '%s' destroyed as expected. API response was: %s", cluster5, name, err.Error())
+		} else if group != nil {
+			return fmt.Errorf("DRS group '%s' was not destroyed as expected", name)
+		}
+	}
+	
+	return nil
+}
+
+func findTestDrsGroup(datacenterName, clusterName, groupName string) (types.BaseClusterGroupInfo, error) {
+	
+	cluster, err := findTestCluster(datacenterName, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	
+	config, err := getConfigurationEx(context.Background(), cluster)
+	if err != nil {
+		return nil, err
+	}
+	
+	for _, g := range config.Group {
+		if g.GetClusterGroupInfo().Name == groupName {
+			return g, nil
+		}
+	}
+	
+	return nil, nil
+}
+
+const testAccDrsGroupConfig = `
+
+resource "vsphere_datacenter" "dc5" {
+	name = "datacenter5"
+}
+
+resource "vsphere_cluster" "c5" {
+	name = "cluster5"
+	datacenter_id = "${vsphere_datacenter.dc5.id}"
+  
+	drs {}
+}
+
+resource "vsphere_host" "h5" {
+	host = "%s"
+	datacenter_id = "${vsphere_datacenter.dc5.id}"
+	cluster_id = "${vsphere_cluster.c5.id}"
+	
+	user = "%s"
+	password = "%s"
+	license = "%s"
+	
+	ssl_no_verify = true
+}
+
+resource "vsphere_drs_group" "hg5" {
+	depends_on = ["vsphere_host.h5"]
+
+	name = "host_group5"
+	datacenter_id = "${vsphere_datacenter.dc5.id}"
+	cluster_id = "${vsphere_cluster.c5.id}"
+	type = "host"
+	members = ["%s"]
+}
+
+resource "vsphere_drs_group" "vg5" {
+	name = "vm_group5"
+	datacenter_id = "${vsphere_datacenter.dc5.id}"
+	cluster_id = "${vsphere_cluster.c5.id}"
+	type = "vm"
+}
+`
@@ -0,0 +1,194 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func resourceVsphereDrsVMHostRule() *schema.Resource {
+
+	return &schema.Resource{
+
+		Create: resourceVsphereDrsVMHostRuleCreate,
+		Read:   resourceVsphereDrsVMHostRuleRead,
+		Update: resourceVsphereDrsVMHostRuleUpdate,
+		Delete: resourceVsphereDrsRuleDelete,
+
+		Schema: map[string]*schema.Schema{
+
+			"name": &schema.Schema{
+				Type: schema.TypeString,
+				Required: true,
+			},
+			"datacenter_id": &schema.Schema{
+				Type: schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"cluster_id": &schema.Schema{
+				Type: schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"vm_group": &schema.Schema{
+				Type: schema.TypeString,
+				Required: true,
+			},
+			"host_group": &schema.Schema{
+				Type: schema.TypeString,
+				Required: true,
+			},
+			"policy": &schema.Schema{
+				Type: schema.TypeString, // One of mustRunOn, shouldRunOn, mustNotRunOn or shouldNotRunOn
+				Required: true,
+			},
+			"enabled": &schema.Schema{
+				Type: schema.TypeBool,
+				Optional: true,
+				Default: true,
+			},
+			"key": &schema.Schema{
+				Type: schema.TypeInt,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func resourceVsphereDrsVMHostRuleCreate(d *schema.ResourceData, meta interface{}) error {
+
+	ruleInfo, err := getClusterVMHostRuleInfo(d)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[DEBUG] Creating DRS vm/host rule '%s' in cluster '%s'", d.Get("name").(string), d.Get("cluster_id").(string))
+
+	err = reconfigureClusterRule(d, meta, types.ClusterRuleSpec {
+			ArrayUpdateSpec: types.ArrayUpdateSpec {
+				Operation: types.ArrayUpdateOperationAdd,
+			},
+			Info: ruleInfo,
+		} )
+	if err != nil {
+		log.Printf("[ERROR] Unable to create DRS rule '%s'", d.Get("name").(string))
+		return err
+	}
+
+	rule, err := findClusterRule(d, meta)
+	if err != nil {
+		return err
+	}
+	if rule == nil {
+		return fmt.Errorf("DRS rule '%s' was not found after it was created", d.Get("name").(string))
+	}
+
+	d.SetId(strconv.Itoa(rule.GetClusterRuleInfo().Key))
+	return resourceVsphereDrsVMHostRuleRead(d, meta)
+}
+
+func resourceVsphereDrsVMHostRuleRead(d *schema.ResourceData, meta interface{}) error {
+
+	rule, err := findClusterRule(d, meta)
+	if err != nil {
+		return err
+	}
+	if rule == nil {
+		d.SetId("")
+		return nil
+	}
+
+	vmHostRule, ok := rule.(*types.ClusterVmHostRuleInfo)
+	if !ok {
+		return fmt.Errorf("DRS rule '%s' is not a vm/host rule", d.Id())
+	}
+
+	mandatory := vmHostRule.Mandatory != nil && *vmHostRule.Mandatory
+
+	var policy string
+	if vmHostRule.AffineHostGroupName != "" {
+		d.Set("host_group", vmHostRule.AffineHostGroupName)
+		if mandatory {
+			policy = "mustRunOn"
+		} else {
+			policy = "shouldRunOn"
+		}
+	} else {
+		d.Set("host_group", vmHostRule.AntiAffineHostGroupName)
+		if mandatory {
+			policy = "mustNotRunOn"
+		} else {
+			policy = "shouldNotRunOn"
+		}
+	}
+
+	d.SetId(strconv.Itoa(vmHostRule.Key))
+	d.Set("name", vmHostRule.Name)
+	d.Set("vm_group", vmHostRule.VmGroupName)
+	d.Set("policy", policy)
+	d.Set("enabled", vmHostRule.Enabled != nil && *vmHostRule.Enabled)
+	d.Set("key", vmHostRule.Key)
+	return nil
+}
+
+func resourceVsphereDrsVMHostRuleUpdate(d *schema.ResourceData, meta interface{}) error {
+
+	ruleInfo, err := getClusterVMHostRuleInfo(d)
+	if err != nil {
+		return err
+	}
+	ruleInfo.Key = d.Get("key").(int)
+
+	log.Printf("[DEBUG] Updating DRS rule: %s", d.Id())
+
+	err = reconfigureClusterRule(d, meta, types.ClusterRuleSpec {
+			ArrayUpdateSpec: types.ArrayUpdateSpec {
+				Operation: types.ArrayUpdateOperationEdit,
+			},
+			Info: ruleInfo,
+		} )
+	if err != nil {
+		log.Printf("[ERROR] Unable to update DRS rule '%s'", d.Id())
+		return err
+	}
+
+	return resourceVsphereDrsVMHostRuleRead(d, meta)
+}
+
+func getClusterVMHostRuleInfo(d *schema.ResourceData) (*types.ClusterVmHostRuleInfo, error) {
+
+	enabled := d.Get("enabled").(bool)
+
+	ruleInfo := &types.ClusterVmHostRuleInfo {
+		ClusterRuleInfo: types.ClusterRuleInfo {
+			Name: d.Get("name").(string),
+			Enabled: &enabled,
+		},
+		VmGroupName: d.Get("vm_group").(string),
+	}
+
+	var mandatory bool
+
+	hostGroup := d.Get("host_group").(string)
+	switch d.Get("policy").(string) {
+		case "mustRunOn":
+			mandatory = true
+			ruleInfo.AffineHostGroupName = hostGroup
+		case "shouldRunOn":
+			ruleInfo.AffineHostGroupName = hostGroup
+		case "mustNotRunOn":
+			mandatory = true
+			ruleInfo.AntiAffineHostGroupName = hostGroup
+		case "shouldNotRunOn":
+			ruleInfo.AntiAffineHostGroupName = hostGroup
+		default:
+			return nil, fmt.Errorf("invalid DRS vm/host rule policy '%s'. it should be one of mustRunOn, shouldRunOn, mustNotRunOn or shouldNotRunOn", d.Get("policy").(string))
+	}
+	ruleInfo.Mandatory = &mandatory
+
+	return ruleInfo, nil
+}
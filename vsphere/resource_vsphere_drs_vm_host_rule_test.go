@@ -0,0 +1,209 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/kr/pretty"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestAccVsphereDrsVMHostRule_normal(t *testing.T) {
+	
+	_, filename, _, _ := runtime.Caller(0)
+	ut := os.Getenv("UNIT_TEST")
+	if ut == "" || ut == filepath.Base(filename) {
+		
+		resource.Test( t, 
+			resource.TestCase {
+				PreCheck: func() { testAccPreCheck(t) },
+				Providers: testAccProviders,
+				CheckDestroy: testAccCheckDrsVMHostRuleDestroy,
+				Steps: []resource.TestStep {
+					resource.TestStep {
+						Config: fmt.Sprintf( testAccDrsVMHostRuleConfig, 
+							testEsxHost.IP,
+							testEsxHost.User,
+							testEsxHost.Password,
+							testEsxHost.License,
+							testEsxHost.IP,
+							"rule6",
+						),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckDrsVMHostRuleExists("vsphere_drs_vm_host_rule.r6"),
+							resource.TestCheckResourceAttr(
+								"vsphere_drs_vm_host_rule.r6", "policy", "shouldRunOn"),
+							resource.TestCheckResourceAttr(
+								"vsphere_drs_vm_host_rule.r6", "enabled", "true"),
+						),
+					},
+					resource.TestStep {
+						Config: fmt.Sprintf( testAccDrsVMHostRuleConfig, 
+							testEsxHost.IP,
+							testEsxHost.User,
+							testEsxHost.Password,
+							testEsxHost.License,
+							testEsxHost.IP,
+							"rule6-renamed",
+						),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckDrsVMHostRuleExists("vsphere_drs_vm_host_rule.r6"),
+							resource.TestCheckResourceAttr(
+								"vsphere_drs_vm_host_rule.r6", "name", "rule6-renamed"),
+						),
+					},
+					resource.TestStep {
+						Config: fmt.Sprintf( testAccDrsVMHostRuleConfig, 
+							testEsxHost.IP,
+							testEsxHost.User,
+							testEsxHost.Password,
+							testEsxHost.License,
+							testEsxHost.IP,
+							"rule6",
+						),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckDrsVMHostRuleExists("vsphere_drs_vm_host_rule.r6"),
+							resource.TestCheckResourceAttr(
+								"vsphere_drs_vm_host_rule.r6", "name", "rule6"),
+						),
+					},
+				},
+			} )
+	}
+}
+
+func testAccCheckDrsVMHostRuleExists(resource string) resource.TestCheckFunc {
+	
+	return func(s *terraform.State) error {
+		
+		rs, ok := s.RootModule().Resources[resource]
+		if !ok {
+			return fmt.Errorf("DRS rule '%s' not found in terraform state", resource)
+		}
+		
+		log.Printf("[DEBUG] Terraform DRS rule: %# v", pretty.Formatter(rs))
+		
+		attributes := rs.Primary.Attributes
+		
+		rule, err := findTestDrsRuleByKey(attributes["datacenter_id"], attributes["cluster_id"], rs.Primary.ID)
+		if err != nil {
+			return err
+		}
+		if rule == nil {
+			return fmt.Errorf("DRS rule '%s' not found in cluster '%s'", rs.Primary.ID, attributes["cluster_id"])
+		}
+		
+		vmHostRule, ok := rule.(*types.ClusterVmHostRuleInfo)
+		if !ok {
+			return fmt.Errorf("DRS rule '%s' is not a vm/host rule", rs.Primary.ID)
+		}
+		if vmHostRule.Name != attributes["name"] {
+			return fmt.Errorf("DRS rule name mis-match")
+		}
+		if vmHostRule.VmGroupName != attributes["vm_group"] {
+			return fmt.Errorf("DRS rule vm group mis-match")
+		}
+		if vmHostRule.AffineHostGroupName != attributes["host_group"] {
+			return fmt.Errorf("DRS rule host group mis-match")
+		}
+		
+		return nil
+	}
+}
+
+func testAccCheckDrsVMHostRuleDestroy(s *terraform.State) error {
+	
+	const datacenter6 = "datacenter6"
+	const cluster6 = "cluster6"
+	const rule6 = "rule6"
+	
+	rule, err := findTestDrsRule(datacenter6, cluster6, rule6)
+	if err != nil {
+		log.Printf("[DEBUG] Cluster '%s' of DRS rule '%s' destroyed as expected. API response was: %s", cluster6, rule6, err.Error())
+	} else if rule != nil {
+		return fmt.Errorf("DRS rule '%s' was not destroyed as expected", rule6)
+	}
+	
+	return nil
+}
+
+func findTestDrsRule(datacenterName, clusterName, ruleName string) (types.BaseClusterRuleInfo, error) {
+	
+	cluster, err := findTestCluster(datacenterName, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	
+	config, err := getConfigurationEx(context.Background(), cluster)
+	if err != nil {
+		return nil, err
+	}
+	
+	for _, r := range config.Rule {
+		if r.GetClusterRuleInfo().Name == ruleName {
+			return r, nil
+		}
+	}
+	
+	return nil, nil
+}
+
+const testAccDrsVMHostRuleConfig = `
+
+resource "vsphere_datacenter" "dc6" {
+	name = "datacenter6"
+}
+
+resource "vsphere_cluster" "c6" {
+	name = "cluster6"
+	datacenter_id = "${vsphere_datacenter.dc6.id}"
+  
+	drs {}
+}
+
+resource "vsphere_host" "h6" {
+	host = "%s"
+	datacenter_id = "${vsphere_datacenter.dc6.id}"
+	cluster_id = "${vsphere_cluster.c6.id}"
+	
+	user = "%s"
+	password = "%s"
+	license = "%s"
+	
+	ssl_no_verify = true
+}
+
+resource "vsphere_drs_group" "hg6" {
+	depends_on = ["vsphere_host.h6"]
+
+	name = "host_group6"
+	datacenter_id = "${vsphere_datacenter.dc6.id}"
+	cluster_id = "${vsphere_cluster.c6.id}"
+	type = "host"
+	members = ["%s"]
+}
+
+resource "vsphere_drs_group" "vg6" {
+	name = "vm_group6"
+	datacenter_id = "${vsphere_datacenter.dc6.id}"
+	cluster_id = "${vsphere_cluster.c6.id}"
+	type = "vm"
+}
+
+resource "vsphere_drs_vm_host_rule" "r6" {
+	name = "%s"
+	datacenter_id = "${vsphere_datacenter.dc6.id}"
+	cluster_id = "${vsphere_cluster.c6.id}"
+	vm_group = "${vsphere_drs_group.vg6.id}"
+	host_group = "${vsphere_drs_group.hg6.id}"
+	policy = "shouldRunOn"
+}
+`
@@ -0,0 +1,293 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func resourceVsphereDrsVMRule() *schema.Resource {
+
+	return &schema.Resource{
+
+		Create: resourceVsphereDrsVMRuleCreate,
+		Read:   resourceVsphereDrsVMRuleRead,
+		Update: resourceVsphereDrsVMRuleUpdate,
+		Delete: resourceVsphereDrsRuleDelete,
+
+		Schema: map[string]*schema.Schema{
+
+			"name": &schema.Schema{
+				Type: schema.TypeString,
+				Required: true,
+			},
+			"datacenter_id": &schema.Schema{
+				Type: schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"cluster_id": &schema.Schema{
+				Type: schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"type": &schema.Schema{
+				Type: schema.TypeString, // One of affinity or antiAffinity
+				Required: true,
+				ForceNew: true,
+			},
+			"virtual_machines": &schema.Schema{
+				Type: schema.TypeList,
+				Required: true,
+				Elem: &schema.Schema{Type: schema.TypeString},
+			},
+			"enabled": &schema.Schema{
+				Type: schema.TypeBool,
+				Optional: true,
+				Default: true,
+			},
+			"mandatory": &schema.Schema{
+				Type: schema.TypeBool,
+				Optional: true,
+				Default: false,
+			},
+			"key": &schema.Schema{
+				Type: schema.TypeInt,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func resourceVsphereDrsVMRuleCreate(d *schema.ResourceData, meta interface{}) error {
+
+	ruleInfo, err := getClusterVMRuleInfo(d, meta)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[DEBUG] Creating DRS %s rule '%s' in cluster '%s'", d.Get("type").(string), d.Get("name").(string), d.Get("cluster_id").(string))
+
+	err = reconfigureClusterRule(d, meta, types.ClusterRuleSpec {
+			ArrayUpdateSpec: types.ArrayUpdateSpec {
+				Operation: types.ArrayUpdateOperationAdd,
+			},
+			Info: ruleInfo,
+		} )
+	if err != nil {
+		log.Printf("[ERROR] Unable to create DRS rule '%s'", d.Get("name").(string))
+		return err
+	}
+
+	// Rules are identified by their key so that renames show as changes
+	rule, err := findClusterRule(d, meta)
+	if err != nil {
+		return err
+	}
+	if rule == nil {
+		return fmt.Errorf("DRS rule '%s' was not found after it was created", d.Get("name").(string))
+	}
+
+	d.SetId(strconv.Itoa(rule.GetClusterRuleInfo().Key))
+	return resourceVsphereDrsVMRuleRead(d, meta)
+}
+
+func resourceVsphereDrsVMRuleRead(d *schema.ResourceData, meta interface{}) error {
+
+	rule, err := findClusterRule(d, meta)
+	if err != nil {
+		return err
+	}
+	if rule == nil {
+		d.SetId("")
+		return nil
+	}
+
+	client := meta.(*govmomi.Client).Client
+
+	var vms []interface{}
+
+	switch r := rule.(type) {
+		case *types.ClusterAffinityRuleSpec:
+			d.Set("type", "affinity")
+			vms, err = getObjectNames(context.Background(), client, r.Vm)
+		case *types.ClusterAntiAffinityRuleSpec:
+			d.Set("type", "antiAffinity")
+			vms, err = getObjectNames(context.Background(), client, r.Vm)
+		default:
+			return fmt.Errorf("DRS rule '%s' is not a virtual machine affinity or anti-affinity rule", d.Id())
+	}
+	if err != nil {
+		log.Printf("[ERROR] Unable to read virtual machines of DRS rule: '%s'", d.Id())
+		return err
+	}
+
+	ruleInfo := rule.GetClusterRuleInfo()
+	d.SetId(strconv.Itoa(ruleInfo.Key))
+	d.Set("name", ruleInfo.Name)
+	d.Set("virtual_machines", vms)
+	d.Set("enabled", ruleInfo.Enabled != nil && *ruleInfo.Enabled)
+	d.Set("mandatory", ruleInfo.Mandatory != nil && *ruleInfo.Mandatory)
+	d.Set("key", ruleInfo.Key)
+	return nil
+}
+
+func resourceVsphereDrsVMRuleUpdate(d *schema.ResourceData, meta interface{}) error {
+
+	ruleInfo, err := getClusterVMRuleInfo(d, meta)
+	if err != nil {
+		return err
+	}
+	ruleInfo.GetClusterRuleInfo().Key = d.Get("key").(int)
+
+	log.Printf("[DEBUG] Updating DRS rule: %s", d.Id())
+
+	err = reconfigureClusterRule(d, meta, types.ClusterRuleSpec {
+			ArrayUpdateSpec: types.ArrayUpdateSpec {
+				Operation: types.ArrayUpdateOperationEdit,
+			},
+			Info: ruleInfo,
+		} )
+	if err != nil {
+		log.Printf("[ERROR] Unable to update DRS rule '%s'", d.Id())
+		return err
+	}
+
+	return resourceVsphereDrsVMRuleRead(d, meta)
+}
+
+func resourceVsphereDrsRuleDelete(d *schema.ResourceData, meta interface{}) error {
+
+	rule, err := findClusterRule(d, meta)
+	if err != nil {
+		return err
+	}
+	if rule == nil {
+		log.Printf("[DEBUG] DRS rule '%s' has already been removed", d.Id())
+		return nil
+	}
+
+	log.Printf("[DEBUG] Deleting DRS rule: %s", d.Id())
+
+	return reconfigureClusterRule(d, meta, types.ClusterRuleSpec {
+			ArrayUpdateSpec: types.ArrayUpdateSpec {
+				Operation: types.ArrayUpdateOperationRemove,
+				RemoveKey: int32(rule.GetClusterRuleInfo().Key),
+			},
+		} )
+}
+
+func getClusterVMRuleInfo(d *schema.ResourceData, meta interface{}) (types.BaseClusterRuleInfo, error) {
+
+	finder, _, err := getFinder(d, meta)
+	if err != nil {
+		log.Printf("[ERROR] Unable to create finder for operations on DRS rule: '%s'", d.Get("name").(string))
+		return nil, err
+	}
+
+	var names []string
+	for _, v := range d.Get("virtual_machines").([]interface{}) {
+		names = append(names, v.(string))
+	}
+	if len(names) < 2 {
+		return nil, fmt.Errorf("DRS rule '%s' requires at least 2 virtual machines", d.Get("name").(string))
+	}
+
+	vms, err := getVirtualMachineReferences(names, finder)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled := d.Get("enabled").(bool)
+	mandatory := d.Get("mandatory").(bool)
+
+	ruleInfo := types.ClusterRuleInfo {
+		Name: d.Get("name").(string),
+		Enabled: &enabled,
+		Mandatory: &mandatory,
+	}
+
+	switch d.Get("type").(string) {
+		case "affinity":
+			return &types.ClusterAffinityRuleSpec {
+				ClusterRuleInfo: ruleInfo,
+				Vm: vms,
+			}, nil
+		case "antiAffinity":
+			return &types.ClusterAntiAffinityRuleSpec {
+				ClusterRuleInfo: ruleInfo,
+				Vm: vms,
+			}, nil
+	}
+
+	return nil, fmt.Errorf("invalid DRS rule type '%s'. it should be one of affinity or antiAffinity", d.Get("type").(string))
+}
+
+// Finds the cluster rule by its key, given as the id or the key attribute,
+// and falls back to the rule's name if the key is not known or no longer
+// exists.
+func findClusterRule(d *schema.ResourceData, meta interface{}) (types.BaseClusterRuleInfo, error) {
+
+	finder, _, err := getFinder(d, meta)
+	if err != nil {
+		log.Printf("[ERROR] Unable to create finder for operations on DRS rule: '%s'", d.Id())
+		return nil, err
+	}
+
+	cluster, err := getCluster(d.Get("cluster_id").(string), finder, meta.(*govmomi.Client))
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := getConfigurationEx(context.Background(), cluster)
+	if err != nil {
+		log.Printf("[ERROR] Unable read configuration of cluster: '%s'", d.Get("cluster_id").(string))
+		return nil, err
+	}
+
+	key, err := strconv.Atoi(d.Id())
+	if err != nil {
+		key = d.Get("key").(int)
+	}
+	if key != 0 {
+		for _, r := range config.Rule {
+			if r.GetClusterRuleInfo().Key == key {
+				return r, nil
+			}
+		}
+	}
+	for _, r := range config.Rule {
+		if r.GetClusterRuleInfo().Name == d.Get("name").(string) {
+			return r, nil
+		}
+	}
+
+	log.Printf("[DEBUG] DRS rule '%s' no longer exists in cluster '%s'", d.Id(), d.Get("cluster_id").(string))
+	return nil, nil
+}
+
+func reconfigureClusterRule(d *schema.ResourceData, meta interface{}, ruleSpec types.ClusterRuleSpec) error {
+
+	finder, _, err := getFinder(d, meta)
+	if err != nil {
+		log.Printf("[ERROR] Unable to create finder for operations on DRS rule: '%s'", d.Get("name").(string))
+		return err
+	}
+
+	cluster, err := getCluster(d.Get("cluster_id").(string), finder, meta.(*govmomi.Client))
+	if err != nil {
+		return err
+	}
+
+	spec := types.ClusterConfigSpecEx {
+		RulesSpec: []types.ClusterRuleSpec{ ruleSpec },
+	}
+	return reconfigureComputeResource(context.Background(), meta.(*govmomi.Client), cluster, spec)
+}
+
@@ -0,0 +1,182 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/kr/pretty"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestAccVsphereDrsVMRule_normal(t *testing.T) {
+
+	datacenter := os.Getenv("DRS_RULE_DATACENTER")
+	cluster := os.Getenv("DRS_RULE_CLUSTER")
+	vms := strings.Split(os.Getenv("DRS_RULE_VMS"), ",")
+	if datacenter == "" || cluster == "" || len(vms) < 2 {
+		t.Skip("DRS_RULE_DATACENTER, DRS_RULE_CLUSTER and DRS_RULE_VMS with at least 2 comma separated VMs of the cluster must be set for the DRS VM rule acceptance test")
+	}
+	members := fmt.Sprintf(`"%s"`, strings.Join(vms, `", "`))
+
+	_, filename, _, _ := runtime.Caller(0)
+	ut := os.Getenv("UNIT_TEST")
+	if ut == "" || ut == filepath.Base(filename) {
+
+		resource.Test( t,
+			resource.TestCase {
+				PreCheck: func() { testAccPreCheck(t) },
+				Providers: testAccProviders,
+				CheckDestroy: testAccCheckDrsVMRuleDestroy(datacenter, cluster),
+				Steps: []resource.TestStep {
+					resource.TestStep {
+						Config: fmt.Sprintf(testAccDrsVMRuleConfig, "rule7", datacenter, cluster, "antiAffinity", members, "true"),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckDrsVMRuleExists("vsphere_drs_vm_rule.r7", vms),
+							resource.TestCheckResourceAttr("vsphere_drs_vm_rule.r7", "type", "antiAffinity"),
+							resource.TestCheckResourceAttr("vsphere_drs_vm_rule.r7", "enabled", "true"),
+						),
+					},
+					resource.TestStep {
+						Config: fmt.Sprintf(testAccDrsVMRuleConfig, "rule7-renamed", datacenter, cluster, "antiAffinity", members, "false"),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckDrsVMRuleExists("vsphere_drs_vm_rule.r7", vms),
+							resource.TestCheckResourceAttr("vsphere_drs_vm_rule.r7", "name", "rule7-renamed"),
+							resource.TestCheckResourceAttr("vsphere_drs_vm_rule.r7", "enabled", "false"),
+						),
+					},
+					resource.TestStep {
+						Config: fmt.Sprintf(testAccDrsVMRuleConfig, "rule7", datacenter, cluster, "affinity", members, "true"),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckDrsVMRuleExists("vsphere_drs_vm_rule.r7", vms),
+							resource.TestCheckResourceAttr("vsphere_drs_vm_rule.r7", "type", "affinity"),
+						),
+					},
+				},
+			} )
+	}
+}
+
+func testAccCheckDrsVMRuleExists(resource string, vms []string) resource.TestCheckFunc {
+
+	return func(s *terraform.State) error {
+
+		rs, ok := s.RootModule().Resources[resource]
+		if !ok {
+			return fmt.Errorf("DRS rule '%s' not found in terraform state", resource)
+		}
+
+		log.Printf("[DEBUG] Terraform DRS rule: %# v", pretty.Formatter(rs))
+
+		attributes := rs.Primary.Attributes
+
+		rule, err := findTestDrsRuleByKey(attributes["datacenter_id"], attributes["cluster_id"], rs.Primary.ID)
+		if err != nil {
+			return err
+		}
+		if rule == nil {
+			return fmt.Errorf("DRS rule '%s' not found in cluster '%s'", rs.Primary.ID, attributes["cluster_id"])
+		}
+
+		var (
+			ruleType string
+			refs []types.ManagedObjectReference
+		)
+		switch r := rule.(type) {
+			case *types.ClusterAffinityRuleSpec:
+				ruleType, refs = "affinity", r.Vm
+			case *types.ClusterAntiAffinityRuleSpec:
+				ruleType, refs = "antiAffinity", r.Vm
+			default:
+				return fmt.Errorf("DRS rule '%s' is not a virtual machine affinity or anti-affinity rule", rs.Primary.ID)
+		}
+
+		ruleInfo := rule.GetClusterRuleInfo()
+		if ruleInfo.Name != attributes["name"] {
+			return fmt.Errorf("DRS rule name mis-match")
+		}
+		if ruleType != attributes["type"] {
+			return fmt.Errorf("DRS rule type mis-match")
+		}
+		if strconv.FormatBool(ruleInfo.Enabled != nil && *ruleInfo.Enabled) != attributes["enabled"] {
+			return fmt.Errorf("DRS rule enabled flag mis-match")
+		}
+
+		client := testAccProvider.Meta().(*govmomi.Client)
+
+		names, err := getObjectNames(context.Background(), client.Client, refs)
+		if err != nil {
+			return err
+		}
+		if len(names) != len(vms) {
+			return fmt.Errorf("DRS rule has VMs %v but expected %v", names, vms)
+		}
+		for i, name := range names {
+			if name.(string) != vms[i] {
+				return fmt.Errorf("DRS rule has VMs %v but expected %v", names, vms)
+			}
+		}
+
+		return nil
+	}
+}
+
+func testAccCheckDrsVMRuleDestroy(datacenter, cluster string) resource.TestCheckFunc {
+
+	return func(s *terraform.State) error {
+
+		const rule7 = "rule7"
+
+		rule, err := findTestDrsRule(datacenter, cluster, rule7)
+		if err != nil {
+			return err
+		}
+		if rule != nil {
+			return fmt.Errorf("DRS rule '%s' was not destroyed as expected", rule7)
+		}
+		return nil
+	}
+}
+
+func findTestDrsRuleByKey(datacenterName, clusterName, key string) (types.BaseClusterRuleInfo, error) {
+
+	cluster, err := findTestCluster(datacenterName, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := getConfigurationEx(context.Background(), cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range config.Rule {
+		if strconv.Itoa(r.GetClusterRuleInfo().Key) == key {
+			return r, nil
+		}
+	}
+
+	return nil, nil
+}
+
+const testAccDrsVMRuleConfig = `
+
+resource "vsphere_drs_vm_rule" "r7" {
+	name = "%s"
+	datacenter_id = "%s"
+	cluster_id = "%s"
+	type = "%s"
+	virtual_machines = [ %s ]
+	enabled = %s
+}
+`
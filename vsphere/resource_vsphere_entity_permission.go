@@ -0,0 +1,239 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func resourceVsphereEntityPermission() *schema.Resource {
+
+	return &schema.Resource{
+
+		Create: resourceVsphereEntityPermissionCreate,
+		Read:   resourceVsphereEntityPermissionRead,
+		Update: resourceVsphereEntityPermissionUpdate,
+		Delete: resourceVsphereEntityPermissionDelete,
+
+		Schema: map[string]*schema.Schema{
+
+			"entity_id": &schema.Schema{
+				Type: schema.TypeString, // object_id of a datacenter, cluster, host, resource pool, vApp or other managed entity
+				Required: true,
+				ForceNew: true,
+			},
+			"principal": &schema.Schema{
+				Type: schema.TypeString, // User or group such as DOMAIN\name
+				Required: true,
+				ForceNew: true,
+			},
+			"group": &schema.Schema{
+				Type: schema.TypeBool,
+				Optional: true,
+				Default: false,
+				ForceNew: true,
+			},
+			"role_id": &schema.Schema{
+				Type: schema.TypeString, // Id of a vsphere_role or the id or name of a system role such as ReadOnly
+				Required: true,
+			},
+			"propagate": &schema.Schema{
+				Type: schema.TypeBool, // Apply the permission to the children of the entity
+				Optional: true,
+				Default: true,
+			},
+			"keep": &schema.Schema{
+				Type: schema.TypeBool,
+				Optional: true,
+			},
+		},
+	}
+}
+
+func resourceVsphereEntityPermissionCreate(d *schema.ResourceData, meta interface{}) error {
+
+	err := setEntityPermission(d, meta)
+	if err != nil {
+		return err
+	}
+
+	d.SetId(fmt.Sprintf("%s:%s", d.Get("entity_id").(string), d.Get("principal").(string)))
+	return resourceVsphereEntityPermissionRead(d, meta)
+}
+
+func resourceVsphereEntityPermissionRead(d *schema.ResourceData, meta interface{}) error {
+
+	client := meta.(*govmomi.Client)
+
+	entity, err := findPermissionEntity(d, meta)
+	if err != nil {
+		return err
+	}
+	if entity == nil {
+		log.Printf("[DEBUG] Entity of permission '%s' no longer exists", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	req := types.RetrieveEntityPermissions{
+		This: *client.Client.ServiceContent.AuthorizationManager,
+		Entity: *entity,
+		Inherited: false,
+	}
+	res, err := methods.RetrieveEntityPermissions(context.Background(), client.Client, &req)
+	if err != nil {
+		return err
+	}
+
+	permission := findEntityPermission(res.Returnval, d.Get("principal").(string), d.Get("group").(bool))
+	if permission == nil {
+		log.Printf("[DEBUG] Permission '%s' no longer exists", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	// A role given by name is kept as long as it still refers to the granted role
+	roles, err := getAuthorizationRoles(context.Background(), client)
+	if err != nil {
+		log.Printf("[ERROR] Unable to read roles of permission '%s'", d.Id())
+		return err
+	}
+	if roleID, ok := findPermissionRoleID(roles, d.Get("role_id").(string)); !ok || roleID != permission.RoleId {
+		d.Set("role_id", strconv.Itoa(permission.RoleId))
+	}
+	d.Set("propagate", permission.Propagate)
+	return nil
+}
+
+func resourceVsphereEntityPermissionUpdate(d *schema.ResourceData, meta interface{}) error {
+
+	if d.HasChange("role_id") || d.HasChange("propagate") {
+
+		err := setEntityPermission(d, meta)
+		if err != nil {
+			return err
+		}
+	}
+
+	return resourceVsphereEntityPermissionRead(d, meta)
+}
+
+func resourceVsphereEntityPermissionDelete(d *schema.ResourceData, meta interface{}) error {
+
+	if keep, ok := d.GetOk("keep"); !ok || !keep.(bool) {
+
+		client := meta.(*govmomi.Client)
+
+		entity, err := findPermissionEntity(d, meta)
+		if err != nil || entity == nil {
+			return err
+		}
+
+		log.Printf("[DEBUG] Deleting permission: %s", d.Id())
+
+		req := types.RemoveEntityPermission{
+			This: *client.Client.ServiceContent.AuthorizationManager,
+			Entity: *entity,
+			User: d.Get("principal").(string),
+			IsGroup: d.Get("group").(bool),
+		}
+		_, err = methods.RemoveEntityPermission(context.Background(), client.Client, &req)
+		if err != nil && !isNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Grants the role to the principal on the entity replacing any permission
+// the principal already has on it.
+func setEntityPermission(d *schema.ResourceData, meta interface{}) error {
+
+	client := meta.(*govmomi.Client)
+
+	entity, err := findPermissionEntity(d, meta)
+	if err != nil {
+		return err
+	}
+	if entity == nil {
+		return fmt.Errorf("entity '%s' was not found", d.Get("entity_id").(string))
+	}
+
+	roleID, err := getPermissionRoleID(context.Background(), client, d.Get("role_id").(string))
+	if err != nil {
+		return err
+	}
+
+	principal := d.Get("principal").(string)
+
+	log.Printf("[DEBUG] Granting role %d to '%s' on '%s'", roleID, principal, entity.Value)
+
+	req := types.SetEntityPermissions{
+		This: *client.Client.ServiceContent.AuthorizationManager,
+		Entity: *entity,
+		Permission: []types.Permission{
+			types.Permission{
+				Principal: principal,
+				Group: d.Get("group").(bool),
+				RoleId: roleID,
+				Propagate: d.Get("propagate").(bool),
+			},
+		},
+	}
+	_, err = methods.SetEntityPermissions(context.Background(), client.Client, &req)
+	if err != nil {
+		log.Printf("[ERROR] Unable to grant role %d to '%s' on '%s'", roleID, principal, entity.Value)
+	}
+	return err
+}
+
+func findPermissionEntity(d *schema.ResourceData, meta interface{}) (*types.ManagedObjectReference, error) {
+	return findEntityReference(context.Background(), meta.(*govmomi.Client).Client, d.Get("entity_id").(string))
+}
+
+// Returns the id of the role with the given id or name.
+func getPermissionRoleID(ctx context.Context, client *govmomi.Client, role string) (int, error) {
+
+	roles, err := getAuthorizationRoles(ctx, client)
+	if err != nil {
+		return 0, err
+	}
+
+	id, ok := findPermissionRoleID(roles, role)
+	if !ok {
+		return 0, fmt.Errorf("role '%s' was not found", role)
+	}
+	return id, nil
+}
+
+// Returns the id of a role given by id or name.
+func findPermissionRoleID(roles []types.AuthorizationRole, role string) (int, bool) {
+
+	id, err := strconv.Atoi(role)
+	for _, r := range roles {
+		if (err == nil && r.RoleId == id) || r.Name == role {
+			return r.RoleId, true
+		}
+	}
+	return 0, false
+}
+
+// Finds the permission of a user or group. vCenter may change the case of
+// the principal's domain so it is compared case insensitively.
+func findEntityPermission(permissions []types.Permission, principal string, group bool) *types.Permission {
+
+	for i, p := range permissions {
+		if strings.EqualFold(p.Principal, principal) && p.Group == group {
+			return &permissions[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/kr/pretty"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestAccVsphereEntityPermission_normal(t *testing.T) {
+
+	principal := os.Getenv("PERMISSION_PRINCIPAL")
+	if principal == "" {
+		t.Skip("PERMISSION_PRINCIPAL must be set to a vCenter user for the entity permission acceptance test")
+	}
+
+	_, filename, _, _ := runtime.Caller(0)
+	ut := os.Getenv("UNIT_TEST")
+	if ut == "" || ut == filepath.Base(filename) {
+
+		resource.Test( t,
+			resource.TestCase {
+				PreCheck: func() { testAccPreCheck(t) },
+				Providers: testAccProviders,
+				CheckDestroy: testAccCheckEntityPermissionDestroy,
+				Steps: []resource.TestStep {
+					resource.TestStep {
+						Config: fmt.Sprintf(testAccEntityPermissionConfig, principal, "${vsphere_role.role20.id}", "true"),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckEntityPermissionExists("vsphere_entity_permission.p1", true),
+						),
+					},
+					resource.TestStep {
+						Config: fmt.Sprintf(testAccEntityPermissionConfig, principal, "ReadOnly", "false"),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckEntityPermissionExists("vsphere_entity_permission.p1", false),
+							resource.TestCheckResourceAttr("vsphere_entity_permission.p1", "role_id", "ReadOnly"),
+						),
+					},
+				},
+			} )
+	}
+}
+
+func testAccCheckEntityPermissionExists(resource string, propagate bool) resource.TestCheckFunc {
+
+	return func(s *terraform.State) error {
+
+		rs, ok := s.RootModule().Resources[resource]
+		if !ok {
+			return fmt.Errorf("permission '%s' not found in terraform state", resource)
+		}
+
+		log.Printf("[DEBUG] Terraform permission: %# v", pretty.Formatter(rs))
+
+		attributes := rs.Primary.Attributes
+
+		permission, err := findTestEntityPermission(attributes["entity_id"], attributes["principal"])
+		if err != nil {
+			return err
+		}
+		if permission == nil {
+			return fmt.Errorf("permission '%s' was not found in vCenter", rs.Primary.ID)
+		}
+		if permission.Propagate != propagate {
+			return fmt.Errorf("permission '%s' has propagate %t but expected %t", rs.Primary.ID, permission.Propagate, propagate)
+		}
+
+		client := testAccProvider.Meta().(*govmomi.Client)
+		roleID, err := getPermissionRoleID(context.Background(), client, attributes["role_id"])
+		if err != nil {
+			return err
+		}
+		if roleID != permission.RoleId {
+			return fmt.Errorf("permission '%s' grants role %d but expected %d", rs.Primary.ID, permission.RoleId, roleID)
+		}
+		return nil
+	}
+}
+
+func testAccCheckEntityPermissionDestroy(s *terraform.State) error {
+
+	const p1 = "vsphere_entity_permission.p1"
+
+	_, ok := s.RootModule().Resources[p1]
+	if ok {
+		return fmt.Errorf("permission '%s' still exists in the terraform state", p1)
+	}
+	return nil
+}
+
+func findTestEntityPermission(entityID, principal string) (*types.Permission, error) {
+
+	client := testAccProvider.Meta().(*govmomi.Client)
+
+	entity, err := findEntityReference(context.Background(), client.Client, entityID)
+	if err != nil || entity == nil {
+		return nil, err
+	}
+
+	req := types.RetrieveEntityPermissions{
+		This: *client.Client.ServiceContent.AuthorizationManager,
+		Entity: *entity,
+	}
+	res, err := methods.RetrieveEntityPermissions(context.Background(), client.Client, &req)
+	if err != nil {
+		return nil, err
+	}
+	return findEntityPermission(res.Returnval, principal, false), nil
+}
+
+func TestFindEntityPermission(t *testing.T) {
+
+	permissions := []types.Permission{
+		types.Permission{ Principal: "VSPHERE.LOCAL\\ops", Group: true, RoleId: 1 },
+		types.Permission{ Principal: "VSPHERE.LOCAL\\ops", Group: false, RoleId: 2 },
+	}
+
+	permission := findEntityPermission(permissions, "VSPHERE.LOCAL\\ops", false)
+	if permission == nil || permission.RoleId != 2 {
+		t.Fatalf("expected the user permission but got: %v", permission)
+	}
+	permission = findEntityPermission(permissions, "vsphere.local\\ops", true)
+	if permission == nil || permission.RoleId != 1 {
+		t.Fatalf("expected the group permission regardless of the domain's case but got: %v", permission)
+	}
+	permission = findEntityPermission(permissions, "VSPHERE.LOCAL\\dev", true)
+	if permission != nil {
+		t.Fatalf("expected no permission but got: %v", permission)
+	}
+}
+
+const testAccEntityPermissionConfig = `
+
+resource "vsphere_datacenter" "dc20" {
+	name = "datacenter20"
+}
+
+resource "vsphere_role" "role20" {
+	name = "terraform-permission-test"
+	privileges = [ "VirtualMachine.Interact.PowerOn" ]
+}
+
+resource "vsphere_entity_permission" "p1" {
+	entity_id = "${vsphere_datacenter.dc20.object_id}"
+	principal = "%s"
+	role_id = "%s"
+	propagate = %s
+}
+`
@@ -0,0 +1,281 @@
+package vsphere
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/object"
+)
+
+func resourceVsphereFile() *schema.Resource {
+
+	return &schema.Resource{
+
+		Create: resourceVsphereFileCreate,
+		Read:   resourceVsphereFileRead,
+		Update: resourceVsphereFileUpdate,
+		Delete: resourceVsphereFileDelete,
+
+		Schema: map[string]*schema.Schema{
+
+			"datacenter_id": &schema.Schema{
+				Type: schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"source_datastore": &schema.Schema{
+				Type: schema.TypeString, // Datastore of the source file when copying between datastores
+				Optional: true,
+				ForceNew: true,
+			},
+			"source_file": &schema.Schema{
+				Type: schema.TypeString, // Local path or path in the source datastore
+				Required: true,
+			},
+			"datastore": &schema.Schema{
+				Type: schema.TypeString,
+				Required: true,
+			},
+			"destination_file": &schema.Schema{
+				Type: schema.TypeString, // Path in the datastore
+				Required: true,
+			},
+			"source_hash": &schema.Schema{
+				Type: schema.TypeString, // SHA-256 hash of the local source file last uploaded
+				Computed: true,
+			},
+			"keep": &schema.Schema{
+				Type: schema.TypeBool,
+				Optional: true,
+			},
+		},
+	}
+}
+
+func resourceVsphereFileCreate(d *schema.ResourceData, meta interface{}) error {
+
+	datastore, datacenter, err := getFileDatastore(d, meta, d.Get("datastore").(string))
+	if err != nil {
+		return err
+	}
+
+	err = copyFileToDatastore(d, meta, datacenter, datastore)
+	if err != nil {
+		return err
+	}
+
+	d.SetId(datastore.Path(d.Get("destination_file").(string)))
+	return resourceVsphereFileRead(d, meta)
+}
+
+func resourceVsphereFileRead(d *schema.ResourceData, meta interface{}) error {
+
+	datastore, _, err := getFileDatastore(d, meta, d.Get("datastore").(string))
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	exists, err := datastoreFileExists(context.Background(), meta.(*govmomi.Client), datastore, d.Get("destination_file").(string))
+	if err != nil {
+		return err
+	}
+	if !exists {
+		log.Printf("[DEBUG] File '%s' no longer exists", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	if _, ok := d.GetOk("source_datastore"); !ok {
+
+		// An uploaded file whose local source has changed since it was
+		// uploaded is treated as gone so that the next apply uploads it again
+		hash, err := getFileHash(d.Get("source_file").(string))
+		if err != nil {
+			log.Printf("[DEBUG] Unable to read local file '%s': %s", d.Get("source_file").(string), err.Error())
+		} else if isFileSourceChanged(hash, d.Get("source_hash").(string)) {
+			log.Printf("[DEBUG] Local file '%s' has changed since it was uploaded to '%s'", d.Get("source_file").(string), d.Id())
+			d.SetId("")
+		}
+	}
+
+	return nil
+}
+
+func resourceVsphereFileUpdate(d *schema.ResourceData, meta interface{}) error {
+
+	datastore, datacenter, err := getFileDatastore(d, meta, d.Get("datastore").(string))
+	if err != nil {
+		return err
+	}
+
+	client := meta.(*govmomi.Client)
+
+	if d.HasChange("datastore") || d.HasChange("destination_file") {
+
+		oldDatastoreName, _ := d.GetChange("datastore")
+		oldDestinationFile, _ := d.GetChange("destination_file")
+
+		oldDatastore, _, err := getFileDatastore(d, meta, oldDatastoreName.(string))
+		if err != nil {
+			return err
+		}
+
+		err = makeDatastoreFileFolder(context.Background(), client, datacenter, datastore, d.Get("destination_file").(string))
+		if err != nil {
+			return err
+		}
+
+		source := oldDatastore.Path(oldDestinationFile.(string))
+		destination := datastore.Path(d.Get("destination_file").(string))
+
+		log.Printf("[DEBUG] Moving file '%s' to '%s'", source, destination)
+
+		task, err := object.NewFileManager(client.Client).MoveDatastoreFile(
+			context.Background(), source, datacenter, destination, datacenter, true)
+		if err != nil {
+			return err
+		}
+		_, err = waitForTask(client.Client, task, fmt.Sprintf("move file '%s' to '%s'", source, destination), longTaskTimeout)
+		if err != nil {
+			log.Printf("[ERROR] Unable to move file '%s' to '%s'", source, destination)
+			return err
+		}
+
+		d.SetId(destination)
+	}
+
+	if d.HasChange("source_file") {
+
+		err = copyFileToDatastore(d, meta, datacenter, datastore)
+		if err != nil {
+			return err
+		}
+	}
+
+	return resourceVsphereFileRead(d, meta)
+}
+
+func resourceVsphereFileDelete(d *schema.ResourceData, meta interface{}) error {
+
+	if keep, ok := d.GetOk("keep"); !ok || !keep.(bool) {
+
+		_, datacenter, err := getFileDatastore(d, meta, d.Get("datastore").(string))
+		if err != nil {
+			return err
+		}
+
+		log.Printf("[DEBUG] Deleting file: %s", d.Id())
+
+		client := meta.(*govmomi.Client)
+
+		task, err := object.NewFileManager(client.Client).DeleteDatastoreFile(context.Background(), d.Id(), datacenter)
+		if err != nil {
+			return err
+		}
+		_, err = waitForTask(client.Client, task, fmt.Sprintf("delete file '%s'", d.Id()), defaultTaskTimeout)
+		if err != nil && !isFileNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Uploads the local source file or copies the source file of the source
+// datastore to the destination path in the datastore.
+func copyFileToDatastore(d *schema.ResourceData, meta interface{}, datacenter *object.Datacenter, datastore *object.Datastore) error {
+
+	client := meta.(*govmomi.Client)
+	sourceFile := d.Get("source_file").(string)
+	destinationFile := d.Get("destination_file").(string)
+
+	if v, ok := d.GetOk("source_datastore"); ok {
+
+		sourceDatastore, _, err := getFileDatastore(d, meta, v.(string))
+		if err != nil {
+			return err
+		}
+
+		err = makeDatastoreFileFolder(context.Background(), client, datacenter, datastore, destinationFile)
+		if err != nil {
+			return err
+		}
+
+		source := sourceDatastore.Path(sourceFile)
+		destination := datastore.Path(destinationFile)
+
+		log.Printf("[DEBUG] Copying file '%s' to '%s'", source, destination)
+
+		task, err := object.NewFileManager(client.Client).CopyDatastoreFile(
+			context.Background(), source, datacenter, destination, datacenter, true)
+		if err != nil {
+			return err
+		}
+		_, err = waitForTask(client.Client, task, fmt.Sprintf("copy file '%s' to '%s'", source, destination), longTaskTimeout)
+		if err != nil {
+			log.Printf("[ERROR] Unable to copy file '%s' to '%s'", source, destination)
+		}
+		return err
+	}
+
+	hash, err := getFileHash(sourceFile)
+	if err != nil {
+		return err
+	}
+
+	err = uploadDatastoreFile(context.Background(), client, datacenter, datastore, sourceFile, destinationFile)
+	if err != nil {
+		log.Printf("[ERROR] Unable to upload file '%s' to '%s'", sourceFile, datastore.Path(destinationFile))
+		return err
+	}
+
+	d.Set("source_hash", hash)
+	return nil
+}
+
+func getFileDatastore(d *schema.ResourceData, meta interface{}, name string) (*object.Datastore, *object.Datacenter, error) {
+
+	finder, datacenter, err := getFinder(d, meta)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	datastore, err := finder.Datastore(context.Background(), name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("datastore '%s' was not found: %s", name, err.Error())
+	}
+
+	return datastore, datacenter, nil
+}
+
+// Returns the hex encoded SHA-256 hash of the content of a local file.
+func getFileHash(localPath string) (string, error) {
+
+	f, err := os.Open(localPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Returns whether the hash of the local source file differs from the hash
+// of the file last uploaded. Files uploaded without a recorded hash are
+// not considered changed.
+func isFileSourceChanged(hash, uploadedHash string) bool {
+	return uploadedHash != "" && hash != uploadedHash
+}
@@ -0,0 +1,197 @@
+package vsphere
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"golang.org/x/net/context"
+	
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/kr/pretty"
+	"github.com/vmware/govmomi"
+)
+
+const testFileDatastore = "datastore1"
+
+func TestAccVsphereFile_normal(t *testing.T) {
+	
+	_, filename, _, _ := runtime.Caller(0)
+	ut := os.Getenv("UNIT_TEST")
+	if ut == "" || ut == filepath.Base(filename) {
+		
+		sourceFile, err := ioutil.TempFile("", "terraform-vsphere-file")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(sourceFile.Name())
+		
+		sourceFile.WriteString("vsphere_file acceptance test")
+		sourceFile.Close()
+		
+		resource.Test( t, 
+			resource.TestCase {
+				PreCheck: func() { testAccPreCheck(t) },
+				Providers: testAccProviders,
+				CheckDestroy: testAccCheckFileDestroy,
+				Steps: []resource.TestStep {
+					resource.TestStep {
+						Config: fmt.Sprintf( testAccFileConfig, 
+							testEsxHost.IP,
+							testEsxHost.User,
+							testEsxHost.Password,
+							testEsxHost.License,
+							sourceFile.Name(),
+							"terraform/file8.txt",
+						),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckFileExists("vsphere_file.f8"),
+							resource.TestCheckResourceAttr(
+								"vsphere_file.f8", "destination_file", "terraform/file8.txt"),
+						),
+					},
+					resource.TestStep {
+						Config: fmt.Sprintf( testAccFileConfig, 
+							testEsxHost.IP,
+							testEsxHost.User,
+							testEsxHost.Password,
+							testEsxHost.License,
+							sourceFile.Name(),
+							"terraform/moved/file8.txt",
+						),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckFileExists("vsphere_file.f8"),
+							resource.TestCheckResourceAttr(
+								"vsphere_file.f8", "destination_file", "terraform/moved/file8.txt"),
+						),
+					},
+				},
+			} )
+	}
+}
+
+func TestFileHash(t *testing.T) {
+	
+	f, err := ioutil.TempFile("", "terraform-vsphere-hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	
+	f.WriteString("abc")
+	f.Close()
+	
+	hash, err := getFileHash(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+		t.Fatalf("unexpected hash '%s'", hash)
+	}
+	
+	if _, err := getFileHash(f.Name() + ".missing"); err == nil {
+		t.Fatalf("expected an error for a missing file")
+	}
+	
+	if isFileSourceChanged(hash, hash) || isFileSourceChanged(hash, "") {
+		t.Fatalf("expected an unchanged file or a file without a recorded hash not to be uploaded again")
+	}
+	if !isFileSourceChanged(hash, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855") {
+		t.Fatalf("expected a file whose hash has changed to be uploaded again")
+	}
+}
+
+func testAccCheckFileExists(resource string) resource.TestCheckFunc {
+	
+	return func(s *terraform.State) error {
+		
+		rs, ok := s.RootModule().Resources[resource]
+		if !ok {
+			return fmt.Errorf("file '%s' not found in terraform state", resource)
+		}
+		
+		log.Printf("[DEBUG] Terraform file: %# v", pretty.Formatter(rs))
+		
+		attributes := rs.Primary.Attributes
+		
+		exists, err := testFileExists("datacenter8", attributes["datastore"], attributes["destination_file"])
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("file '%s' was not found in the datastore", rs.Primary.ID)
+		}
+		return nil
+	}
+}
+
+func testAccCheckFileDestroy(s *terraform.State) error {
+
+	const f8 = "vsphere_file.f8"
+
+	_, ok := s.RootModule().Resources[f8]
+	if ok {
+		return fmt.Errorf("file '%s' still exists in the terraform state", f8)
+	}
+
+	exists, err := testFileExists("datacenter8", testFileDatastore, "terraform/moved/file8.txt")
+	if err != nil {
+		log.Printf("[DEBUG] File destroyed with its datacenter as expected. API response was: %s", err.Error())
+	} else if exists {
+		return fmt.Errorf("file '%s' was not destroyed as expected", f8)
+	}
+	
+	return nil
+}
+
+func testFileExists(datacenterName, datastoreName, path string) (bool, error) {
+	
+	finder, err := getTestFinder(datacenterName)
+	if err != nil {
+		return false, err
+	}
+	
+	datastore, err := finder.Datastore(context.Background(), datastoreName)
+	if err != nil {
+		return false, err
+	}
+	
+	return datastoreFileExists(context.Background(), testAccProvider.Meta().(*govmomi.Client), datastore, path)
+}
+
+const testAccFileConfig = `
+
+resource "vsphere_datacenter" "dc8" {
+	name = "datacenter8"
+
+#	keep = true
+}
+
+resource "vsphere_host" "h8" {
+	host = "%s"
+	datacenter_id = "${vsphere_datacenter.dc8.id}"
+	
+	user = "%s"
+	password = "%s"
+	license = "%s"
+	
+	ssl_no_verify = true
+#	keep = true
+}
+
+resource "vsphere_file" "f8" {
+	depends_on = ["vsphere_host.h8"]
+
+	datacenter_id = "${vsphere_datacenter.dc8.id}"
+	source_file = "%s"
+	datastore = "` + testFileDatastore + `"
+	destination_file = "%s"
+	
+#	keep = false
+}
+`
@@ -9,6 +9,7 @@ import (
 	"golang.org/x/net/context"
 	
 	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/vmware/govmomi"
 	"github.com/vmware/govmomi/find"
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/vim25/mo"
@@ -54,6 +55,10 @@ func resourceVsphereHost() *schema.Resource {
 				Type: schema.TypeBool,
 				Optional: true,
 			},
+			"custom_attributes": &schema.Schema{
+				Type: schema.TypeMap, // Values of custom attributes keyed by attribute name
+				Optional: true,
+			},
 			"keep": &schema.Schema{
 				Type: schema.TypeBool,
 				Optional: true,
@@ -107,7 +112,7 @@ func resourceVsphereHostCreate(d *schema.ResourceData, meta interface{}) error {
 			
 			clusterName := v.(string)
 			
-			cluster, err := finder.ClusterComputeResource(context.Background(), clusterName)
+			cluster, err := getCluster(clusterName, finder, meta.(*govmomi.Client))
 			if err != nil {
 				log.Printf("[ERROR] Cluster '%s' to which host '%s' should be added was not found", clusterName, hostName)
 				return err
@@ -119,7 +124,7 @@ func resourceVsphereHostCreate(d *schema.ResourceData, meta interface{}) error {
 			if err != nil {
 				return err
 			}
-		  	err = task.Wait(context.Background())
+		  	_, err = waitForTask(meta.(*govmomi.Client).Client, task, fmt.Sprintf("add host '%s'", hostName), longTaskTimeout)
 			if err != nil {
 				var t mo.Task
 				
@@ -130,7 +135,7 @@ func resourceVsphereHostCreate(d *schema.ResourceData, meta interface{}) error {
 					spec.SslThumbprint = t.Info.Error.Fault.(*types.SSLVerifyFault).Thumbprint
 					task, err = cluster.AddHost(context.Background(), spec, true, lic, nil)
 					if err == nil {
-						err = task.Wait(context.Background())
+						_, err = waitForTask(meta.(*govmomi.Client).Client, task, fmt.Sprintf("add host '%s'", hostName), longTaskTimeout)
 					}
 				}
 				if err != nil {
@@ -153,7 +158,7 @@ func resourceVsphereHostCreate(d *schema.ResourceData, meta interface{}) error {
 			if err != nil {
 				return err
 			}
-		  	err = task.Wait(context.Background())
+		  	_, err = waitForTask(meta.(*govmomi.Client).Client, task, fmt.Sprintf("add host '%s'", hostName), longTaskTimeout)
 			if err != nil {
 				var t mo.Task
 				
@@ -164,7 +169,7 @@ func resourceVsphereHostCreate(d *schema.ResourceData, meta interface{}) error {
 					spec.SslThumbprint = t.Info.Error.Fault.(*types.SSLVerifyFault).Thumbprint
 					task, err = df.HostFolder.AddStandaloneHost(context.Background(), spec, true, lic, nil)
 					if err == nil {
-						err = task.Wait(context.Background())
+						_, err = waitForTask(meta.(*govmomi.Client).Client, task, fmt.Sprintf("add host '%s'", hostName), longTaskTimeout)
 					}
 				}
 				if err != nil {
@@ -175,7 +180,7 @@ func resourceVsphereHostCreate(d *schema.ResourceData, meta interface{}) error {
 	}
 	
 	d.SetId(d.Get("host").(string))
-	return resourceVsphereHostRead(d, meta)
+	return resourceVsphereHostUpdate(d, meta)
 }
 
 func resourceVsphereHostRead(d *schema.ResourceData, meta interface{}) error {
@@ -186,15 +191,30 @@ func resourceVsphereHostRead(d *schema.ResourceData, meta interface{}) error {
 		return err
 	}	
 
+	err = readCustomAttributes(d, meta, hostSystem.Reference())
+	if err != nil {
+		return err
+	}
+
 	d.Set("object_id", hostSystem.Reference().Value) 
 	return nil
 }
 
 func resourceVsphereHostUpdate(d *schema.ResourceData, meta interface{}) error {
 	
-	// TODO: Implement updates
+	// TODO: Implement updates of the connection settings
 	
-	return nil
+	hostSystem, err := findHost(d, meta)
+	if err != nil {
+		return err
+	}
+	
+	err = updateCustomAttributes(d, meta, hostSystem.Reference())
+	if err != nil {
+		return err
+	}
+	
+	return resourceVsphereHostRead(d, meta)
 }
 
 func resourceVsphereHostDelete(d *schema.ResourceData, meta interface{}) error {
@@ -243,7 +263,7 @@ func resourceVsphereHostDelete(d *schema.ResourceData, meta interface{}) error {
 				if err != nil {
 					return err
 				}
-			  	err = task.Wait(context.Background())
+			  	_, err = waitForTask(meta.(*govmomi.Client).Client, task, fmt.Sprintf("remove host '%s'", d.Get("host").(string)), defaultTaskTimeout)
 				if err != nil {
 					return err
 				}				
@@ -271,8 +291,15 @@ func findHost(d *schema.ResourceData, meta interface{}) (*object.HostSystem, err
 	
 	v, ok := d.GetOk("cluster_id")
 	if ok {
-		cluster := v.(string)
-		clusterName = &cluster
+		cluster, err := getCluster(v.(string), finder, meta.(*govmomi.Client))
+		if err != nil {
+			return nil, err
+		}
+		name, err := getObjectName(context.Background(), meta.(*govmomi.Client).Client, cluster.Reference())
+		if err != nil {
+			return nil, err
+		}
+		clusterName = &name
 	} else {
 		clusterName = nil
 	}
@@ -7,9 +7,12 @@ import (
 	"path/filepath"
 	"runtime"
 	"testing"
+	
+	"golang.org/x/net/context"
 
 	"github.com/hashicorp/terraform/helper/resource"
 	"github.com/hashicorp/terraform/terraform"
+	"github.com/vmware/govmomi"
 	"github.com/vmware/govmomi/object"
 	"github.com/kr/pretty"
 )
@@ -150,7 +153,17 @@ func findTestHost(hostName, datacenterName string, clusterName string) (*object.
 	if clusterName == "" {
 		cluster = nil
 	} else {
-		cluster = &clusterName
+		client := testAccProvider.Meta().(*govmomi.Client)
+		
+		c, err := getCluster(clusterName, finder, client)
+		if err != nil {
+			return nil, err
+		}
+		name, err := getObjectName(context.Background(), client.Client, c.Reference())
+		if err != nil {
+			return nil, err
+		}
+		cluster = &name
 	}
 	
 	hostSystem, err := getHost(hostName, datacenterName, cluster, finder)
@@ -9,8 +9,10 @@ import (
 	"golang.org/x/net/context"
 	
 	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/vmware/govmomi"
 	"github.com/vmware/govmomi/find"
 	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/methods"
 	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/types"
 	"github.com/kr/pretty"
@@ -36,57 +38,14 @@ func resourceVsphereResourcePool() *schema.Resource {
 				Required: true,
 			},
 			"parent_id": &schema.Schema{
-				Type: schema.TypeString,
+				Type: schema.TypeString, // Id or inventory path of a cluster, standalone host or resource pool
 				Required: true,
 			},
-			"cpu": &schema.Schema{
-				Type: schema.TypeList,
-				Optional: true,
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"shares": &schema.Schema{
-							Type: schema.TypeString, // One of low, normal, high or an integer value for custom level
-							Optional: true,
-						},
-						"reservation": &schema.Schema{
-							Type: schema.TypeInt,
-							Optional: true,
-						},
-						"expandable_reservation": &schema.Schema{
-							Type: schema.TypeBool,
-							Optional: true,
-						},
-						"limit": &schema.Schema{
-							Type: schema.TypeInt,
-							Optional: true,
-						},
-					},
-				},
-			},
-			"memory": &schema.Schema{
-				Type: schema.TypeList,
+			"cpu": resourceAllocationSchema(),
+			"memory": resourceAllocationSchema(),
+			"custom_attributes": &schema.Schema{
+				Type: schema.TypeMap, // Values of custom attributes keyed by attribute name
 				Optional: true,
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"shares": &schema.Schema{
-							Type: schema.TypeString, // One of low, normal, high or an integer value for custom level
-							Optional: true,
-						},
-						"reservation": &schema.Schema{
-							Type: schema.TypeInt,
-							Optional: true,
-						},
-						"expandable_reservation": &schema.Schema{
-							Type: schema.TypeBool,
-							Optional: true,
-							Default: true,
-						},
-						"limit": &schema.Schema{
-							Type: schema.TypeInt,
-							Optional: true,
-						},
-					},
-				},
 			},
 			"keep": &schema.Schema{
 				Type: schema.TypeBool,
@@ -115,21 +74,20 @@ func resourceVsphereResourcePoolCreate(d *schema.ResourceData, meta interface{})
 				return err
 			}
 			
-			parentResourcePool, err := finder.ResourcePool(
-				context.Background(), fmt.Sprintf("/%s/*/%s/Resources", d.Get("datacenter_id").(string), d.Get("parent_id").(string)))
+			parentResourcePool, err := getResourcePoolParent(d.Get("parent_id").(string), finder, meta.(*govmomi.Client))
 			if err != nil {
-				log.Printf("[ERROR] Unable to retrieve default resource pool of parent '%s'", d.Get("parent_id").(string))
+				log.Printf("[ERROR] Unable to retrieve resource pool of parent '%s'", d.Get("parent_id").(string))
 				return err
 			}
 			
-			log.Printf("[DEBUG] Creating new resource pool at %s", parentResourcePool.InventoryPath)
+			log.Printf("[DEBUG] Creating new resource pool in parent '%s'", d.Get("parent_id").(string))
 			
 			spec := types.ResourceConfigSpec{}
-			err = getAllocationInfo("cpu", &spec.CpuAllocation, d)
+			err = getAllocationInfo("cpu", d.Get("cpu").([]interface{}), &spec.CpuAllocation)
 			if err != nil {
 				return err
 			}
-			err = getAllocationInfo("memory", &spec.MemoryAllocation, d)
+			err = getAllocationInfo("memory", d.Get("memory").([]interface{}), &spec.MemoryAllocation)
 			if err != nil {
 				return err
 			}
@@ -145,9 +103,10 @@ func resourceVsphereResourcePoolCreate(d *schema.ResourceData, meta interface{})
 		}
 	}
 	
-	d.SetId(d.Get("name").(string))
+	d.SetId(resourcePool.Reference().Value)
 	d.Set("object_id", resourcePool.Reference().Value)
-	return nil
+	
+	return updateCustomAttributes(d, meta, resourcePool.Reference())
 }
 
 func resourceVsphereResourcePoolRead(d *schema.ResourceData, meta interface{}) error {
@@ -158,19 +117,46 @@ func resourceVsphereResourcePoolRead(d *schema.ResourceData, meta interface{}) e
 		return err
 	}
 	
-	log.Printf("[DEBUG] Reading configuration of resource pool at %s", resourcePool.InventoryPath)
+	log.Printf("[DEBUG] Reading configuration of resource pool: %s", resourcePool.Reference().Value)
 	
 	var mrp mo.ResourcePool
 	
-	ps := []string{"config"}
+	ps := []string{"name", "parent", "config"}
 	err = resourcePool.Properties(context.Background(), resourcePool.Reference(), ps, &mrp)
 	if err != nil {
 		return err
 	}
 	
-	putAllocationInfo("cpu", &mrp.Config.CpuAllocation, d)
-	putAllocationInfo("memory", &mrp.Config.CpuAllocation, d)
+	// Track moves made outside of terraform by reporting the
+	// actual parent if it differs from the configured one
+	if mrp.Parent != nil {
+		
+		finder, _, err := getFinder(d, meta)
+		if err != nil {
+			return err
+		}
+		
+		parentResourcePool, err := getResourcePoolParent(d.Get("parent_id").(string), finder, meta.(*govmomi.Client))
+		if err != nil {
+			log.Printf("[ERROR] Unable to find parent '%s' of resource pool: '%s'", d.Get("parent_id").(string), d.Id())
+			return err
+		}
+		if parentResourcePool.Reference().Value != mrp.Parent.Value {
+			d.Set("parent_id", mrp.Parent.Value)
+		}
+	}
+	
+	d.Set("cpu", putAllocationInfo(&mrp.Config.CpuAllocation))
+	d.Set("memory", putAllocationInfo(&mrp.Config.MemoryAllocation))
+	
+	err = readCustomAttributes(d, meta, resourcePool.Reference())
+	if err != nil {
+		return err
+	}
 	
+	// Resource pools created by earlier versions were identified by their name
+	d.SetId(resourcePool.Reference().Value)
+	d.Set("name", mrp.Name)
 	d.Set("object_id", resourcePool.Reference().Value)
 	return nil
 }
@@ -183,25 +169,51 @@ func resourceVsphereResourcePoolUpdate(d *schema.ResourceData, meta interface{})
 		return err
 	}
 	
-	log.Printf("[DEBUG] Updating resource pool at %s", resourcePool.InventoryPath)
+	log.Printf("[DEBUG] Updating resource pool: %s", d.Id())
+	
+	client := meta.(*govmomi.Client)
+	
+	name, err := getObjectName(context.Background(), client.Client, resourcePool.Reference())
+	if err != nil {
+		return err
+	}
+	if name != d.Get("name").(string) {
+		
+		log.Printf("[DEBUG] Renaming resource pool '%s' to '%s'", name, d.Get("name").(string))
+		
+		err = renameObject(context.Background(), client.Client, resourcePool.Reference(), d.Get("name").(string))
+		if err != nil {
+			log.Printf("[ERROR] Unable to rename resource pool '%s'", name)
+			return err
+		}
+	}
+	
+	if d.HasChange("parent_id") {
+		
+		err = moveResourcePool(d, meta, resourcePool)
+		if err != nil {
+			log.Printf("[ERROR] Unable to move resource pool '%s' to parent '%s'", d.Get("name").(string), d.Get("parent_id").(string))
+			return err
+		}
+	}
 	
 	spec := types.ResourceConfigSpec{}
-	err = getAllocationInfo("cpu", &spec.CpuAllocation, d)
+	err = getAllocationInfo("cpu", d.Get("cpu").([]interface{}), &spec.CpuAllocation)
 	if err != nil {
 		return err
 	}
-	err = getAllocationInfo("memory", &spec.MemoryAllocation, d)
+	err = getAllocationInfo("memory", d.Get("memory").([]interface{}), &spec.MemoryAllocation)
 	if err != nil {
 		return err
 	}
 	
-	err = resourcePool.UpdateConfig(context.Background(), d.Get("name").(string), &spec)
+	err = resourcePool.UpdateConfig(context.Background(), "", &spec)
 	if err != nil {
 		log.Printf("[ERROR] Unable to update resource pool '%s'", d.Get("name").(string))
 		return err				
 	}
 	
-	return nil
+	return updateCustomAttributes(d, meta, resourcePool.Reference())
 }
 
 func resourceVsphereResourcePoolDelete(d *schema.ResourceData, meta interface{}) error {
@@ -219,7 +231,7 @@ func resourceVsphereResourcePoolDelete(d *schema.ResourceData, meta interface{})
 		if err != nil {
 			return err
 		}
-	  	err = task.Wait(context.Background())
+	  	_, err = waitForTask(meta.(*govmomi.Client).Client, task, fmt.Sprintf("delete resource pool '%s'", d.Id()), defaultTaskTimeout)
 		if err != nil {
 			return err
 		}		
@@ -237,7 +249,24 @@ func findResourcePool(d *schema.ResourceData, meta interface{}) (*object.Resourc
 		return nil, err
 	}
 	
-	resourcePool, err := getResourcePool(d.Get("name").(string), d.Get("parent_id").(string), finder)
+	client := meta.(*govmomi.Client)
+	
+	if d.Id() != "" {
+		ref, err := findObjectReference(context.Background(), client.Client, "ResourcePool", d.Id())
+		if err != nil {
+			return nil, err
+		}
+		if ref != nil {
+			return object.NewResourcePool(client.Client, *ref), nil
+		}
+	}
+	
+	parentResourcePool, err := getResourcePoolParent(d.Get("parent_id").(string), finder, client)
+	if err != nil {
+		return nil, err
+	}
+	
+	resourcePool, err := getResourcePool(d.Get("name").(string), parentResourcePool, client)
 	if err != nil {
 		return nil, err
 	}
@@ -245,69 +274,209 @@ func findResourcePool(d *schema.ResourceData, meta interface{}) (*object.Resourc
 	return resourcePool, nil
 }
 
-func getResourcePool(name string, parent string, finder *find.Finder) (*object.ResourcePool, error) {
+// Returns the child resource pool with the given name of the parent resource pool.
+func getResourcePool(name string, parent *object.ResourcePool, client *govmomi.Client) (*object.ResourcePool, error) {
 	
-	path := fmt.Sprintf("/Resources/%s", name)
-	matchPath := fmt.Sprintf("/%s%s", parent, path)
-	searchPath := fmt.Sprintf("*%s", path)
-	
-	resourcePoolList, err := finder.ResourcePoolList(context.Background(), searchPath)
+	child, err := object.NewSearchIndex(client.Client).FindChild(context.Background(), parent, name)
 	if err != nil {
-		log.Printf("[ERROR] VMOMI error when searching for resource pool at path '%s': %s", searchPath, err.Error())
+		log.Printf("[ERROR] VMOMI error when searching for resource pool '%s' in parent '%s': %s", name, parent.Reference().Value, err.Error())
 		return nil, err
 	}
+	if child == nil || child.Reference().Type != "ResourcePool" {
+		return nil, fmt.Errorf("resource pool %s was not found", name)
+	}
+	
+	return object.NewResourcePool(client.Client, child.Reference()), nil
+}
 
-	for _, rp := range resourcePoolList {
+// Resolves the resource pool in which child resource pools of the given parent
+// are created. The parent may be a cluster, a standalone host or a resource
+// pool identified either by its managed object id or by its inventory path.
+func getResourcePoolParent(parentID string, finder *find.Finder, client *govmomi.Client) (*object.ResourcePool, error) {
+	
+	var (
+		ref *types.ManagedObjectReference
+		err error
+	)
+	
+	for _, refType := range []string{"ResourcePool", "ClusterComputeResource", "ComputeResource", "HostSystem"} {
+		ref, err = findObjectReference(context.Background(), client.Client, refType, parentID)
+		if err != nil {
+			return nil, err
+		}
+		if ref != nil {
+			break
+		}
+	}
+	
+	if ref == nil {
 		
-		if strings.HasSuffix(rp.InventoryPath, matchPath) {
-			return rp, nil
+		if strings.HasPrefix(parentID, "/") {
+			
+			r, err := object.NewSearchIndex(client.Client).FindByInventoryPath(context.Background(), parentID)
+			if err != nil {
+				return nil, err
+			}
+			if r == nil {
+				return nil, fmt.Errorf("parent '%s' was not found", parentID)
+			}
+			reference := r.Reference()
+			ref = &reference
+			
+		} else {
+			
+			// Paths relative to the datacenter's host folder such
+			// as the name of a cluster or a standalone host
+			computeResource, err := finder.ComputeResource(context.Background(), parentID)
+			if err != nil {
+				resourcePool, err := finder.ResourcePool(context.Background(), parentID)
+				if err != nil {
+					log.Printf("[ERROR] Unable find parent '%s'", parentID)
+					return nil, err
+				}
+				return resourcePool, nil
+			}
+			reference := computeResource.Reference()
+			ref = &reference
 		}
 	}
 	
-	return nil, fmt.Errorf("resource pool %s was not found", name)
+	switch ref.Type {
+		case "ResourcePool", "VirtualApp":
+			return object.NewResourcePool(client.Client, *ref), nil
+		case "ClusterComputeResource", "ComputeResource":
+			return object.NewComputeResource(client.Client, *ref).ResourcePool(context.Background())
+		case "HostSystem":
+			var mhs mo.HostSystem
+			
+			host := object.NewHostSystem(client.Client, *ref)
+			err = host.Properties(context.Background(), *ref, []string{"parent"}, &mhs)
+			if err != nil {
+				return nil, err
+			}
+			if mhs.Parent == nil {
+				return nil, fmt.Errorf("host '%s' does not belong to a compute resource", parentID)
+			}
+			return object.NewComputeResource(client.Client, *mhs.Parent).ResourcePool(context.Background())
+	}
+	
+	return nil, fmt.Errorf("'%s' of type %s cannot be the parent of a resource pool", parentID, ref.Type)
 }
 
-func getAllocationInfo(allocType string, allocInfo *types.ResourceAllocationInfo, d *schema.ResourceData) error {
+func moveResourcePool(d *schema.ResourceData, meta interface{}, resourcePool *object.ResourcePool) error {
+	
+	finder, _, err := getFinder(d, meta)
+	if err != nil {
+		return err
+	}
+	
+	client := meta.(*govmomi.Client)
+	
+	parentResourcePool, err := getResourcePoolParent(d.Get("parent_id").(string), finder, client)
+	if err != nil {
+		return err
+	}
+	
+	var mrp mo.ResourcePool
+	
+	err = resourcePool.Properties(context.Background(), resourcePool.Reference(), []string{"parent"}, &mrp)
+	if err != nil {
+		return err
+	}
+	if mrp.Parent != nil && mrp.Parent.Value == parentResourcePool.Reference().Value {
+		return nil
+	}
+	
+	log.Printf("[DEBUG] Moving resource pool '%s' to parent '%s'", d.Id(), d.Get("parent_id").(string))
+	
+	req := types.MoveIntoResourcePool{
+		This: parentResourcePool.Reference(),
+		List: []types.ManagedObjectReference{ resourcePool.Reference() },
+	}
+	_, err = methods.MoveIntoResourcePool(context.Background(), client.Client, &req)
+	return err
+}
 
-	var err error
+// Returns the schema of the cpu or memory allocation section shared by
+// resource pools and vApps. The defaults are vCenter's.
+func resourceAllocationSchema() *schema.Schema {
 
-	v, ok := d.GetOk(fmt.Sprintf("%s.#", allocType))
-	if ok {
-		count := v.(int)
-		if count > 1 {
-			return fmt.Errorf("only 1 %s allocation section permitted", allocType)
-		}
-		if count == 1 {
+	return &schema.Schema{
+		Type: schema.TypeList,
+		Optional: true,
+		Computed: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"shares": &schema.Schema{
+					Type: schema.TypeString, // One of low, normal, high or an integer value for custom level
+					Optional: true,
+					Default: "normal",
+				},
+				"reservation": &schema.Schema{
+					Type: schema.TypeInt,
+					Optional: true,
+				},
+				"expandable_reservation": &schema.Schema{
+					Type: schema.TypeBool,
+					Optional: true,
+					Default: true,
+				},
+				"limit": &schema.Schema{
+					Type: schema.TypeInt, // -1 for unlimited
+					Optional: true,
+					Default: -1,
+				},
+			},
+		},
+	}
+}
+
+// Populates the resource allocation from the given cpu or memory configuration
+// section. vCenter's defaults are used if no section is configured.
+func getAllocationInfo(allocType string, allocList []interface{}, allocInfo *types.ResourceAllocationInfo) error {
 
-			expandableReservation := d.Get(fmt.Sprintf("%s.0.expandable_reservation", allocType)).(bool)
-			allocInfo.ExpandableReservation = &expandableReservation
+	var err error
+	
+	if len(allocList) > 1 {
+		return fmt.Errorf("only 1 %s allocation section permitted", allocType)
+	}
+	
+	expandableReservation := true
+	
+	allocInfo.Reservation = 0
+	allocInfo.ExpandableReservation = &expandableReservation
+	allocInfo.Limit = -1
+	allocInfo.Shares = &types.SharesInfo{
+		Level: types.SharesLevelNormal,
+	}
+	
+	if len(allocList) == 1 && allocList[0] != nil {
+		
+		alloc := allocList[0].(map[string]interface{})
+		
+		if v, ok := alloc["expandable_reservation"]; ok {
+			expandableReservation = v.(bool)
+		}
+		if v, ok := alloc["reservation"]; ok {
+			allocInfo.Reservation = int64(v.(int))
+		}
+		if v, ok := alloc["limit"]; ok {
+			allocInfo.Limit = int64(v.(int))
+		}
+		if v, ok := alloc["shares"]; ok && v.(string) != "" {
 			
-			if v, ok := d.GetOk(fmt.Sprintf("%s.0.shares", allocType)); ok {
-				shares := types.SharesInfo{}
-				level := types.SharesLevel(v.(string))
-				if level != types.SharesLevelLow && 
-					level != types.SharesLevelNormal && 
-					level != types.SharesLevelHigh {
-						
-					shares.Shares, err = strconv.Atoi(v.(string))
-					if err != nil {
-						return fmt.Errorf("error converting customer share value to int: %s", err.Error())
-					}
-					shares.Level = types.SharesLevelCustom
-				} else {
-					shares.Level = level
+			level := types.SharesLevel(v.(string))
+			if level != types.SharesLevelLow && 
+				level != types.SharesLevelNormal && 
+				level != types.SharesLevelHigh {
+				
+				allocInfo.Shares.Shares, err = strconv.Atoi(v.(string))
+				if err != nil {
+					return fmt.Errorf("error converting custom %s share value to int: %s", allocType, err.Error())
 				}
-				allocInfo.Shares = &shares
+				allocInfo.Shares.Level = types.SharesLevelCustom
 			} else {
-				allocInfo.Shares = &types.SharesInfo{
-					Level: types.SharesLevelNormal,
-				}
-			}
-			if v, ok := d.GetOk(fmt.Sprintf("%s.0.reservation", allocType)); ok {
-				allocInfo.Reservation = int64(v.(int))
-			}
-			if v, ok := d.GetOk(fmt.Sprintf("%s.0.limit", allocType)); ok {
-				allocInfo.Limit = int64(v.(int))
+				allocInfo.Shares.Level = level
 			}
 		}
 	}
@@ -316,23 +485,22 @@ func getAllocationInfo(allocType string, allocInfo *types.ResourceAllocationInfo
 	return nil
 }
 
-func putAllocationInfo(allocType string, allocInfo *types.ResourceAllocationInfo, d *schema.ResourceData) {
+// Returns the cpu or memory configuration section for the given resource allocation.
+func putAllocationInfo(allocInfo *types.ResourceAllocationInfo) []interface{} {
 	
 	configState := make(map[string]interface{})
 	
-	if allocInfo.Reservation != 0 {
-		configState["reservation"] = strconv.FormatInt(allocInfo.Reservation, 10)
-	}
-	if allocInfo.Limit != 0 {
-		configState["limit"] = strconv.FormatInt(allocInfo.Limit, 10)
-	}
-	configState["expandable_reservation"] = strconv.FormatBool(*allocInfo.ExpandableReservation)
+	configState["reservation"] = int(allocInfo.Reservation)
+	configState["limit"] = int(allocInfo.Limit)
+	configState["expandable_reservation"] = allocInfo.ExpandableReservation != nil && *allocInfo.ExpandableReservation
 	
-	if allocInfo.Shares.Level == types.SharesLevelCustom {
+	if allocInfo.Shares == nil {
+		configState["shares"] = string(types.SharesLevelNormal)
+	} else if allocInfo.Shares.Level == types.SharesLevelCustom {
 		configState["shares"] = strconv.Itoa(allocInfo.Shares.Shares)
 	} else {
 		configState["shares"] = string(allocInfo.Shares.Level)
 	}
 	
-	d.Set(allocType, append(make([]map[string]interface{}, 0, 1), configState))
+	return []interface{}{ configState }
 }
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"strconv"
 	"testing"
@@ -14,16 +15,21 @@ import (
 	"github.com/hashicorp/terraform/helper/resource"
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/kr/pretty"
+	"github.com/vmware/govmomi"
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/types"
 )
 
-var keepResourcePool bool
+var (
+	keepResourcePool bool
+	resourcePoolIDs map[string]string
+)
 
 func TestAccVsphereResourcePool_normal(t *testing.T) {
 	
 	keepResourcePool = false
+	resourcePoolIDs = make(map[string]string)
 
 	_, filename, _, _ := runtime.Caller(0)
 	ut := os.Getenv("UNIT_TEST")
@@ -41,6 +47,7 @@ func TestAccVsphereResourcePool_normal(t *testing.T) {
 							testEsxHost.User,
 							testEsxHost.Password,
 							testEsxHost.License,
+							"resource_pool1",
 						),
 						Check: resource.ComposeTestCheckFunc(
 							testAccCheckResourcePoolExists("vsphere_resource_pool.rp1"),
@@ -62,6 +69,23 @@ func TestAccVsphereResourcePool_normal(t *testing.T) {
 								"vsphere_resource_pool.rp1", "memory.0.expandable_reservation", "true"),
 							resource.TestCheckResourceAttr(
 								"vsphere_resource_pool.rp1", "memory.0.limit", "3072"),
+							
+							testAccCheckResourcePoolExists("vsphere_resource_pool.rp2"),
+							resource.TestCheckResourceAttr("vsphere_resource_pool.rp2", "name", "resource_pool2"),
+						),
+					},
+					resource.TestStep {
+						Config: fmt.Sprintf( testAccResourcePoolConfig, 
+							testEsxHost.IP,
+							testEsxHost.User,
+							testEsxHost.Password,
+							testEsxHost.License,
+							"resource_pool1_renamed",
+						),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckResourcePoolExists("vsphere_resource_pool.rp1"),
+							resource.TestCheckResourceAttr("vsphere_resource_pool.rp1", "name", "resource_pool1_renamed"),
+							testAccCheckResourcePoolExists("vsphere_resource_pool.rp2"),
 						),
 					},
 				},
@@ -83,7 +107,7 @@ func testAccCheckResourcePoolExists(resource string) resource.TestCheckFunc {
 		log.Printf("[DEBUG] Terraform resource pool: %# v", pretty.Formatter(rs))
 
 		attributes := rs.Primary.Attributes
-		resourcePoolName := rs.Primary.ID
+		resourcePoolName := attributes["name"]
 		
 		datacenterName := attributes["datacenter_id"]
 		parentName := attributes["parent_id"]
@@ -92,6 +116,13 @@ func testAccCheckResourcePoolExists(resource string) resource.TestCheckFunc {
 		if err != nil {
 			return err
 		}
+		if resourcePool.Reference().Value != rs.Primary.ID {
+			return fmt.Errorf("resource pool id mismatch. expected '%s' but got '%s'", resourcePool.Reference().Value, rs.Primary.ID)
+		}
+		if id, ok := resourcePoolIDs[resource]; ok && id != rs.Primary.ID {
+			return fmt.Errorf("resource pool was recreated instead of being updated in place")
+		}
+		resourcePoolIDs[resource] = rs.Primary.ID
 		
 		var mrp mo.ResourcePool
 		
@@ -120,7 +151,7 @@ func testAccCheckResourcePoolDestroy(s *terraform.State) error {
 	const rp1 = "vsphere_resource_pool.rp1"
 	const datacenter3 = "datacenter3"
 	const cluster3 = "cluster3"
-	const resourcePool1 = "resource_pool1"
+	const resourcePool1 = "resource_pool1_renamed"
 
 	var(
 		ok bool
@@ -151,7 +182,14 @@ func findTestResourcePool(resourcePoolName, parentName string, datacenterName st
 		return nil, err
 	}
 	
-	resourcePool, err := getResourcePool(resourcePoolName, parentName, finder)
+	client := testAccProvider.Meta().(*govmomi.Client)
+	
+	parent, err := getResourcePoolParent(parentName, finder, client)
+	if err != nil {
+		return nil, err
+	}
+	
+	resourcePool, err := getResourcePool(resourcePoolName, parent, client)
 	if err != nil {
 		return nil, err
 	}
@@ -173,29 +211,101 @@ func verifyResourceAllocation(allocType string, allocInfo types.ResourceAllocati
 	if num == 1 {
 		
 		if strconv.FormatInt(allocInfo.Reservation, 10) != attributes[fmt.Sprintf("%s.0.reservation", allocType)] {
-			fmt.Errorf("resource allocation %s reservation value mis-match", allocType)
+			return fmt.Errorf("resource allocation %s reservation value mis-match", allocType)
 		}
 		if strconv.FormatBool(*allocInfo.ExpandableReservation) != attributes[fmt.Sprintf("%s.0.expandable_reservation", allocType)] {
-			fmt.Errorf("resource allocation %s expandable reservation value mis-match", allocType)
+			return fmt.Errorf("resource allocation %s expandable reservation value mis-match", allocType)
 		}
 		if strconv.FormatInt(allocInfo.Limit, 10) != attributes[fmt.Sprintf("%s.0.limit", allocType)] {
-			fmt.Errorf("resource allocation %s limit value mis-match", allocType)
+			return fmt.Errorf("resource allocation %s limit value mis-match", allocType)
 		}
 		
 		v := attributes[fmt.Sprintf("%s.0.shares", allocType)]
-		level := types.SharesLevel(v)
-		if level != types.SharesLevelLow && 
-			level != types.SharesLevelNormal && 
-			level != types.SharesLevelHigh &&
-			(level != types.SharesLevelCustom || strconv.Itoa(allocInfo.Shares.Shares) != v) {
-		
-			fmt.Errorf("resource allocation %s shares structure mis-match", allocType)
+		if allocInfo.Shares.Level == types.SharesLevelCustom {
+			if strconv.Itoa(allocInfo.Shares.Shares) != v {
+				return fmt.Errorf("resource allocation %s custom shares value mis-match", allocType)
+			}
+		} else if string(allocInfo.Shares.Level) != v {
+			return fmt.Errorf("resource allocation %s shares level mis-match", allocType)
 		}
 	}
 	
 	return nil
 } 
 
+func TestResourcePoolAllocationInfo_roundTrip(t *testing.T) {
+	
+	for _, shares := range []string{"low", "normal", "high", "40960"} {
+		for _, limit := range []int{-1, 0, 1024} {
+			
+			alloc := map[string]interface{}{
+				"shares": shares,
+				"reservation": 512,
+				"expandable_reservation": limit != 0,
+				"limit": limit,
+			}
+			
+			allocInfo := types.ResourceAllocationInfo{}
+			if err := getAllocationInfo("cpu", []interface{}{ alloc }, &allocInfo); err != nil {
+				t.Fatalf("unexpected error for shares '%s' and limit %d: %s", shares, limit, err.Error())
+			}
+			if allocInfo.Limit != int64(limit) {
+				t.Fatalf("expected limit %d but got %d", limit, allocInfo.Limit)
+			}
+			
+			allocList := putAllocationInfo(&allocInfo)
+			if !reflect.DeepEqual(allocList, []interface{}{ alloc }) {
+				t.Fatalf("expected allocation %# v but got %# v", pretty.Formatter(alloc), pretty.Formatter(allocList))
+			}
+		}
+	}
+}
+
+func TestResourcePoolAllocationInfo_sharesLevels(t *testing.T) {
+	
+	expected := map[string]types.SharesInfo{
+		"low": types.SharesInfo{ Level: types.SharesLevelLow },
+		"normal": types.SharesInfo{ Level: types.SharesLevelNormal },
+		"high": types.SharesInfo{ Level: types.SharesLevelHigh },
+		"40960": types.SharesInfo{ Level: types.SharesLevelCustom, Shares: 40960 },
+	}
+	
+	for shares, sharesInfo := range expected {
+		
+		allocInfo := types.ResourceAllocationInfo{}
+		err := getAllocationInfo("memory", []interface{}{ map[string]interface{}{ "shares": shares } }, &allocInfo)
+		if err != nil {
+			t.Fatalf("unexpected error for shares '%s': %s", shares, err.Error())
+		}
+		if !reflect.DeepEqual(*allocInfo.Shares, sharesInfo) {
+			t.Fatalf("expected shares %# v but got %# v", pretty.Formatter(sharesInfo), pretty.Formatter(allocInfo.Shares))
+		}
+	}
+	
+	allocInfo := types.ResourceAllocationInfo{}
+	if err := getAllocationInfo("memory", []interface{}{ map[string]interface{}{ "shares": "lots" } }, &allocInfo); err == nil {
+		t.Fatalf("expected an error for an invalid shares value")
+	}
+}
+
+func TestResourcePoolAllocationInfo_defaults(t *testing.T) {
+	
+	allocInfo := types.ResourceAllocationInfo{}
+	if err := getAllocationInfo("cpu", []interface{}{}, &allocInfo); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if allocInfo.Limit != -1 || allocInfo.Reservation != 0 || 
+		!*allocInfo.ExpandableReservation || allocInfo.Shares.Level != types.SharesLevelNormal {
+		
+		t.Fatalf("unexpected default allocation %# v", pretty.Formatter(allocInfo))
+	}
+	
+	alloc := map[string]interface{}{ "shares": "normal" }
+	if err := getAllocationInfo("cpu", []interface{}{ alloc, alloc }, &allocInfo); err == nil {
+		t.Fatalf("expected an error for more than one allocation section")
+	}
+}
+
 const testAccResourcePoolConfig = `
 
 resource "vsphere_datacenter" "dc3" {
@@ -229,7 +339,7 @@ resource "vsphere_host" "h3" {
 resource "vsphere_resource_pool" "rp1" {
 	depends_on = ["vsphere_host.h3"]
 
-	name = "resource_pool1"
+	name = "%s"
 	datacenter_id = "${vsphere_datacenter.dc3.id}"
 	parent_id = "${vsphere_cluster.c3.id}"
 	
@@ -249,4 +359,10 @@ resource "vsphere_resource_pool" "rp1" {
 	
 #	keep = false
 }
+
+resource "vsphere_resource_pool" "rp2" {
+	name = "resource_pool2"
+	datacenter_id = "${vsphere_datacenter.dc3.id}"
+	parent_id = "${vsphere_resource_pool.rp1.id}"
+}
 `
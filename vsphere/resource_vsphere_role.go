@@ -0,0 +1,222 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// Privileges vCenter adds to every role
+var implicitRolePrivileges = []string{
+	"System.Anonymous",
+	"System.Read",
+	"System.View",
+}
+
+func resourceVsphereRole() *schema.Resource {
+
+	return &schema.Resource{
+
+		Create: resourceVsphereRoleCreate,
+		Read:   resourceVsphereRoleRead,
+		Update: resourceVsphereRoleUpdate,
+		Delete: resourceVsphereRoleDelete,
+
+		Schema: map[string]*schema.Schema{
+
+			"name": &schema.Schema{
+				Type: schema.TypeString,
+				Required: true,
+			},
+			"privileges": &schema.Schema{
+				Type: schema.TypeList, // Privilege ids such as VirtualMachine.Interact.PowerOn
+				Optional: true,
+				Elem: &schema.Schema{ Type: schema.TypeString },
+			},
+			"keep": &schema.Schema{
+				Type: schema.TypeBool,
+				Optional: true,
+			},
+		},
+	}
+}
+
+func resourceVsphereRoleCreate(d *schema.ResourceData, meta interface{}) error {
+
+	client := meta.(*govmomi.Client)
+	name := d.Get("name").(string)
+
+	log.Printf("[DEBUG] Creating role: %s", name)
+
+	req := types.AddAuthorizationRole{
+		This: *client.Client.ServiceContent.AuthorizationManager,
+		Name: name,
+		PrivIds: getRolePrivilegeIds(d),
+	}
+	res, err := methods.AddAuthorizationRole(context.Background(), client.Client, &req)
+	if err != nil {
+		log.Printf("[ERROR] Unable to create role '%s'", name)
+		return err
+	}
+
+	d.SetId(strconv.Itoa(res.Returnval))
+	return resourceVsphereRoleRead(d, meta)
+}
+
+func resourceVsphereRoleRead(d *schema.ResourceData, meta interface{}) error {
+
+	role, err := findRole(d, meta)
+	if err != nil {
+		return err
+	}
+	if role == nil {
+		log.Printf("[DEBUG] Role '%s' no longer exists", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	d.Set("name", role.Name)
+	d.Set("privileges", getRolePrivileges(role.Privilege, d.Get("privileges").([]interface{})))
+	return nil
+}
+
+func resourceVsphereRoleUpdate(d *schema.ResourceData, meta interface{}) error {
+
+	if d.HasChange("name") || d.HasChange("privileges") {
+
+		client := meta.(*govmomi.Client)
+		roleID, _ := strconv.Atoi(d.Id())
+
+		log.Printf("[DEBUG] Updating role: %s", d.Id())
+
+		req := types.UpdateAuthorizationRole{
+			This: *client.Client.ServiceContent.AuthorizationManager,
+			RoleId: roleID,
+			NewName: d.Get("name").(string),
+			PrivIds: getRolePrivilegeIds(d),
+		}
+		_, err := methods.UpdateAuthorizationRole(context.Background(), client.Client, &req)
+		if err != nil {
+			log.Printf("[ERROR] Unable to update role '%s'", d.Id())
+			return err
+		}
+	}
+
+	return resourceVsphereRoleRead(d, meta)
+}
+
+func resourceVsphereRoleDelete(d *schema.ResourceData, meta interface{}) error {
+
+	if keep, ok := d.GetOk("keep"); !ok || !keep.(bool) {
+
+		client := meta.(*govmomi.Client)
+		roleID, _ := strconv.Atoi(d.Id())
+
+		log.Printf("[DEBUG] Deleting role: %s", d.Id())
+
+		// Roles still granted by permissions are not removed as that would
+		// silently revoke the access given by those permissions
+		req := types.RemoveAuthorizationRole{
+			This: *client.Client.ServiceContent.AuthorizationManager,
+			RoleId: roleID,
+			FailIfUsed: true,
+		}
+		_, err := methods.RemoveAuthorizationRole(context.Background(), client.Client, &req)
+		if err != nil && !isNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func findRole(d *schema.ResourceData, meta interface{}) (*types.AuthorizationRole, error) {
+
+	roleID, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return nil, fmt.Errorf("invalid role id '%s'", d.Id())
+	}
+
+	roles, err := getAuthorizationRoles(context.Background(), meta.(*govmomi.Client))
+	if err != nil {
+		return nil, err
+	}
+
+	for i, role := range roles {
+		if role.RoleId == roleID {
+			return &roles[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// Returns the roles of vCenter including the system roles.
+func getAuthorizationRoles(ctx context.Context, client *govmomi.Client) ([]types.AuthorizationRole, error) {
+
+	var m mo.AuthorizationManager
+
+	err := property.DefaultCollector(client.Client).RetrieveOne(ctx, *client.Client.ServiceContent.AuthorizationManager, []string{"roleList"}, &m)
+	if err != nil {
+		return nil, err
+	}
+	return m.RoleList, nil
+}
+
+func getRolePrivilegeIds(d *schema.ResourceData) []string {
+
+	var privileges []string
+	for _, p := range d.Get("privileges").([]interface{}) {
+		privileges = append(privileges, p.(string))
+	}
+	return privileges
+}
+
+// Returns the privileges of a role to store in the state. The configured
+// list is returned when the role has exactly the configured privileges so
+// that their order does not show as a change. The privileges vCenter adds
+// to every role are left out unless they are configured.
+func getRolePrivileges(actual []string, configured []interface{}) []interface{} {
+
+	wanted := make(map[string]bool)
+	for _, p := range configured {
+		wanted[p.(string)] = true
+	}
+	implicit := make(map[string]bool)
+	for _, p := range implicitRolePrivileges {
+		implicit[p] = true
+	}
+
+	var privileges []string
+	for _, p := range actual {
+		if !implicit[p] || wanted[p] {
+			privileges = append(privileges, p)
+		}
+	}
+
+	if len(privileges) == len(wanted) {
+		same := true
+		for _, p := range privileges {
+			same = same && wanted[p]
+		}
+		if same {
+			return configured
+		}
+	}
+
+	sort.Strings(privileges)
+
+	result := make([]interface{}, len(privileges))
+	for i, p := range privileges {
+		result[i] = p
+	}
+	return result
+}
@@ -0,0 +1,145 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/kr/pretty"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestAccVsphereRole_normal(t *testing.T) {
+
+	_, filename, _, _ := runtime.Caller(0)
+	ut := os.Getenv("UNIT_TEST")
+	if ut == "" || ut == filepath.Base(filename) {
+
+		resource.Test( t,
+			resource.TestCase {
+				PreCheck: func() { testAccPreCheck(t) },
+				Providers: testAccProviders,
+				CheckDestroy: testAccCheckRoleDestroy,
+				Steps: []resource.TestStep {
+					resource.TestStep {
+						Config: fmt.Sprintf(testAccRoleConfig, "terraform-operator", `"VirtualMachine.Interact.PowerOn"`),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckRoleExists("vsphere_role.role1", "terraform-operator", 1),
+							resource.TestCheckResourceAttr("vsphere_role.role1", "privileges.#", "1"),
+						),
+					},
+					resource.TestStep {
+						Config: fmt.Sprintf(testAccRoleConfig, "terraform-power-user",
+							`"VirtualMachine.Interact.PowerOn", "VirtualMachine.Interact.PowerOff"`),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckRoleExists("vsphere_role.role1", "terraform-power-user", 2),
+							resource.TestCheckResourceAttr("vsphere_role.role1", "privileges.#", "2"),
+							resource.TestCheckResourceAttr("vsphere_role.role1", "privileges.1", "VirtualMachine.Interact.PowerOff"),
+						),
+					},
+				},
+			} )
+	}
+}
+
+func testAccCheckRoleExists(resource, name string, privileges int) resource.TestCheckFunc {
+
+	return func(s *terraform.State) error {
+
+		rs, ok := s.RootModule().Resources[resource]
+		if !ok {
+			return fmt.Errorf("role '%s' not found in terraform state", resource)
+		}
+
+		log.Printf("[DEBUG] Terraform role: %# v", pretty.Formatter(rs))
+
+		role, err := findTestRole(rs.Primary.ID)
+		if err != nil {
+			return err
+		}
+		if role == nil {
+			return fmt.Errorf("role '%s' was not found in vCenter", rs.Primary.ID)
+		}
+		if role.Name != name {
+			return fmt.Errorf("role '%s' is named '%s' but expected '%s'", rs.Primary.ID, role.Name, name)
+		}
+		if n := len(getRolePrivileges(role.Privilege, nil)); n != privileges {
+			return fmt.Errorf("role '%s' has %d privileges but expected %d", rs.Primary.ID, n, privileges)
+		}
+		return nil
+	}
+}
+
+func testAccCheckRoleDestroy(s *terraform.State) error {
+
+	const role1 = "vsphere_role.role1"
+
+	_, ok := s.RootModule().Resources[role1]
+	if ok {
+		return fmt.Errorf("role '%s' still exists in the terraform state", role1)
+	}
+
+	roles, err := getAuthorizationRoles(context.Background(), testAccProvider.Meta().(*govmomi.Client))
+	if err != nil {
+		return err
+	}
+	for _, role := range roles {
+		if role.Name == "terraform-power-user" {
+			return fmt.Errorf("role '%s' was not destroyed as expected", role1)
+		}
+	}
+	return nil
+}
+
+func findTestRole(id string) (*types.AuthorizationRole, error) {
+
+	roleID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := getAuthorizationRoles(context.Background(), testAccProvider.Meta().(*govmomi.Client))
+	if err != nil {
+		return nil, err
+	}
+	for i, role := range roles {
+		if role.RoleId == roleID {
+			return &roles[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func TestRolePrivileges(t *testing.T) {
+
+	actual := []string{ "System.Anonymous", "System.Read", "System.View", "VirtualMachine.Interact.PowerOn", "VirtualMachine.Interact.PowerOff" }
+
+	configured := []interface{}{ "VirtualMachine.Interact.PowerOn", "VirtualMachine.Interact.PowerOff" }
+	privileges := getRolePrivileges(actual, configured)
+	if len(privileges) != 2 || privileges[0] != "VirtualMachine.Interact.PowerOn" || privileges[1] != "VirtualMachine.Interact.PowerOff" {
+		t.Fatalf("expected the configured privileges in their configured order but got: %v", privileges)
+	}
+
+	configured = []interface{}{ "System.View", "VirtualMachine.Interact.PowerOn" }
+	privileges = getRolePrivileges(actual, configured)
+	if len(privileges) != 3 || privileges[0] != "System.View" || privileges[1] != "VirtualMachine.Interact.PowerOff" {
+		t.Fatalf("expected the configured implicit privilege and the actual privileges sorted but got: %v", privileges)
+	}
+}
+
+const testAccRoleConfig = `
+
+resource "vsphere_role" "role1" {
+	name = "%s"
+	privileges = [ %s ]
+}
+`
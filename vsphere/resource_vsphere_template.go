@@ -0,0 +1,464 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func resourceVsphereTemplate() *schema.Resource {
+
+	return &schema.Resource{
+
+		Create: resourceVsphereTemplateCreate,
+		Read:   resourceVsphereTemplateRead,
+		Update: resourceVsphereTemplateUpdate,
+		Delete: resourceVsphereTemplateDelete,
+
+		Schema: map[string]*schema.Schema{
+
+			"name": &schema.Schema{
+				Type: schema.TypeString, // Required for clones. A marked VM keeps its name unless one is given.
+				Optional: true,
+				Computed: true,
+			},
+			"datacenter_id": &schema.Schema{
+				Type: schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"source_vm": &schema.Schema{
+				Type: schema.TypeString, // Name or object_id of the VM the template is created from
+				Required: true,
+				ForceNew: true,
+			},
+			"mark_as_template": &schema.Schema{
+				Type: schema.TypeBool, // Convert the source VM itself instead of cloning it
+				Optional: true,
+				Default: false,
+				ForceNew: true,
+			},
+			"allow_shutdown": &schema.Schema{
+				Type: schema.TypeBool, // Shut down a running source VM to mark it as a template
+				Optional: true,
+				Default: false,
+			},
+			"version": &schema.Schema{
+				Type: schema.TypeString, // Changing it re-creates the template from the source VM
+				Optional: true,
+				ForceNew: true,
+			},
+			"resource_pool": &schema.Schema{
+				Type: schema.TypeString, // Resource pool of the clone and of the VM a template is converted back to
+				Optional: true,
+				ForceNew: true,
+			},
+			"host": &schema.Schema{
+				Type: schema.TypeString, // Name or object_id of the host the clone is registered on
+				Optional: true,
+				ForceNew: true,
+			},
+			"datastore": &schema.Schema{
+				Type: schema.TypeString, // Datastore of the clone's files
+				Optional: true,
+				ForceNew: true,
+			},
+			"on_destroy": &schema.Schema{
+				Type: schema.TypeString, // One of convert or delete. Marked VMs are converted and clones deleted by default.
+				Optional: true,
+			},
+			"keep": &schema.Schema{
+				Type: schema.TypeBool,
+				Optional: true,
+			},
+			"uuid": &schema.Schema{
+				Type: schema.TypeString,
+				Computed: true,
+			},
+			"guest_id": &schema.Schema{
+				Type: schema.TypeString,
+				Computed: true,
+			},
+			"disk": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"path": &schema.Schema{
+							Type: schema.TypeString,
+							Computed: true,
+						},
+						"size_gb": &schema.Schema{
+							Type: schema.TypeInt,
+							Computed: true,
+						},
+						"thin_provisioned": &schema.Schema{
+							Type: schema.TypeBool,
+							Computed: true,
+						},
+						"datastore": &schema.Schema{
+							Type: schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
+			"object_id": &schema.Schema{
+				Type: schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func resourceVsphereTemplateCreate(d *schema.ResourceData, meta interface{}) error {
+
+	client := meta.(*govmomi.Client)
+	source := d.Get("source_vm").(string)
+
+	finder, datacenter, err := getFinder(d, meta)
+	if err != nil {
+		log.Printf("[ERROR] Unable to create finder for operations on template of VM: '%s'", source)
+		return err
+	}
+
+	vm, err := getTemplateSourceVM(context.Background(), client, finder, source)
+	if err != nil {
+		return err
+	}
+
+	// A VM can only be turned into a template while it is powered off
+	poweredOn, err := isVMPoweredOn(context.Background(), vm)
+	if err != nil {
+		return err
+	}
+	if poweredOn && d.Get("mark_as_template").(bool) {
+
+		if !d.Get("allow_shutdown").(bool) {
+			return fmt.Errorf("VM '%s' must be powered off to be marked as a template. set allow_shutdown to have it shut down", source)
+		}
+
+		log.Printf("[DEBUG] Shutting down VM '%s' to mark it as a template", source)
+
+		err = shutdownVM(client, vm)
+		if err != nil {
+			return err
+		}
+	}
+
+	var ref types.ManagedObjectReference
+
+	if d.Get("mark_as_template").(bool) {
+		ref, err = markVMAsTemplate(d, meta, vm)
+	} else {
+		ref, err = cloneVMToTemplate(d, meta, finder, datacenter, vm)
+	}
+	if err != nil {
+		return err
+	}
+
+	d.SetId(ref.Value)
+	d.Set("object_id", ref.Value)
+	return resourceVsphereTemplateRead(d, meta)
+}
+
+func resourceVsphereTemplateRead(d *schema.ResourceData, meta interface{}) error {
+
+	client := meta.(*govmomi.Client)
+
+	var mvm mo.VirtualMachine
+
+	err := property.DefaultCollector(client.Client).RetrieveOne(context.Background(), templateReference(d), []string{"name", "config"}, &mvm)
+	if err != nil {
+		if isManagedObjectNotFound(err) {
+			log.Printf("[DEBUG] Template '%s' no longer exists", d.Id())
+			d.SetId("")
+			return nil
+		}
+		return err
+	}
+
+	// A template converted back to a VM outside of terraform no longer exists
+	if mvm.Config == nil || !mvm.Config.Template {
+		log.Printf("[DEBUG] Template '%s' is no longer a template", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	d.Set("name", mvm.Name)
+	d.Set("uuid", mvm.Config.Uuid)
+	d.Set("guest_id", mvm.Config.GuestId)
+	d.Set("disk", getTemplateDisks(object.VirtualDeviceList(mvm.Config.Hardware.Device)))
+	d.Set("object_id", d.Id())
+	return nil
+}
+
+func resourceVsphereTemplateUpdate(d *schema.ResourceData, meta interface{}) error {
+
+	if d.HasChange("name") {
+
+		log.Printf("[DEBUG] Renaming template '%s' to '%s'", d.Id(), d.Get("name").(string))
+
+		err := renameObject(context.Background(), meta.(*govmomi.Client).Client, templateReference(d), d.Get("name").(string))
+		if err != nil {
+			return err
+		}
+	}
+
+	return resourceVsphereTemplateRead(d, meta)
+}
+
+func resourceVsphereTemplateDelete(d *schema.ResourceData, meta interface{}) error {
+
+	if keep, ok := d.GetOk("keep"); !ok || !keep.(bool) {
+
+		client := meta.(*govmomi.Client)
+		vm := object.NewVirtualMachine(client.Client, templateReference(d))
+
+		onDestroy, err := getTemplateDestroyAction(d.Get("on_destroy").(string), d.Get("mark_as_template").(bool))
+		if err != nil {
+			return err
+		}
+
+		if onDestroy == "convert" {
+
+			finder, _, err := getFinder(d, meta)
+			if err != nil {
+				log.Printf("[ERROR] Unable to create finder for operations on template: '%s'", d.Id())
+				return err
+			}
+
+			pool, err := getTemplateResourcePool(d, meta, finder, vm)
+			if err != nil {
+				return err
+			}
+
+			log.Printf("[DEBUG] Converting template '%s' back to a VM", d.Id())
+
+			err = vm.MarkAsVirtualMachine(context.Background(), *pool, nil)
+			if err != nil && !isManagedObjectNotFound(err) {
+				log.Printf("[ERROR] Unable to convert template '%s' back to a VM", d.Id())
+				return err
+			}
+			return nil
+		}
+
+		log.Printf("[DEBUG] Deleting template: %s", d.Id())
+
+		task, err := vm.Destroy(context.Background())
+		if err != nil {
+			if isManagedObjectNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		_, err = waitForTask(client.Client, task, fmt.Sprintf("delete template '%s'", d.Id()), defaultTaskTimeout)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Turns the source VM itself into the template renaming it if a name is given.
+func markVMAsTemplate(d *schema.ResourceData, meta interface{}, vm *object.VirtualMachine) (types.ManagedObjectReference, error) {
+
+	client := meta.(*govmomi.Client)
+	ref := vm.Reference()
+
+	if v, ok := d.GetOk("name"); ok {
+
+		name, err := getObjectName(context.Background(), client.Client, ref)
+		if err != nil {
+			return ref, err
+		}
+		if name != v.(string) {
+			err = renameObject(context.Background(), client.Client, ref, v.(string))
+			if err != nil {
+				return ref, err
+			}
+		}
+	}
+
+	log.Printf("[DEBUG] Marking VM '%s' as a template", d.Get("source_vm").(string))
+
+	err := vm.MarkAsTemplate(context.Background())
+	if err != nil {
+		log.Printf("[ERROR] Unable to mark VM '%s' as a template", d.Get("source_vm").(string))
+	}
+	return ref, err
+}
+
+// Clones the source VM to a new template in the folder of the source VM.
+func cloneVMToTemplate(d *schema.ResourceData, meta interface{}, finder *find.Finder, datacenter *object.Datacenter, vm *object.VirtualMachine) (types.ManagedObjectReference, error) {
+
+	client := meta.(*govmomi.Client)
+
+	name, ok := d.GetOk("name")
+	if !ok {
+		return types.ManagedObjectReference{}, fmt.Errorf("a name is required to clone VM '%s' to a template", d.Get("source_vm").(string))
+	}
+
+	spec := types.VirtualMachineCloneSpec{
+		Template: true,
+		PowerOn: false,
+	}
+
+	if v, ok := d.GetOk("resource_pool"); ok {
+
+		pool, err := getResourcePoolParent(v.(string), finder, client)
+		if err != nil {
+			return types.ManagedObjectReference{}, err
+		}
+		ref := pool.Reference()
+		spec.Location.Pool = &ref
+	}
+
+	host, err := getVMHost(d, meta, finder)
+	if err != nil {
+		return types.ManagedObjectReference{}, err
+	}
+	if host != nil {
+		ref := host.Reference()
+		spec.Location.Host = &ref
+	}
+
+	if v, ok := d.GetOk("datastore"); ok {
+
+		datastore, err := findDatastore(context.Background(), finder, v.(string))
+		if err != nil {
+			return types.ManagedObjectReference{}, err
+		}
+		ref := datastore.Reference()
+		spec.Location.Datastore = &ref
+	}
+
+	folder, err := getTemplateFolder(context.Background(), client, datacenter, vm)
+	if err != nil {
+		return types.ManagedObjectReference{}, err
+	}
+
+	desc := fmt.Sprintf("clone VM '%s' to template '%s'", d.Get("source_vm").(string), name.(string))
+
+	task, err := vm.Clone(context.Background(), folder, name.(string), spec)
+	if err != nil {
+		log.Printf("[ERROR] Unable to %s", desc)
+		return types.ManagedObjectReference{}, err
+	}
+	info, err := waitForTask(client.Client, task, desc, longTaskTimeout)
+	if err != nil {
+		return types.ManagedObjectReference{}, err
+	}
+
+	return info.Result.(types.ManagedObjectReference), nil
+}
+
+func templateReference(d *schema.ResourceData) types.ManagedObjectReference {
+	return types.ManagedObjectReference{ Type: "VirtualMachine", Value: d.Id() }
+}
+
+// Returns the VM with the given object_id or name.
+func getTemplateSourceVM(ctx context.Context, client *govmomi.Client, finder *find.Finder, id string) (*object.VirtualMachine, error) {
+
+	ref, err := findObjectReference(ctx, client.Client, "VirtualMachine", id)
+	if err != nil {
+		return nil, err
+	}
+	if ref != nil {
+		return object.NewVirtualMachine(client.Client, *ref), nil
+	}
+	return finder.VirtualMachine(ctx, id)
+}
+
+// Returns the folder of the source VM or the datacenter's VM folder when
+// the source VM belongs to a vApp.
+func getTemplateFolder(ctx context.Context, client *govmomi.Client, datacenter *object.Datacenter, vm *object.VirtualMachine) (*object.Folder, error) {
+
+	var mvm mo.VirtualMachine
+
+	err := vm.Properties(ctx, vm.Reference(), []string{"parent"}, &mvm)
+	if err != nil {
+		return nil, err
+	}
+	if mvm.Parent != nil && mvm.Parent.Type == "Folder" {
+		return object.NewFolder(client.Client, *mvm.Parent), nil
+	}
+
+	folders, err := datacenter.Folders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return folders.VmFolder, nil
+}
+
+// Returns the resource pool a template is converted back to a VM in. It
+// defaults to the root resource pool of the host the template is on.
+func getTemplateResourcePool(d *schema.ResourceData, meta interface{}, finder *find.Finder, vm *object.VirtualMachine) (*object.ResourcePool, error) {
+
+	if v, ok := d.GetOk("resource_pool"); ok {
+		return getResourcePoolParent(v.(string), finder, meta.(*govmomi.Client))
+	}
+
+	var mvm mo.VirtualMachine
+
+	err := vm.Properties(context.Background(), vm.Reference(), []string{"runtime"}, &mvm)
+	if err != nil {
+		return nil, err
+	}
+	if mvm.Runtime.Host == nil {
+		return nil, fmt.Errorf("template '%s' is not registered on a host", d.Id())
+	}
+	return object.NewHostSystem(meta.(*govmomi.Client).Client, *mvm.Runtime.Host).ResourcePool(context.Background())
+}
+
+// Returns what is done with a template when it is destroyed.
+func getTemplateDestroyAction(onDestroy string, marked bool) (string, error) {
+
+	switch onDestroy {
+		case "":
+			if marked {
+				return "convert", nil
+			}
+			return "delete", nil
+		case "convert", "delete":
+			return onDestroy, nil
+	}
+	return "", fmt.Errorf("invalid on_destroy action '%s'. it should be one of convert or delete", onDestroy)
+}
+
+// Returns the disks of a template in the form of the vsphere_template disk list.
+func getTemplateDisks(devices object.VirtualDeviceList) []map[string]interface{} {
+
+	disks := []map[string]interface{}{}
+
+	for _, device := range devices.SelectByType((*types.VirtualDisk)(nil)) {
+
+		disk := device.(*types.VirtualDisk)
+		thin := false
+		fileName := ""
+		if backing, ok := disk.Backing.(types.BaseVirtualDeviceFileBackingInfo); ok {
+			fileName = backing.GetVirtualDeviceFileBackingInfo().FileName
+		}
+		if backing, ok := disk.Backing.(*types.VirtualDiskFlatVer2BackingInfo); ok && backing.ThinProvisioned != nil {
+			thin = *backing.ThinProvisioned
+		}
+
+		disks = append(disks, map[string]interface{}{
+			"path": fileName,
+			"size_gb": int(disk.CapacityInKB / (1024 * 1024)),
+			"thin_provisioned": thin,
+			"datastore": getDatastorePathName(fileName),
+		})
+	}
+
+	return disks
+}
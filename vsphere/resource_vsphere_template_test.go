@@ -0,0 +1,160 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/kr/pretty"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestAccVsphereTemplate_normal(t *testing.T) {
+
+	source := os.Getenv("TEMPLATE_SOURCE_VM")
+	if source == "" {
+		t.Skip("TEMPLATE_SOURCE_VM must be set to the name of a VM for the template acceptance test")
+	}
+
+	_, filename, _, _ := runtime.Caller(0)
+	ut := os.Getenv("UNIT_TEST")
+	if ut == "" || ut == filepath.Base(filename) {
+
+		resource.Test( t,
+			resource.TestCase {
+				PreCheck: func() { testAccPreCheck(t) },
+				Providers: testAccProviders,
+				CheckDestroy: testAccCheckTemplateDestroy,
+				Steps: []resource.TestStep {
+					resource.TestStep {
+						Config: fmt.Sprintf(testAccTemplateConfig, source, "1"),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckTemplateExists("vsphere_template.t1"),
+							resource.TestCheckResourceAttr("vsphere_template.t1", "name", "template1"),
+						),
+					},
+					resource.TestStep {
+						Config: fmt.Sprintf(testAccTemplateConfig, source, "2"),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckTemplateExists("vsphere_template.t1"),
+							resource.TestCheckResourceAttr("vsphere_template.t1", "version", "2"),
+						),
+					},
+				},
+			} )
+	}
+}
+
+func testAccCheckTemplateExists(resource string) resource.TestCheckFunc {
+
+	return func(s *terraform.State) error {
+
+		rs, ok := s.RootModule().Resources[resource]
+		if !ok {
+			return fmt.Errorf("template '%s' not found in terraform state", resource)
+		}
+
+		log.Printf("[DEBUG] Terraform template: %# v", pretty.Formatter(rs))
+
+		client := testAccProvider.Meta().(*govmomi.Client)
+
+		var mvm mo.VirtualMachine
+		ref := types.ManagedObjectReference{ Type: "VirtualMachine", Value: rs.Primary.ID }
+
+		err := property.DefaultCollector(client.Client).RetrieveOne(context.Background(), ref, []string{"config"}, &mvm)
+		if err != nil {
+			return err
+		}
+		if mvm.Config == nil || !mvm.Config.Template {
+			return fmt.Errorf("VM '%s' is not a template", rs.Primary.ID)
+		}
+		if mvm.Config.Uuid != rs.Primary.Attributes["uuid"] {
+			return fmt.Errorf("template '%s' has uuid '%s' but expected '%s'", rs.Primary.ID, mvm.Config.Uuid, rs.Primary.Attributes["uuid"])
+		}
+		return nil
+	}
+}
+
+func testAccCheckTemplateDestroy(s *terraform.State) error {
+
+	const t1 = "vsphere_template.t1"
+
+	_, ok := s.RootModule().Resources[t1]
+	if ok {
+		return fmt.Errorf("template '%s' still exists in the terraform state", t1)
+	}
+	return nil
+}
+
+func TestTemplateDestroyAction(t *testing.T) {
+
+	cases := []struct {
+		onDestroy string
+		marked bool
+		expected string
+	}{
+		{ "", true, "convert" },
+		{ "", false, "delete" },
+		{ "delete", true, "delete" },
+		{ "convert", false, "convert" },
+	}
+	for _, c := range cases {
+		action, err := getTemplateDestroyAction(c.onDestroy, c.marked)
+		if err != nil || action != c.expected {
+			t.Fatalf("expected on_destroy '%s' of a template marked %v to %s but got: %s %v", c.onDestroy, c.marked, c.expected, action, err)
+		}
+	}
+
+	_, err := getTemplateDestroyAction("unregister", false)
+	if err == nil {
+		t.Fatalf("expected an error for an unknown on_destroy action")
+	}
+}
+
+func TestTemplateDisks(t *testing.T) {
+
+	thin := true
+	devices := object.VirtualDeviceList{
+		&types.VirtualDisk{
+			VirtualDevice: types.VirtualDevice{
+				Key: 2000,
+				Backing: &types.VirtualDiskFlatVer2BackingInfo{
+					VirtualDeviceFileBackingInfo: types.VirtualDeviceFileBackingInfo{ FileName: "[datastore1] template1/template1.vmdk" },
+					ThinProvisioned: &thin,
+				},
+			},
+			CapacityInKB: 10 * 1024 * 1024,
+		},
+		&types.VirtualCdrom{ VirtualDevice: types.VirtualDevice{ Key: 3000 } },
+	}
+
+	disks := getTemplateDisks(devices)
+	if len(disks) != 1 {
+		t.Fatalf("expected 1 disk but got: %# v", pretty.Formatter(disks))
+	}
+	disk := disks[0]
+	if disk["path"] != "[datastore1] template1/template1.vmdk" || disk["size_gb"] != 10 ||
+		disk["thin_provisioned"] != true || disk["datastore"] != "datastore1" {
+		t.Fatalf("unexpected template disk: %# v", pretty.Formatter(disk))
+	}
+}
+
+const testAccTemplateConfig = `
+
+resource "vsphere_template" "t1" {
+	name = "template1"
+	source_vm = "%s"
+	version = "%s"
+}
+`
@@ -0,0 +1,303 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func resourceVsphereVAppContainer() *schema.Resource {
+
+	return &schema.Resource{
+
+		Create: resourceVsphereVAppContainerCreate,
+		Read:   resourceVsphereVAppContainerRead,
+		Update: resourceVsphereVAppContainerUpdate,
+		Delete: resourceVsphereVAppContainerDelete,
+
+		Schema: map[string]*schema.Schema{
+
+			"name": &schema.Schema{
+				Type: schema.TypeString,
+				Required: true,
+			},
+			"datacenter_id": &schema.Schema{
+				Type: schema.TypeString,
+				Required: true,
+			},
+			"parent_id": &schema.Schema{
+				Type: schema.TypeString, // Id or inventory path of a cluster, standalone host, resource pool or vApp
+				Required: true,
+			},
+			"cpu": resourceAllocationSchema(),
+			"memory": resourceAllocationSchema(),
+			"keep": &schema.Schema{
+				Type: schema.TypeBool,
+				Optional: true,
+			},
+			"object_id": &schema.Schema{
+				Type: schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func resourceVsphereVAppContainerCreate(d *schema.ResourceData, meta interface{}) error {
+
+	vApp, err := findVAppContainer(d, meta)
+	if err != nil {
+
+		if !strings.Contains(err.Error(), "not found") {
+			return err
+		}
+
+		finder, datacenter, err := getFinder(d, meta)
+		if err != nil {
+			log.Printf("[ERROR] Unable to create finder for operations on vApp: '%s'", d.Get("name").(string))
+			return err
+		}
+
+		client := meta.(*govmomi.Client)
+
+		parentResourcePool, err := getResourcePoolParent(d.Get("parent_id").(string), finder, client)
+		if err != nil {
+			log.Printf("[ERROR] Unable to retrieve resource pool of parent '%s'", d.Get("parent_id").(string))
+			return err
+		}
+
+		req := types.CreateVApp{
+			This: parentResourcePool.Reference(),
+			Name: d.Get("name").(string),
+		}
+		err = getAllocationInfo("cpu", d.Get("cpu").([]interface{}), &req.ResSpec.CpuAllocation)
+		if err != nil {
+			return err
+		}
+		err = getAllocationInfo("memory", d.Get("memory").([]interface{}), &req.ResSpec.MemoryAllocation)
+		if err != nil {
+			return err
+		}
+
+		// Child vApps are placed in their parent vApp's folder
+		if parentResourcePool.Reference().Type != "VirtualApp" {
+
+			df, err := datacenter.Folders(context.Background())
+			if err != nil {
+				return err
+			}
+			vmFolder := df.VmFolder.Reference()
+			req.VmFolder = &vmFolder
+		}
+
+		log.Printf("[DEBUG] Creating new vApp in parent '%s'", d.Get("parent_id").(string))
+
+		res, err := methods.CreateVApp(context.Background(), client.Client, &req)
+		if err != nil {
+			log.Printf("[ERROR] Unable to create vApp '%s'", d.Get("name").(string))
+			return err
+		}
+
+		vApp = &object.VirtualApp{ ResourcePool: object.NewResourcePool(client.Client, res.Returnval) }
+	}
+
+	d.SetId(vApp.Reference().Value)
+	d.Set("object_id", vApp.Reference().Value)
+	return resourceVsphereVAppContainerRead(d, meta)
+}
+
+func resourceVsphereVAppContainerRead(d *schema.ResourceData, meta interface{}) error {
+
+	vApp, err := findVAppContainer(d, meta)
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	log.Printf("[DEBUG] Reading configuration of vApp: %s", vApp.Reference().Value)
+
+	var mva mo.VirtualApp
+
+	ps := []string{"name", "parent", "config"}
+	err = vApp.Properties(context.Background(), vApp.Reference(), ps, &mva)
+	if err != nil {
+		return err
+	}
+
+	if mva.Parent != nil {
+
+		finder, _, err := getFinder(d, meta)
+		if err != nil {
+			return err
+		}
+
+		parentResourcePool, err := getResourcePoolParent(d.Get("parent_id").(string), finder, meta.(*govmomi.Client))
+		if err != nil {
+			log.Printf("[ERROR] Unable to find parent '%s' of vApp: '%s'", d.Get("parent_id").(string), d.Id())
+			return err
+		}
+		if parentResourcePool.Reference().Value != mva.Parent.Value {
+			d.Set("parent_id", mva.Parent.Value)
+		}
+	}
+
+	d.Set("name", mva.Name)
+	d.Set("cpu", putAllocationInfo(&mva.Config.CpuAllocation))
+	d.Set("memory", putAllocationInfo(&mva.Config.MemoryAllocation))
+	d.Set("object_id", vApp.Reference().Value)
+	return nil
+}
+
+func resourceVsphereVAppContainerUpdate(d *schema.ResourceData, meta interface{}) error {
+
+	vApp, err := findVAppContainer(d, meta)
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	log.Printf("[DEBUG] Updating vApp: %s", d.Id())
+
+	client := meta.(*govmomi.Client)
+
+	name, err := getObjectName(context.Background(), client.Client, vApp.Reference())
+	if err != nil {
+		return err
+	}
+	if name != d.Get("name").(string) {
+
+		log.Printf("[DEBUG] Renaming vApp '%s' to '%s'", name, d.Get("name").(string))
+
+		err = renameObject(context.Background(), client.Client, vApp.Reference(), d.Get("name").(string))
+		if err != nil {
+			log.Printf("[ERROR] Unable to rename vApp '%s'", name)
+			return err
+		}
+	}
+
+	if d.HasChange("parent_id") {
+
+		err = moveResourcePool(d, meta, vApp.ResourcePool)
+		if err != nil {
+			log.Printf("[ERROR] Unable to move vApp '%s' to parent '%s'", d.Get("name").(string), d.Get("parent_id").(string))
+			return err
+		}
+	}
+
+	spec := types.ResourceConfigSpec{}
+	err = getAllocationInfo("cpu", d.Get("cpu").([]interface{}), &spec.CpuAllocation)
+	if err != nil {
+		return err
+	}
+	err = getAllocationInfo("memory", d.Get("memory").([]interface{}), &spec.MemoryAllocation)
+	if err != nil {
+		return err
+	}
+
+	err = vApp.UpdateConfig(context.Background(), "", &spec)
+	if err != nil {
+		log.Printf("[ERROR] Unable to update vApp '%s'", d.Get("name").(string))
+		return err
+	}
+
+	return resourceVsphereVAppContainerRead(d, meta)
+}
+
+func resourceVsphereVAppContainerDelete(d *schema.ResourceData, meta interface{}) error {
+
+	if keep, ok := d.GetOk("keep"); !ok || !keep.(bool) {
+
+		vApp, err := findVAppContainer(d, meta)
+		if err != nil {
+			return err
+		}
+
+		client := meta.(*govmomi.Client)
+
+		var mva mo.VirtualApp
+
+		err = vApp.Properties(context.Background(), vApp.Reference(), []string{"summary"}, &mva)
+		if err != nil {
+			return err
+		}
+
+		if summary, ok := mva.Summary.(*types.VirtualAppSummary); ok && summary.VAppState == types.VirtualAppVAppStateStarted {
+
+			log.Printf("[DEBUG] Powering off vApp: %s", d.Id())
+
+			res, err := methods.PowerOffVApp_Task(context.Background(), client.Client,
+				&types.PowerOffVApp_Task{ This: vApp.Reference(), Force: true })
+			if err != nil {
+				return err
+			}
+			_, err = waitForTask(client.Client, object.NewTask(client.Client, res.Returnval), fmt.Sprintf("power off vApp '%s'", d.Id()), defaultTaskTimeout)
+			if err != nil {
+				return err
+			}
+		}
+
+		log.Printf("[DEBUG] Deleting vApp: %s", d.Id())
+
+		task, err := vApp.Destroy(context.Background())
+		if err != nil {
+			return err
+		}
+	  	_, err = waitForTask(client.Client, task, fmt.Sprintf("delete vApp '%s'", d.Id()), defaultTaskTimeout)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func findVAppContainer(d *schema.ResourceData, meta interface{}) (*object.VirtualApp, error) {
+
+	finder, _, err := getFinder(d, meta)
+	if err != nil {
+		log.Printf("[ERROR] Unable to create finder for operations on vApp: '%s'", d.Get("name").(string))
+		return nil, err
+	}
+
+	client := meta.(*govmomi.Client)
+
+	if d.Id() != "" {
+		ref, err := findObjectReference(context.Background(), client.Client, "VirtualApp", d.Id())
+		if err != nil {
+			return nil, err
+		}
+		if ref != nil {
+			return &object.VirtualApp{ ResourcePool: object.NewResourcePool(client.Client, *ref) }, nil
+		}
+	}
+
+	parentResourcePool, err := getResourcePoolParent(d.Get("parent_id").(string), finder, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return getVAppContainer(d.Get("name").(string), parentResourcePool, client)
+}
+
+// Returns the child vApp with the given name of the parent resource pool or vApp.
+func getVAppContainer(name string, parent *object.ResourcePool, client *govmomi.Client) (*object.VirtualApp, error) {
+
+	child, err := object.NewSearchIndex(client.Client).FindChild(context.Background(), parent, name)
+	if err != nil {
+		log.Printf("[ERROR] VMOMI error when searching for vApp '%s' in parent '%s': %s", name, parent.Reference().Value, err.Error())
+		return nil, err
+	}
+	if child == nil || child.Reference().Type != "VirtualApp" {
+		return nil, fmt.Errorf("vApp %s was not found", name)
+	}
+
+	return &object.VirtualApp{ ResourcePool: object.NewResourcePool(client.Client, child.Reference()) }, nil
+}
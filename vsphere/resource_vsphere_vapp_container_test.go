@@ -0,0 +1,191 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"golang.org/x/net/context"
+	
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/kr/pretty"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+)
+
+var keepVAppContainer bool
+
+func TestAccVsphereVAppContainer_normal(t *testing.T) {
+	
+	keepVAppContainer = false
+
+	_, filename, _, _ := runtime.Caller(0)
+	ut := os.Getenv("UNIT_TEST")
+	if ut == "" || ut == filepath.Base(filename) {
+		
+		resource.Test( t, 
+			resource.TestCase {
+				PreCheck: func() { testAccPreCheck(t) },
+				Providers: testAccProviders,
+				CheckDestroy: testAccCheckVAppContainerDestroy,
+				Steps: []resource.TestStep {
+					resource.TestStep {
+						Config: fmt.Sprintf( testAccVAppContainerConfig, 
+							testEsxHost.IP,
+							testEsxHost.User,
+							testEsxHost.Password,
+							testEsxHost.License,
+						),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckVAppContainerExists("vsphere_vapp_container.va7"),
+							resource.TestCheckResourceAttr("vsphere_vapp_container.va7", "name", "vapp7"),
+							
+							resource.TestCheckResourceAttr(
+								"vsphere_vapp_container.va7", "cpu.0.shares", "high"),
+							resource.TestCheckResourceAttr(
+								"vsphere_vapp_container.va7", "cpu.0.limit", "-1"),
+							resource.TestCheckResourceAttr(
+								"vsphere_vapp_container.va7", "memory.0.shares", "normal"),
+							resource.TestCheckResourceAttr(
+								"vsphere_vapp_container.va7", "memory.0.reservation", "1024"),
+						),
+					},
+				},
+			} )
+	}
+}
+
+func testAccCheckVAppContainerExists(resource string) resource.TestCheckFunc {
+	
+	return func(s *terraform.State) error {
+		
+		rs, ok := s.RootModule().Resources[resource]
+		if !ok {
+			return fmt.Errorf("vApp '%s' not found in terraform state", resource)
+		}
+		
+		log.Printf("[DEBUG] Terraform vApp: %# v", pretty.Formatter(rs))
+		
+		attributes := rs.Primary.Attributes
+		
+		vApp, err := findTestVAppContainer(attributes["name"], attributes["parent_id"], attributes["datacenter_id"])
+		if err != nil {
+			return err
+		}
+		if vApp.Reference().Value != rs.Primary.ID {
+			return fmt.Errorf("vApp id mismatch. expected '%s' but got '%s'", vApp.Reference().Value, rs.Primary.ID)
+		}
+		
+		var mva mo.VirtualApp
+		
+		err = vApp.Properties(context.Background(), vApp.Reference(), []string{"config"}, &mva)
+		if err != nil {
+			return err
+		}
+		
+		if err := verifyResourceAllocation("cpu", mva.Config.CpuAllocation, rs.Primary); err != nil {
+			return err		
+		}
+		if err := verifyResourceAllocation("memory", mva.Config.MemoryAllocation, rs.Primary); err != nil {
+			return err		
+		}
+		
+		keepVAppContainer = (attributes["keep"] == "true")
+		return nil
+	}
+}
+
+func testAccCheckVAppContainerDestroy(s *terraform.State) error {
+
+	const va7 = "vsphere_vapp_container.va7"
+	const datacenter7 = "datacenter7"
+	const cluster7 = "cluster7"
+	const vApp7 = "vapp7"
+
+	_, ok := s.RootModule().Resources[va7]
+	if ok {
+		return fmt.Errorf("vApp '%s' still exists in the terraform state", va7)
+	}
+
+	vApp, err := findTestVAppContainer(vApp7, cluster7, datacenter7)
+	if err != nil {
+		log.Printf("[DEBUG] vApp '%s' destroyed as expected. API response was: %s", vApp7, err.Error())
+	} else if keepVAppContainer {
+		log.Printf("[DEBUG] vApp '%s' not destroyed as expected", vApp.Reference().Value)
+	} else {
+		return fmt.Errorf("vApp '%s' was not destroyed as expected", vApp.Reference().Value)
+	}
+	
+	return nil
+}
+
+func findTestVAppContainer(vAppName, parentName string, datacenterName string) (*object.VirtualApp, error) {
+	
+	finder, err := getTestFinder(datacenterName)
+	if err != nil {
+		return nil, err
+	}
+	
+	client := testAccProvider.Meta().(*govmomi.Client)
+	
+	parent, err := getResourcePoolParent(parentName, finder, client)
+	if err != nil {
+		return nil, err
+	}
+	
+	return getVAppContainer(vAppName, parent, client)
+}
+
+const testAccVAppContainerConfig = `
+
+resource "vsphere_datacenter" "dc7" {
+	name = "datacenter7"
+
+#	keep = true
+}
+
+resource "vsphere_cluster" "c7" {
+	name = "cluster7"
+	datacenter_id = "${vsphere_datacenter.dc7.id}"
+  
+	drs {}
+
+#	keep = true
+}
+
+resource "vsphere_host" "h7" {
+	host = "%s"
+	datacenter_id = "${vsphere_datacenter.dc7.id}"
+	cluster_id = "${vsphere_cluster.c7.id}"
+	
+	user = "%s"
+	password = "%s"
+	license = "%s"
+	
+	ssl_no_verify = true
+#	keep = true
+}
+
+resource "vsphere_vapp_container" "va7" {
+	depends_on = ["vsphere_host.h7"]
+
+	name = "vapp7"
+	datacenter_id = "${vsphere_datacenter.dc7.id}"
+	parent_id = "${vsphere_cluster.c7.id}"
+	
+	cpu {
+		shares = "high"
+	}
+	
+	memory {
+		reservation = 1024
+	}
+	
+#	keep = false
+}
+`
@@ -0,0 +1,317 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func resourceVsphereVAppEntity() *schema.Resource {
+
+	return &schema.Resource{
+
+		Create: resourceVsphereVAppEntityCreate,
+		Read:   resourceVsphereVAppEntityRead,
+		Update: resourceVsphereVAppEntityUpdate,
+		Delete: resourceVsphereVAppEntityDelete,
+
+		Schema: map[string]*schema.Schema{
+
+			"datacenter_id": &schema.Schema{
+				Type: schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"vapp_id": &schema.Schema{
+				Type: schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"target_id": &schema.Schema{
+				Type: schema.TypeString, // Id or name of a virtual machine or child vApp in the vApp
+				Required: true,
+				ForceNew: true,
+			},
+			"start_order": &schema.Schema{
+				Type: schema.TypeInt,
+				Optional: true,
+				Default: 1,
+			},
+			"start_delay": &schema.Schema{
+				Type: schema.TypeInt, // Seconds to wait before starting the next group
+				Optional: true,
+				Default: 120,
+			},
+			"start_action": &schema.Schema{
+				Type: schema.TypeString, // One of none or powerOn
+				Optional: true,
+				Default: "powerOn",
+			},
+			"wait_for_guest": &schema.Schema{
+				Type: schema.TypeBool,
+				Optional: true,
+				Default: false,
+			},
+			"stop_delay": &schema.Schema{
+				Type: schema.TypeInt, // Seconds to wait before stopping the next group
+				Optional: true,
+				Default: 120,
+			},
+			"stop_action": &schema.Schema{
+				Type: schema.TypeString, // One of none, powerOff, guestShutdown or suspend
+				Optional: true,
+				Default: "powerOff",
+			},
+			"original_config": &schema.Schema{
+				Type: schema.TypeMap, // Settings the entity had before it was configured which are restored on destroy
+				Computed: true,
+			},
+		},
+	}
+}
+
+func resourceVsphereVAppEntityCreate(d *schema.ResourceData, meta interface{}) error {
+
+	vApp, target, err := findVAppEntityTarget(d, meta)
+	if err != nil {
+		return err
+	}
+
+	original, err := findVAppEntityConfig(vApp, target.Value)
+	if err != nil {
+		return err
+	}
+	if original == nil {
+		return fmt.Errorf("'%s' is not a member of vApp '%s'", d.Get("target_id").(string), d.Get("vapp_id").(string))
+	}
+	d.Set("original_config", putVAppEntityOriginalConfig(original))
+
+	log.Printf("[DEBUG] Configuring startup of '%s' in vApp '%s'", d.Get("target_id").(string), d.Get("vapp_id").(string))
+
+	err = reconfigureVAppEntity(meta, vApp, getVAppEntityConfigInfo(d, target))
+	if err != nil {
+		log.Printf("[ERROR] Unable to configure startup of '%s' in vApp '%s'", d.Get("target_id").(string), d.Get("vapp_id").(string))
+		return err
+	}
+
+	d.SetId(target.Value)
+	return resourceVsphereVAppEntityRead(d, meta)
+}
+
+func resourceVsphereVAppEntityRead(d *schema.ResourceData, meta interface{}) error {
+
+	vApp, err := getVAppContainerByID(d.Get("vapp_id").(string), meta)
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	e, err := findVAppEntityConfig(vApp, d.Id())
+	if err != nil {
+		return err
+	}
+	if e != nil {
+		d.Set("start_order", e.StartOrder)
+		d.Set("start_delay", e.StartDelay)
+		d.Set("start_action", e.StartAction)
+		d.Set("wait_for_guest", e.WaitingForGuest != nil && *e.WaitingForGuest)
+		d.Set("stop_delay", e.StopDelay)
+		d.Set("stop_action", e.StopAction)
+		return nil
+	}
+
+	log.Printf("[DEBUG] '%s' is no longer a member of vApp '%s'", d.Id(), d.Get("vapp_id").(string))
+	d.SetId("")
+	return nil
+}
+
+func resourceVsphereVAppEntityUpdate(d *schema.ResourceData, meta interface{}) error {
+
+	vApp, target, err := findVAppEntityTarget(d, meta)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[DEBUG] Updating startup of '%s' in vApp '%s'", d.Id(), d.Get("vapp_id").(string))
+
+	err = reconfigureVAppEntity(meta, vApp, getVAppEntityConfigInfo(d, target))
+	if err != nil {
+		log.Printf("[ERROR] Unable to update startup of '%s' in vApp '%s'", d.Id(), d.Get("vapp_id").(string))
+		return err
+	}
+
+	return resourceVsphereVAppEntityRead(d, meta)
+}
+
+func resourceVsphereVAppEntityDelete(d *schema.ResourceData, meta interface{}) error {
+
+	vApp, target, err := findVAppEntityTarget(d, meta)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[DEBUG] Resetting startup of '%s' in vApp '%s'", d.Id(), d.Get("vapp_id").(string))
+
+	// Entity settings exist for as long as the target is a member of
+	// the vApp so they are restored to what they were before
+	return reconfigureVAppEntity(meta, vApp, getVAppEntityOriginalConfig(d, target))
+}
+
+func getVAppEntityConfigInfo(d *schema.ResourceData, target *types.ManagedObjectReference) types.VAppEntityConfigInfo {
+
+	waitForGuest := d.Get("wait_for_guest").(bool)
+
+	return types.VAppEntityConfigInfo{
+		Key: target,
+		StartOrder: d.Get("start_order").(int),
+		StartDelay: d.Get("start_delay").(int),
+		StartAction: d.Get("start_action").(string),
+		WaitingForGuest: &waitForGuest,
+		StopDelay: d.Get("stop_delay").(int),
+		StopAction: d.Get("stop_action").(string),
+	}
+}
+
+// Returns the settings the entity had before it was configured as strings
+// so that they can be kept in the original_config map.
+func putVAppEntityOriginalConfig(e *types.VAppEntityConfigInfo) map[string]interface{} {
+
+	return map[string]interface{}{
+		"start_order": strconv.Itoa(e.StartOrder),
+		"start_delay": strconv.Itoa(e.StartDelay),
+		"start_action": e.StartAction,
+		"wait_for_guest": strconv.FormatBool(e.WaitingForGuest != nil && *e.WaitingForGuest),
+		"stop_delay": strconv.Itoa(e.StopDelay),
+		"stop_action": e.StopAction,
+	}
+}
+
+// Returns the settings to restore on destroy. Entities configured before the
+// original settings were recorded are reset to vCenter's defaults.
+func getVAppEntityOriginalConfig(d *schema.ResourceData, target *types.ManagedObjectReference) types.VAppEntityConfigInfo {
+
+	entityConfig := types.VAppEntityConfigInfo{
+		Key: target,
+		StartOrder: 1,
+		StartDelay: 120,
+		StartAction: "powerOn",
+		StopDelay: 120,
+		StopAction: "powerOff",
+	}
+	waitForGuest := false
+
+	original := d.Get("original_config").(map[string]interface{})
+	if v, ok := original["start_order"].(string); ok {
+		entityConfig.StartOrder, _ = strconv.Atoi(v)
+	}
+	if v, ok := original["start_delay"].(string); ok {
+		entityConfig.StartDelay, _ = strconv.Atoi(v)
+	}
+	if v, ok := original["start_action"].(string); ok {
+		entityConfig.StartAction = v
+	}
+	if v, ok := original["wait_for_guest"].(string); ok {
+		waitForGuest = v == "true"
+	}
+	if v, ok := original["stop_delay"].(string); ok {
+		entityConfig.StopDelay, _ = strconv.Atoi(v)
+	}
+	if v, ok := original["stop_action"].(string); ok {
+		entityConfig.StopAction = v
+	}
+	entityConfig.WaitingForGuest = &waitForGuest
+
+	return entityConfig
+}
+
+// Returns the settings of the member of the vApp with the given id or
+// nil if it is not a member of the vApp.
+func findVAppEntityConfig(vApp *object.VirtualApp, id string) (*types.VAppEntityConfigInfo, error) {
+
+	var mva mo.VirtualApp
+
+	err := vApp.Properties(context.Background(), vApp.Reference(), []string{"vAppConfig"}, &mva)
+	if err != nil {
+		return nil, err
+	}
+
+	if mva.VAppConfig != nil {
+		for i, e := range mva.VAppConfig.EntityConfig {
+			if e.Key != nil && e.Key.Value == id {
+				return &mva.VAppConfig.EntityConfig[i], nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func reconfigureVAppEntity(meta interface{}, vApp *object.VirtualApp, entityConfig types.VAppEntityConfigInfo) error {
+
+	req := types.UpdateVAppConfig{
+		This: vApp.Reference(),
+		Spec: types.VAppConfigSpec{
+			EntityConfig: []types.VAppEntityConfigInfo{ entityConfig },
+		},
+	}
+
+	_, err := methods.UpdateVAppConfig(context.Background(), meta.(*govmomi.Client).Client, &req)
+	return err
+}
+
+// Returns the vApp and the reference of the virtual machine or
+// child vApp whose start up and shut down is configured.
+func findVAppEntityTarget(d *schema.ResourceData, meta interface{}) (*object.VirtualApp, *types.ManagedObjectReference, error) {
+
+	vApp, err := getVAppContainerByID(d.Get("vapp_id").(string), meta)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client := meta.(*govmomi.Client)
+	targetID := d.Get("target_id").(string)
+
+	for _, refType := range []string{"VirtualMachine", "VirtualApp"} {
+		ref, err := findObjectReference(context.Background(), client.Client, refType, targetID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ref != nil {
+			return vApp, ref, nil
+		}
+	}
+
+	child, err := object.NewSearchIndex(client.Client).FindChild(context.Background(), vApp, targetID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if child == nil {
+		return nil, nil, fmt.Errorf("'%s' was not found in vApp '%s'", targetID, d.Get("vapp_id").(string))
+	}
+
+	ref := child.Reference()
+	return vApp, &ref, nil
+}
+
+func getVAppContainerByID(vAppID string, meta interface{}) (*object.VirtualApp, error) {
+
+	client := meta.(*govmomi.Client)
+
+	ref, err := findObjectReference(context.Background(), client.Client, "VirtualApp", vAppID)
+	if err != nil {
+		return nil, err
+	}
+	if ref == nil {
+		return nil, fmt.Errorf("vApp %s was not found", vAppID)
+	}
+
+	return &object.VirtualApp{ ResourcePool: object.NewResourcePool(client.Client, *ref) }, nil
+}
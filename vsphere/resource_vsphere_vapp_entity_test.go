@@ -0,0 +1,172 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/kr/pretty"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestAccVsphereVAppEntity_normal(t *testing.T) {
+
+	datacenter := os.Getenv("VAPP_ENTITY_DATACENTER")
+	vApp := os.Getenv("VAPP_ENTITY_VAPP")
+	vm := os.Getenv("VAPP_ENTITY_VM")
+	if datacenter == "" || vApp == "" || vm == "" {
+		t.Skip("VAPP_ENTITY_DATACENTER, VAPP_ENTITY_VAPP and VAPP_ENTITY_VM must be set to the object_id of a vApp and the name of one of its VMs for the vApp entity acceptance test")
+	}
+
+	var original *types.VAppEntityConfigInfo
+
+	_, filename, _, _ := runtime.Caller(0)
+	ut := os.Getenv("UNIT_TEST")
+	if ut == "" || ut == filepath.Base(filename) {
+
+		resource.Test( t,
+			resource.TestCase {
+				PreCheck: func() {
+					testAccPreCheck(t)
+					original = findTestVAppEntityConfig(t, datacenter, vApp, vm)
+				},
+				Providers: testAccProviders,
+				CheckDestroy: testAccCheckVAppEntityDestroy(t, datacenter, vApp, vm, &original),
+				Steps: []resource.TestStep {
+					resource.TestStep {
+						Config: fmt.Sprintf(testAccVAppEntityConfig, datacenter, vApp, vm, 2, 30, "guestShutdown"),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckVAppEntityExists("vsphere_vapp_entity.e9"),
+							resource.TestCheckResourceAttr("vsphere_vapp_entity.e9", "start_order", "2"),
+						),
+					},
+					resource.TestStep {
+						Config: fmt.Sprintf(testAccVAppEntityConfig, datacenter, vApp, vm, 3, 60, "powerOff"),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckVAppEntityExists("vsphere_vapp_entity.e9"),
+							resource.TestCheckResourceAttr("vsphere_vapp_entity.e9", "stop_action", "powerOff"),
+						),
+					},
+				},
+			} )
+	}
+}
+
+func testAccCheckVAppEntityExists(resource string) resource.TestCheckFunc {
+
+	return func(s *terraform.State) error {
+
+		rs, ok := s.RootModule().Resources[resource]
+		if !ok {
+			return fmt.Errorf("vApp entity '%s' not found in terraform state", resource)
+		}
+
+		log.Printf("[DEBUG] Terraform vApp entity: %# v", pretty.Formatter(rs))
+
+		attributes := rs.Primary.Attributes
+
+		vApp, err := getVAppContainerByID(attributes["vapp_id"], testAccProvider.Meta())
+		if err != nil {
+			return err
+		}
+		e, err := findVAppEntityConfig(vApp, rs.Primary.ID)
+		if err != nil {
+			return err
+		}
+		if e == nil {
+			return fmt.Errorf("'%s' is not a member of vApp '%s'", rs.Primary.ID, attributes["vapp_id"])
+		}
+		if strconv.Itoa(e.StartOrder) != attributes["start_order"] ||
+			strconv.Itoa(e.StartDelay) != attributes["start_delay"] ||
+			e.StopAction != attributes["stop_action"] {
+			return fmt.Errorf("startup settings of '%s' do not match: %# v", rs.Primary.ID, pretty.Formatter(e))
+		}
+		return nil
+	}
+}
+
+func testAccCheckVAppEntityDestroy(t *testing.T, datacenter, vApp, vm string, original **types.VAppEntityConfigInfo) resource.TestCheckFunc {
+
+	return func(s *terraform.State) error {
+
+		e := findTestVAppEntityConfig(t, datacenter, vApp, vm)
+		if e.StartOrder != (*original).StartOrder || e.StartDelay != (*original).StartDelay ||
+			e.StartAction != (*original).StartAction || e.StopDelay != (*original).StopDelay ||
+			e.StopAction != (*original).StopAction {
+			return fmt.Errorf("startup settings of '%s' were not restored: %# v", vm, pretty.Formatter(e))
+		}
+		return nil
+	}
+}
+
+func findTestVAppEntityConfig(t *testing.T, datacenter, vAppID, vmName string) *types.VAppEntityConfigInfo {
+
+	finder, err := getTestFinder(datacenter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vm, err := finder.VirtualMachine(context.Background(), vmName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vApp, err := getVAppContainerByID(vAppID, testAccProvider.Meta())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := findVAppEntityConfig(vApp, vm.Reference().Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e == nil {
+		t.Fatalf("VM '%s' is not a member of vApp '%s'", vmName, vAppID)
+	}
+	return e
+}
+
+func TestVAppEntityOriginalConfig(t *testing.T) {
+
+	waitForGuest := true
+	original := putVAppEntityOriginalConfig(&types.VAppEntityConfigInfo{
+		StartOrder: 3,
+		StartDelay: 0,
+		StartAction: "none",
+		WaitingForGuest: &waitForGuest,
+		StopDelay: 30,
+		StopAction: "guestShutdown",
+	})
+
+	expected := map[string]interface{}{
+		"start_order": "3",
+		"start_delay": "0",
+		"start_action": "none",
+		"wait_for_guest": "true",
+		"stop_delay": "30",
+		"stop_action": "guestShutdown",
+	}
+	for k, v := range expected {
+		if original[k] != v {
+			t.Fatalf("expected original %s to be '%v' but got: %# v", k, v, pretty.Formatter(original))
+		}
+	}
+}
+
+const testAccVAppEntityConfig = `
+
+resource "vsphere_vapp_entity" "e9" {
+	datacenter_id = "%s"
+	vapp_id = "%s"
+	target_id = "%s"
+
+	start_order = %d
+	start_delay = %d
+	stop_action = "%s"
+}
+`
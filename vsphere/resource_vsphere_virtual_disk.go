@@ -0,0 +1,220 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// Disk types accepted by vsphere_virtual_disk mapped to vSphere's disk types
+var virtualDiskTypes = map[string]types.VirtualDiskType{
+	"thin": types.VirtualDiskTypeThin,
+	"lazy": types.VirtualDiskTypePreallocated,
+	"eagerZeroedThick": types.VirtualDiskTypeEagerZeroedThick,
+}
+
+func resourceVsphereVirtualDisk() *schema.Resource {
+
+	return &schema.Resource{
+
+		Create: resourceVsphereVirtualDiskCreate,
+		Read:   resourceVsphereVirtualDiskRead,
+		Update: resourceVsphereVirtualDiskUpdate,
+		Delete: resourceVsphereVirtualDiskDelete,
+
+		Schema: map[string]*schema.Schema{
+
+			"datacenter_id": &schema.Schema{
+				Type: schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"datastore": &schema.Schema{
+				Type: schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"vmdk_path": &schema.Schema{
+				Type: schema.TypeString, // Path of the vmdk file in the datastore
+				Required: true,
+				ForceNew: true,
+			},
+			"size": &schema.Schema{
+				Type: schema.TypeInt, // Size in GB which can only be increased
+				Required: true,
+			},
+			"adapter_type": &schema.Schema{
+				Type: schema.TypeString, // One of ide, busLogic or lsiLogic
+				Optional: true,
+				Default: "lsiLogic",
+				ForceNew: true,
+			},
+			"type": &schema.Schema{
+				Type: schema.TypeString, // One of thin, lazy or eagerZeroedThick
+				Optional: true,
+				Default: "thin",
+				ForceNew: true,
+			},
+			"keep": &schema.Schema{
+				Type: schema.TypeBool,
+				Optional: true,
+			},
+		},
+	}
+}
+
+func resourceVsphereVirtualDiskCreate(d *schema.ResourceData, meta interface{}) error {
+
+	diskType, ok := virtualDiskTypes[d.Get("type").(string)]
+	if !ok {
+		return fmt.Errorf("invalid disk type '%s'. it should be one of thin, lazy or eagerZeroedThick", d.Get("type").(string))
+	}
+
+	datastore, datacenter, err := getFileDatastore(d, meta, d.Get("datastore").(string))
+	if err != nil {
+		return err
+	}
+
+	client := meta.(*govmomi.Client)
+	vmdkPath := d.Get("vmdk_path").(string)
+
+	err = makeDatastoreFileFolder(context.Background(), client, datacenter, datastore, vmdkPath)
+	if err != nil {
+		return err
+	}
+
+	dcRef := datacenter.Reference()
+	req := types.CreateVirtualDisk_Task{
+		This: *client.Client.ServiceContent.VirtualDiskManager,
+		Name: datastore.Path(vmdkPath),
+		Datacenter: &dcRef,
+		Spec: &types.FileBackedVirtualDiskSpec{
+			VirtualDiskSpec: types.VirtualDiskSpec{
+				DiskType: string(diskType),
+				AdapterType: d.Get("adapter_type").(string),
+			},
+			CapacityKb: int64(d.Get("size").(int)) * 1024 * 1024,
+		},
+	}
+
+	log.Printf("[DEBUG] Creating virtual disk: %s", req.Name)
+
+	res, err := methods.CreateVirtualDisk_Task(context.Background(), client.Client, &req)
+	if err != nil {
+		log.Printf("[ERROR] Unable to create virtual disk '%s'", req.Name)
+		return err
+	}
+	_, err = waitForTask(client.Client, object.NewTask(client.Client, res.Returnval), fmt.Sprintf("create virtual disk '%s'", req.Name), longTaskTimeout)
+	if err != nil {
+		log.Printf("[ERROR] Unable to create virtual disk '%s'", req.Name)
+		return err
+	}
+
+	d.SetId(req.Name)
+	return resourceVsphereVirtualDiskRead(d, meta)
+}
+
+func resourceVsphereVirtualDiskRead(d *schema.ResourceData, meta interface{}) error {
+
+	datastore, _, err := getFileDatastore(d, meta, d.Get("datastore").(string))
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	query := []types.BaseFileQuery{
+		&types.VmDiskFileQuery{
+			Details: &types.VmDiskFileQueryFlags{ CapacityKb: true, DiskType: true },
+		},
+	}
+
+	file, err := searchDatastoreFile(context.Background(), meta.(*govmomi.Client), datastore, d.Get("vmdk_path").(string), query)
+	if err != nil {
+		return err
+	}
+	if file == nil {
+		log.Printf("[DEBUG] Virtual disk '%s' no longer exists", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	if disk, ok := file.(*types.VmDiskFileInfo); ok {
+		d.Set("size", int(disk.CapacityKb / (1024 * 1024)))
+	}
+	return nil
+}
+
+func resourceVsphereVirtualDiskUpdate(d *schema.ResourceData, meta interface{}) error {
+
+	if d.HasChange("size") {
+
+		oldSize, newSize := d.GetChange("size")
+		if newSize.(int) < oldSize.(int) {
+			return fmt.Errorf("virtual disk '%s' cannot be shrunk from %dGB to %dGB", d.Id(), oldSize.(int), newSize.(int))
+		}
+
+		_, datacenter, err := getFileDatastore(d, meta, d.Get("datastore").(string))
+		if err != nil {
+			return err
+		}
+
+		client := meta.(*govmomi.Client)
+
+		eagerZero := d.Get("type").(string) == "eagerZeroedThick"
+		dcRef := datacenter.Reference()
+
+		req := types.ExtendVirtualDisk_Task{
+			This: *client.Client.ServiceContent.VirtualDiskManager,
+			Name: d.Id(),
+			Datacenter: &dcRef,
+			NewCapacityKb: int64(newSize.(int)) * 1024 * 1024,
+			EagerZero: &eagerZero,
+		}
+
+		log.Printf("[DEBUG] Extending virtual disk '%s' to %dGB", d.Id(), newSize.(int))
+
+		res, err := methods.ExtendVirtualDisk_Task(context.Background(), client.Client, &req)
+		if err != nil {
+			return err
+		}
+		_, err = waitForTask(client.Client, object.NewTask(client.Client, res.Returnval), fmt.Sprintf("extend virtual disk '%s'", d.Id()), longTaskTimeout)
+		if err != nil {
+			log.Printf("[ERROR] Unable to extend virtual disk '%s'", d.Id())
+			return err
+		}
+	}
+
+	return resourceVsphereVirtualDiskRead(d, meta)
+}
+
+func resourceVsphereVirtualDiskDelete(d *schema.ResourceData, meta interface{}) error {
+
+	if keep, ok := d.GetOk("keep"); !ok || !keep.(bool) {
+
+		_, datacenter, err := getFileDatastore(d, meta, d.Get("datastore").(string))
+		if err != nil {
+			return err
+		}
+
+		log.Printf("[DEBUG] Deleting virtual disk: %s", d.Id())
+
+		client := meta.(*govmomi.Client)
+
+		task, err := object.NewVirtualDiskManager(client.Client).DeleteVirtualDisk(context.Background(), d.Id(), datacenter)
+		if err != nil {
+			return err
+		}
+		_, err = waitForTask(client.Client, task, fmt.Sprintf("delete virtual disk '%s'", d.Id()), defaultTaskTimeout)
+		if err != nil && !isFileNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
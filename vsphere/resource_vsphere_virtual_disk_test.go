@@ -0,0 +1,171 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"golang.org/x/net/context"
+	
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/kr/pretty"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestAccVsphereVirtualDisk_normal(t *testing.T) {
+	
+	_, filename, _, _ := runtime.Caller(0)
+	ut := os.Getenv("UNIT_TEST")
+	if ut == "" || ut == filepath.Base(filename) {
+		
+		resource.Test( t, 
+			resource.TestCase {
+				PreCheck: func() { testAccPreCheck(t) },
+				Providers: testAccProviders,
+				CheckDestroy: testAccCheckVirtualDiskDestroy,
+				Steps: []resource.TestStep {
+					resource.TestStep {
+						Config: fmt.Sprintf( testAccVirtualDiskConfig, 
+							testEsxHost.IP,
+							testEsxHost.User,
+							testEsxHost.Password,
+							testEsxHost.License,
+							1,
+						),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckVirtualDiskExists("vsphere_virtual_disk.vd9"),
+							resource.TestCheckResourceAttr("vsphere_virtual_disk.vd9", "size", "1"),
+						),
+					},
+					resource.TestStep {
+						Config: fmt.Sprintf( testAccVirtualDiskConfig, 
+							testEsxHost.IP,
+							testEsxHost.User,
+							testEsxHost.Password,
+							testEsxHost.License,
+							2,
+						),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckVirtualDiskExists("vsphere_virtual_disk.vd9"),
+							resource.TestCheckResourceAttr("vsphere_virtual_disk.vd9", "size", "2"),
+						),
+					},
+				},
+			} )
+	}
+}
+
+func testAccCheckVirtualDiskExists(resource string) resource.TestCheckFunc {
+	
+	return func(s *terraform.State) error {
+		
+		rs, ok := s.RootModule().Resources[resource]
+		if !ok {
+			return fmt.Errorf("virtual disk '%s' not found in terraform state", resource)
+		}
+		
+		log.Printf("[DEBUG] Terraform virtual disk: %# v", pretty.Formatter(rs))
+		
+		attributes := rs.Primary.Attributes
+		
+		disk, err := findTestVirtualDisk("datacenter9", attributes["datastore"], attributes["vmdk_path"])
+		if err != nil {
+			return err
+		}
+		if disk == nil {
+			return fmt.Errorf("virtual disk '%s' was not found in the datastore", rs.Primary.ID)
+		}
+		if strconv.FormatInt(disk.CapacityKb / (1024 * 1024), 10) != attributes["size"] {
+			return fmt.Errorf("virtual disk '%s' has a capacity of %dKB but expected %sGB", rs.Primary.ID, disk.CapacityKb, attributes["size"])
+		}
+		return nil
+	}
+}
+
+func testAccCheckVirtualDiskDestroy(s *terraform.State) error {
+
+	const vd9 = "vsphere_virtual_disk.vd9"
+
+	_, ok := s.RootModule().Resources[vd9]
+	if ok {
+		return fmt.Errorf("virtual disk '%s' still exists in the terraform state", vd9)
+	}
+
+	disk, err := findTestVirtualDisk("datacenter9", testFileDatastore, "terraform/disk9.vmdk")
+	if err != nil {
+		log.Printf("[DEBUG] Virtual disk destroyed with its datacenter as expected. API response was: %s", err.Error())
+	} else if disk != nil {
+		return fmt.Errorf("virtual disk '%s' was not destroyed as expected", vd9)
+	}
+	
+	return nil
+}
+
+func findTestVirtualDisk(datacenterName, datastoreName, vmdkPath string) (*types.VmDiskFileInfo, error) {
+	
+	finder, err := getTestFinder(datacenterName)
+	if err != nil {
+		return nil, err
+	}
+	
+	datastore, err := finder.Datastore(context.Background(), datastoreName)
+	if err != nil {
+		return nil, err
+	}
+	
+	query := []types.BaseFileQuery{
+		&types.VmDiskFileQuery{
+			Details: &types.VmDiskFileQueryFlags{ CapacityKb: true },
+		},
+	}
+	
+	file, err := searchDatastoreFile(context.Background(), testAccProvider.Meta().(*govmomi.Client), datastore, vmdkPath, query)
+	if err != nil || file == nil {
+		return nil, err
+	}
+	
+	disk, ok := file.(*types.VmDiskFileInfo)
+	if !ok {
+		return nil, fmt.Errorf("'%s' is not a virtual disk", vmdkPath)
+	}
+	return disk, nil
+}
+
+const testAccVirtualDiskConfig = `
+
+resource "vsphere_datacenter" "dc9" {
+	name = "datacenter9"
+
+#	keep = true
+}
+
+resource "vsphere_host" "h9" {
+	host = "%s"
+	datacenter_id = "${vsphere_datacenter.dc9.id}"
+	
+	user = "%s"
+	password = "%s"
+	license = "%s"
+	
+	ssl_no_verify = true
+#	keep = true
+}
+
+resource "vsphere_virtual_disk" "vd9" {
+	depends_on = ["vsphere_host.h9"]
+
+	datacenter_id = "${vsphere_datacenter.dc9.id}"
+	datastore = "` + testFileDatastore + `"
+	vmdk_path = "terraform/disk9.vmdk"
+	size = %d
+	type = "thin"
+	
+#	keep = false
+}
+`
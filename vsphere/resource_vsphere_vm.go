@@ -2,6 +2,10 @@ package vsphere
 
 import (
 	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
 	
 	"golang.org/x/net/context"
 	"github.com/hashicorp/terraform/helper/schema"
@@ -25,9 +29,178 @@ func resourceVsphereVM() *schema.Resource {
 			
 			"template_name": &schema.Schema{
 				Type:     schema.TypeString,
-				Required: true,
+				Optional: true,
+				ForceNew: true,
+				ConflictsWith: []string{"ovf_source"},
+			},
+			"ovf_source": &schema.Schema{
+				Type:     schema.TypeString, // Path or URL of an OVF or OVA to deploy the VM from
+				Optional: true,
+				ForceNew: true,
+				ConflictsWith: []string{"template_name"},
+			},
+			"ovf_network_mappings": &schema.Schema{
+				Type:     schema.TypeMap, // OVF network names mapped to the names of the networks to connect to
+				Optional: true,
+				ForceNew: true,
+			},
+			"ovf_disk_provisioning": &schema.Schema{
+				Type:     schema.TypeString, // One of thin, thick, eagerZeroedThick, monolithicSparse, monolithicFlat, twoGbMaxExtentSparse, twoGbMaxExtentFlat, seSparse or flat
+				Optional: true,
+				ForceNew: true,
+			},
+			"ovf_properties": &schema.Schema{
+				Type:     schema.TypeMap, // Values of the OVF properties keyed by property id
+				Optional: true,
+				ForceNew: true,
+			},
+			"datastore": &schema.Schema{
+				Type:     schema.TypeString, // Datastore of the VM's files. Changing it migrates the VM with Storage vMotion.
+				Optional: true,
+				ConflictsWith: []string{"datastore_cluster"},
+			},
+			"datastore_cluster": &schema.Schema{
+				Type:     schema.TypeString, // Name or object_id of a datastore cluster whose storage DRS places the VM's files
+				Optional: true,
+				ConflictsWith: []string{"datastore"},
+			},
+			"resource_pool": &schema.Schema{
+				Type:     schema.TypeString, // Id or inventory path of a cluster, standalone host, resource pool or vApp. Changing it migrates the VM.
+				Optional: true,
+			},
+			"host": &schema.Schema{
+				Type:     schema.TypeString, // Name or object_id of the host to run the VM on. Changing it migrates the VM with vMotion.
+				Optional: true,
+			},
+			"migration_priority": &schema.Schema{
+				Type:     schema.TypeString, // One of defaultPriority, highPriority or lowPriority
+				Optional: true,
+				Default:  "defaultPriority",
+			},
+			"guest_id": &schema.Schema{
+				Type:     schema.TypeString, // Guest OS identifier such as otherGuest64, rhel7_64Guest or windows8Server64Guest
+				Optional: true,
+				Computed: true,
+			},
+			"firmware": &schema.Schema{
+				Type:     schema.TypeString, // One of bios or efi
+				Optional: true,
+				Computed: true,
 				ForceNew: true,
 			},
+			"hardware_version": &schema.Schema{
+				Type:     schema.TypeString, // Virtual hardware version such as vmx-10
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+			},
+			"scsi_type": &schema.Schema{
+				Type:     schema.TypeString, // One of lsilogic, buslogic, pvscsi or lsilogic-sas
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+			},
+			"disk": &schema.Schema{
+				Type:     schema.TypeList, // Disks of a VM built from scratch
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"size_gb": &schema.Schema{
+							Type:     schema.TypeInt,
+							Required: true,
+							ForceNew: true,
+						},
+						"thin_provisioned": &schema.Schema{
+							Type:     schema.TypeBool,
+							Optional: true,
+							Default:  true,
+							ForceNew: true,
+						},
+						"datastore": &schema.Schema{
+							Type:     schema.TypeString, // Datastore of the disk when it differs from the VM's. Changing it migrates the disk.
+							Optional: true,
+						},
+					},
+				},
+			},
+			"network_interface": &schema.Schema{
+				Type:     schema.TypeList, // Network adapters of a VM built from scratch
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"label": &schema.Schema{
+							Type:     schema.TypeString, // Name of the network or distributed port group
+							Required: true,
+							ForceNew: true,
+						},
+						"adapter_type": &schema.Schema{
+							Type:     schema.TypeString, // One of e1000, e1000e or vmxnet3
+							Optional: true,
+							Default:  "vmxnet3",
+							ForceNew: true,
+						},
+						"mac_address": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
+			"attached_disk": &schema.Schema{
+				Type:     schema.TypeList, // Existing virtual disks attached to the VM but not deleted with it
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"datastore": &schema.Schema{
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"vmdk_path": &schema.Schema{
+							Type:     schema.TypeString, // Path of the vmdk file in the datastore
+							Required: true,
+						},
+					},
+				},
+			},
+			"cdrom": &schema.Schema{
+				Type:     schema.TypeList, // CD-ROM drives backed by the client device when neither path nor host_device are given
+				Optional: true,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"datastore": &schema.Schema{
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+						"path": &schema.Schema{
+							Type:     schema.TypeString, // Path of an ISO image in the datastore
+							Optional: true,
+						},
+						"upload_iso": &schema.Schema{
+							Type:     schema.TypeString, // Local ISO image uploaded to path in the datastore before it is attached
+							Optional: true,
+						},
+						"host_device": &schema.Schema{
+							Type:     schema.TypeString, // Name of a CD-ROM device of the host such as /vmfs/devices/cdrom/mpx.vmhba1:C0:T0:L0
+							Optional: true,
+						},
+						"start_connected": &schema.Schema{
+							Type:     schema.TypeBool,
+							Optional: true,
+							Default:  true,
+						},
+						"connected": &schema.Schema{
+							Type:     schema.TypeBool,
+							Optional: true,
+							Default:  true,
+						},
+					},
+				},
+			},
 			"vm_name": &schema.Schema{
 				Type:     schema.TypeString,
 				Required: true,
@@ -47,9 +220,72 @@ func resourceVsphereVM() *schema.Resource {
 				Type:     schema.TypeInt,
 				Required: true,
 			},
-			"customization_specification": &schema.Schema{
+			"cores_per_socket": &schema.Schema{
+				Type:     schema.TypeInt,
+				Optional: true,
+				Computed: true,
+			},
+			"cpu_hot_add_enabled": &schema.Schema{
+				Type:     schema.TypeBool, // Hot plug flags default to enabled as VMs were always created with them enabled
+				Optional: true,
+				Default:  true,
+			},
+			"cpu_hot_remove_enabled": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  true,
+			},
+			"memory_hot_add_enabled": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  true,
+			},
+			"nested_hv_enabled": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"cpu_reservation": &schema.Schema{
+				Type:     schema.TypeInt, // MHz
+				Optional: true,
+				Computed: true,
+			},
+			"memory_reservation": &schema.Schema{
+				Type:     schema.TypeInt, // MB
+				Optional: true,
+				Computed: true,
+			},
+			"annotation": &schema.Schema{
 				Type:     schema.TypeString,
-				Required: true,
+				Optional: true,
+				Computed: true,
+			},
+			"extra_config": &schema.Schema{
+				Type:     schema.TypeMap, // Advanced settings such as guestinfo.* keys read by cloud-init
+				Optional: true,
+			},
+			"custom_attributes": &schema.Schema{
+				Type:     schema.TypeMap, // Values of custom attributes keyed by attribute name
+				Optional: true,
+			},
+			"allow_reboot_for_reconfigure": &schema.Schema{
+				Type:     schema.TypeBool, // Shut down the VM to apply changes that cannot be made while it is running
+				Optional: true,
+				Default:  false,
+			},
+			"customization_specification": &schema.Schema{
+				Type:     schema.TypeString, // Applied to VMs cloned from a template
+				Optional: true,
+			},
+			"wait_for_customization": &schema.Schema{
+				Type:     schema.TypeBool, // Wait for the guest to be customized before the VM is created
+				Optional: true,
+				Default:  true,
+			},
+			"customization_timeout": &schema.Schema{
+				Type:     schema.TypeInt, // Minutes to wait for the guest to be customized
+				Optional: true,
+				Default:  10,
 			},
 		},
 	}
@@ -72,15 +308,21 @@ func resourceVsphereVMCreate(d *schema.ResourceData, meta interface{}) error {
 
 	finder.SetDatacenter(datacenter)
 
-	resourcePool, err := finder.DefaultResourcePool(context.Background())
+	host, err := getVMHost(d, meta, finder)
 
 	if err != nil {
 		return err
 	}
 
-	rpRef := resourcePool.Reference()
+	var resourcePool *object.ResourcePool
 
-	vm, err := finder.VirtualMachine(context.Background(), d.Get("template_name").(string))
+	if v, ok := d.GetOk("resource_pool"); ok {
+		resourcePool, err = getResourcePoolParent(v.(string), finder, client)
+	} else if host != nil {
+		resourcePool, err = host.ResourcePool(context.Background())
+	} else {
+		resourcePool, err = finder.DefaultResourcePool(context.Background())
+	}
 
 	if err != nil {
 		return err
@@ -91,60 +333,479 @@ func resourceVsphereVMCreate(d *schema.ResourceData, meta interface{}) error {
 	if err != nil {
 		return err
 	}
+
+	err = uploadVMCdromIsos(d, meta, finder, datacenter, nil)
+
+	if err != nil {
+		return err
+	}
+
+	var vm *object.VirtualMachine
+
+	if _, ok := d.GetOk("ovf_source"); ok {
+		vm, err = createVMFromOvf(d, meta, finder, resourcePool, folders.VmFolder)
+	} else if _, ok := d.GetOk("template_name"); ok {
+		vm, err = createVMFromTemplate(d, meta, finder, resourcePool, host, folders.VmFolder)
+	} else {
+		vm, err = createVMFromScratch(d, meta, finder, resourcePool, host, folders.VmFolder)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	d.SetId(d.Get("vm_name").(string))
+
+	// Disks are moved to their own datastores once the VM exists
+	err = relocateVM(d, meta, finder, vm)
+
+	if err != nil {
+		return err
+	}
+
+	if attachedDisks, ok := d.GetOk("attached_disk"); ok {
+
+		err = configureVMAttachedDisks(context.Background(), client, vm, nil, attachedDisks.([]interface{}))
+
+		if err != nil {
+			return err
+		}
+	}
+
+	// IDE devices cannot be added to a running VM so VMs are powered
+	// on only once their CD-ROM drives have been configured
+	if cdroms, ok := d.GetOk("cdrom"); ok {
+
+		err = configureVMCdroms(context.Background(), vm, cdroms.([]interface{}))
+
+		if err != nil {
+			return err
+		}
+	}
+
+	err = updateCustomAttributes(d, meta, vm.Reference())
+
+	if err != nil {
+		return err
+	}
+
+	task, err := vm.PowerOn(context.Background())
+
+	if err != nil {
+		return err
+	}
+
+	_, err = waitForTask(client.Client, task, fmt.Sprintf("power on VM '%s'", d.Id()), defaultTaskTimeout)
+
+	if err != nil {
+		return err
+	}
+
+	if _, ok := d.GetOk("template_name"); ok {
+
+		if _, ok := d.GetOk("customization_specification"); ok && d.Get("wait_for_customization").(bool) {
+
+			timeout := time.Duration(d.Get("customization_timeout").(int)) * time.Minute
+			err = waitForVMCustomization(client, vm, timeout)
+
+			if err != nil {
+				log.Printf("[ERROR] Customization of VM '%s' did not succeed", d.Id())
+				return err
+			}
+		}
+
+		// Customized clones report their address once the guest is up
+		ip, err := waitForVMIP(vm)
+
+		if err != nil {
+			return err
+		}
+
+		d.Set("ip_address", ip)
+	}
+
+	return resourceVsphereVMRead(d, meta)
+}
+
+func createVMFromTemplate(d *schema.ResourceData, meta interface{}, finder *find.Finder, resourcePool *object.ResourcePool, host *object.HostSystem, folder *object.Folder) (*object.VirtualMachine, error) {
+	client := meta.(*govmomi.Client)
+
+	rpRef := resourcePool.Reference()
+
+	vm, err := finder.VirtualMachine(context.Background(), d.Get("template_name").(string))
+
+	if err != nil {
+		return nil, err
+	}
 	
-	cpuHotAddEnabled := true
-	cpuHotRemoveEnabled := true
-	memoryHotAddEnabled := true
+	configspec := getVMConfigSpec(d)
 
 	clonespec := types.VirtualMachineCloneSpec{
-		Config: &types.VirtualMachineConfigSpec{
-			NumCPUs:             d.Get("cpus").(int),
-			MemoryMB:            int64(d.Get("memory_mb").(int)),
-			CpuHotAddEnabled:    &cpuHotAddEnabled,
-			CpuHotRemoveEnabled: &cpuHotRemoveEnabled,
-			MemoryHotAddEnabled: &memoryHotAddEnabled,
-		},
+		Config: &configspec,
 		Location: types.VirtualMachineRelocateSpec{
 			Pool: &rpRef,
 		},
-		PowerOn: true,
+		PowerOn: false,
+	}
+
+	if host != nil {
+		hostRef := host.Reference()
+		clonespec.Location.Host = &hostRef
 	}
 
-	ipAddress := d.Get("ip_address").(string)
+	if v, ok := d.GetOk("datastore"); ok {
+
+		datastore, err := findDatastore(context.Background(), finder, v.(string))
+
+		if err != nil {
+			return nil, err
+		}
+
+		dsRef := datastore.Reference()
+		clonespec.Location.Datastore = &dsRef
+	}
+
+	if _, ok := d.GetOk("customization_specification"); ok {
+
+		ipAddress := d.Get("ip_address").(string)
+
+		specManager := object.NewCustomizationSpecManager(client.Client) //client.CustomizationSpecManager()
+		specItem, err := specManager.GetCustomizationSpec(context.Background(), d.Get("customization_specification").(string))
+		if err != nil {
+			return nil, err
+		}
+
+		if ipAddress != "" {
+			ip := types.CustomizationFixedIp{
+				IpAddress: ipAddress,
+			}
+			specItem.Spec.NicSettingMap[0].Adapter.Ip = &ip
+		} else {
+			ip := types.CustomizationDhcpIpGenerator{}
+			specItem.Spec.NicSettingMap[0].Adapter.Ip = &ip
+		}
+
+		clonespec.Customization = &specItem.Spec
+	}
+
+	pod, err := getVMDatastoreCluster(d, meta, finder)
 
-	specManager := object.NewCustomizationSpecManager(client.Client) //client.CustomizationSpecManager()
-	specItem, err := specManager.GetCustomizationSpec(context.Background(), d.Get("customization_specification").(string))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if ipAddress != "" {
-		ip := types.CustomizationFixedIp{
-			IpAddress: ipAddress,
+	if pod != nil {
+
+		templateRef := vm.Reference()
+		folderRef := folder.Reference()
+		podRef := pod.Reference()
+
+		ref, err := applyStorageDrsPlacement(context.Background(), client, types.StoragePlacementSpec{
+				Type: "clone",
+				Vm: &templateRef,
+				CloneSpec: &clonespec,
+				CloneName: d.Get("vm_name").(string),
+				Folder: &folderRef,
+				PodSelectionSpec: types.StorageDrsPodSelectionSpec{ StoragePod: &podRef },
+			}, fmt.Sprintf("clone template '%s' to VM '%s'", d.Get("template_name").(string), d.Get("vm_name").(string)))
+
+		if err != nil {
+			return nil, err
 		}
-		specItem.Spec.NicSettingMap[0].Adapter.Ip = &ip
-	} else {
-		ip := types.CustomizationDhcpIpGenerator{}
-		specItem.Spec.NicSettingMap[0].Adapter.Ip = &ip
+
+		return object.NewVirtualMachine(client.Client, *ref), nil
 	}
 
-	clonespec.Customization = &specItem.Spec
+	task, err := vm.Clone(context.Background(), folder, d.Get("vm_name").(string), clonespec)
+
+	if err != nil {
+		return nil, err
+	}
 
-	task, err := vm.Clone(context.Background(), folders.VmFolder, d.Get("vm_name").(string), clonespec)
+	info, err := waitForTask(client.Client, task, fmt.Sprintf("clone template '%s' to VM '%s'", d.Get("template_name").(string), d.Get("vm_name").(string)), longTaskTimeout)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	_, err = task.WaitForResult(context.Background(), nil)
+	return object.NewVirtualMachine(client.Client, info.Result.(types.ManagedObjectReference)), nil
+}
+
+func createVMFromScratch(d *schema.ResourceData, meta interface{}, finder *find.Finder, resourcePool *object.ResourcePool, host *object.HostSystem, folder *object.Folder) (*object.VirtualMachine, error) {
+	client := meta.(*govmomi.Client)
+
+	pod, err := getVMDatastoreCluster(d, meta, finder)
 
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	devices, err := newVMDiskDevices(d.Get("scsi_type").(string), d.Get("disk").([]interface{}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	networkDevices, err := newVMNetworkDevices(finder, d.Get("network_interface").([]interface{}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	devices = append(devices, networkDevices...)
+
+	configspec := getVMConfigSpec(d)
+
+	configspec.Name = d.Get("vm_name").(string)
+	configspec.Firmware = d.Get("firmware").(string)
+	configspec.Version = d.Get("hardware_version").(string)
+	configspec.DeviceChange = addDeviceConfigSpecs(devices)
+
+	if configspec.GuestId == "" {
+		configspec.GuestId = "otherGuest64"
+	}
+
+	// Storage DRS picks the datastores of the VM's files and disks
+	if pod != nil {
+
+		configspec.Files = &types.VirtualMachineFileInfo{}
+
+		poolRef := resourcePool.Reference()
+		folderRef := folder.Reference()
+		podRef := pod.Reference()
+
+		placement := types.StoragePlacementSpec{
+			Type: "create",
+			ConfigSpec: &configspec,
+			ResourcePool: &poolRef,
+			Folder: &folderRef,
+			PodSelectionSpec: types.StorageDrsPodSelectionSpec{
+				InitialVmConfig: []types.VmPodConfigForPlacement{
+					types.VmPodConfigForPlacement{
+						StoragePod: podRef,
+						Disk: getPodDiskLocators(configspec.DeviceChange),
+					},
+				},
+			},
+		}
+		if host != nil {
+			hostRef := host.Reference()
+			placement.Host = &hostRef
+		}
+
+		ref, err := applyStorageDrsPlacement(context.Background(), client, placement, fmt.Sprintf("create VM '%s'", d.Get("vm_name").(string)))
+
+		if err != nil {
+			return nil, err
+		}
+
+		return object.NewVirtualMachine(client.Client, *ref), nil
+	}
+
+	datastore, err := getVMDatastore(d, finder)
+
+	if err != nil {
+		return nil, err
+	}
+
+	configspec.Files = &types.VirtualMachineFileInfo{
+		VmPathName: fmt.Sprintf("[%s]", datastore.Name()),
+	}
+
+	task, err := folder.CreateVM(context.Background(), configspec, resourcePool, host)
+
+	if err != nil {
+		return nil, err
 	}
 
+	info, err := waitForTask(client.Client, task, fmt.Sprintf("create VM '%s'", d.Get("vm_name").(string)), longTaskTimeout)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return object.NewVirtualMachine(client.Client, info.Result.(types.ManagedObjectReference)), nil
+}
+
+func createVMFromOvf(d *schema.ResourceData, meta interface{}, finder *find.Finder, resourcePool *object.ResourcePool, folder *object.Folder) (*object.VirtualMachine, error) {
+	client := meta.(*govmomi.Client)
+
+	datastore, err := getVMDatastore(d, finder)
+
+	if err != nil {
+		return nil, err
+	}
+
+	params := ovfImportParams{
+		Source:           d.Get("ovf_source").(string),
+		Name:             d.Get("vm_name").(string),
+		NetworkMappings:  make(map[string]string),
+		DiskProvisioning: d.Get("ovf_disk_provisioning").(string),
+		Properties:       make(map[string]string),
+	}
+
+	for k, v := range d.Get("ovf_network_mappings").(map[string]interface{}) {
+		params.NetworkMappings[k] = v.(string)
+	}
+	for k, v := range d.Get("ovf_properties").(map[string]interface{}) {
+		params.Properties[k] = v.(string)
+	}
+
+	// Uploading the disks can take hours so the import stops when terraform is interrupted
+	ctx, cancel := context.WithTimeout(stopContext, longTaskTimeout)
+	defer cancel()
+
+	vm, err := importOvf(ctx, client, finder, params, resourcePool, datastore, folder)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// The imported VM is tracked so that a failed reconfiguration
+	// taints it instead of leaving a VM with a conflicting name
 	d.SetId(d.Get("vm_name").(string))
 
-	return resourceVsphereVMRead(d, meta)
+	task, err := vm.Reconfigure(context.Background(), getVMConfigSpec(d))
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = waitForTask(client.Client, task, fmt.Sprintf("configure VM '%s'", d.Get("vm_name").(string)), defaultTaskTimeout)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return vm, nil
+}
+
+func getVMDatastore(d *schema.ResourceData, finder *find.Finder) (*object.Datastore, error) {
+
+	if v, ok := d.GetOk("datastore"); ok {
+		return findDatastore(context.Background(), finder, v.(string))
+	}
+
+	return finder.DefaultDatastore(context.Background())
+}
+
+// Uploads the local ISO images of the cdrom list to their datastores. On
+// update only images whose source or destination changed are uploaded.
+func uploadVMCdromIsos(d *schema.ResourceData, meta interface{}, finder *find.Finder, datacenter *object.Datacenter, old []interface{}) error {
+	client := meta.(*govmomi.Client)
+
+	for i, c := range d.Get("cdrom").([]interface{}) {
+
+		cdrom := c.(map[string]interface{})
+
+		if cdrom["upload_iso"].(string) == "" {
+			continue
+		}
+
+		if i < len(old) {
+			o := old[i].(map[string]interface{})
+			if o["upload_iso"] == cdrom["upload_iso"] && o["datastore"] == cdrom["datastore"] && o["path"] == cdrom["path"] {
+				continue
+			}
+		}
+
+		if cdrom["path"].(string) == "" {
+			return fmt.Errorf("the path in the datastore to upload '%s' to must be given", cdrom["upload_iso"].(string))
+		}
+
+		datastore, err := findDatastore(context.Background(), finder, cdrom["datastore"].(string))
+
+		if err != nil {
+			return err
+		}
+
+		err = uploadDatastoreFile(context.Background(), client, datacenter, datastore, cdrom["upload_iso"].(string), cdrom["path"].(string))
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns the config spec of the VM settings that are applied when the VM
+// is created and reconfigured in place on update. Zero reservations and an
+// empty annotation are omitted by the bindings so they are left unchanged.
+func getVMConfigSpec(d *schema.ResourceData) types.VirtualMachineConfigSpec {
+
+	cpuHotAddEnabled := d.Get("cpu_hot_add_enabled").(bool)
+	cpuHotRemoveEnabled := d.Get("cpu_hot_remove_enabled").(bool)
+	memoryHotAddEnabled := d.Get("memory_hot_add_enabled").(bool)
+	nestedHVEnabled := d.Get("nested_hv_enabled").(bool)
+
+	configspec := types.VirtualMachineConfigSpec{
+		NumCPUs:             d.Get("cpus").(int),
+		NumCoresPerSocket:   d.Get("cores_per_socket").(int),
+		MemoryMB:            int64(d.Get("memory_mb").(int)),
+		CpuHotAddEnabled:    &cpuHotAddEnabled,
+		CpuHotRemoveEnabled: &cpuHotRemoveEnabled,
+		MemoryHotAddEnabled: &memoryHotAddEnabled,
+		NestedHVEnabled:     &nestedHVEnabled,
+		GuestId:             d.Get("guest_id").(string),
+		Annotation:          d.Get("annotation").(string),
+	}
+
+	if v, ok := d.GetOk("cpu_reservation"); ok {
+		configspec.CpuAllocation = &types.ResourceAllocationInfo{
+			Reservation: int64(v.(int)),
+		}
+	}
+	if v, ok := d.GetOk("memory_reservation"); ok {
+		configspec.MemoryAllocation = &types.ResourceAllocationInfo{
+			Reservation: int64(v.(int)),
+		}
+	}
+
+	old, new := d.GetChange("extra_config")
+	configspec.ExtraConfig = getExtraConfigChanges(old.(map[string]interface{}), new.(map[string]interface{}))
+
+	return configspec
+}
+
+// Returns the extra config options to set. Keys removed from the
+// configuration are set to an empty value which removes them from the VM.
+func getExtraConfigChanges(old, new map[string]interface{}) []types.BaseOptionValue {
+
+	var keys []string
+	for k := range old {
+		if _, ok := new[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	for k := range new {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var options []types.BaseOptionValue
+	for _, k := range keys {
+		value := ""
+		if v, ok := new[k]; ok {
+			value = v.(string)
+		}
+		options = append(options, &types.OptionValue{ Key: k, Value: value })
+	}
+	return options
+}
+
+// Returns the values of the VM's extra config options for the configured keys.
+func getVMExtraConfig(extraConfig []types.BaseOptionValue, configured map[string]interface{}) map[string]interface{} {
+
+	values := make(map[string]interface{})
+
+	for _, option := range extraConfig {
+		o := option.GetOptionValue()
+		if _, ok := configured[o.Key]; ok {
+			values[o.Key] = fmt.Sprintf("%v", o.Value)
+		}
+	}
+	return values
 }
 
 func resourceVsphereVMRead(d *schema.ResourceData, meta interface{}) error {
@@ -171,15 +832,10 @@ func resourceVsphereVMRead(d *schema.ResourceData, meta interface{}) error {
 			d.SetId("")
 			return nil
 		}
-	}
-
-	ip, err := vm.WaitForIP(context.Background())
-	if err != nil {
 		return err
 	}
-	d.Set("ip_address", ip)
 
-	props := []string{"summary"}
+	props := []string{"summary", "config", "guest", "resourcePool"}
 
 	var mvm mo.VirtualMachine
 
@@ -192,7 +848,58 @@ func resourceVsphereVMRead(d *schema.ResourceData, meta interface{}) error {
 	d.Set("memory_mb", mvm.Summary.Config.MemorySizeMB)
 	d.Set("cpus", mvm.Summary.Config.NumCpu)
 
-	return nil
+	// VMs without guest tools running do not report an address
+	if mvm.Guest != nil && mvm.Guest.IpAddress != "" {
+		d.Set("ip_address", mvm.Guest.IpAddress)
+	}
+
+	if mvm.Config != nil {
+
+		d.Set("guest_id", mvm.Config.GuestId)
+		d.Set("firmware", mvm.Config.Firmware)
+		d.Set("hardware_version", mvm.Config.Version)
+		d.Set("cores_per_socket", mvm.Config.Hardware.NumCoresPerSocket)
+		d.Set("cpu_hot_add_enabled", mvm.Config.CpuHotAddEnabled != nil && *mvm.Config.CpuHotAddEnabled)
+		d.Set("cpu_hot_remove_enabled", mvm.Config.CpuHotRemoveEnabled != nil && *mvm.Config.CpuHotRemoveEnabled)
+		d.Set("memory_hot_add_enabled", mvm.Config.MemoryHotAddEnabled != nil && *mvm.Config.MemoryHotAddEnabled)
+		d.Set("nested_hv_enabled", mvm.Config.NestedHVEnabled != nil && *mvm.Config.NestedHVEnabled)
+		d.Set("annotation", mvm.Config.Annotation)
+		d.Set("extra_config", getVMExtraConfig(mvm.Config.ExtraConfig, d.Get("extra_config").(map[string]interface{})))
+
+		if mvm.Config.CpuAllocation != nil {
+			d.Set("cpu_reservation", int(mvm.Config.CpuAllocation.Reservation))
+		}
+		if mvm.Config.MemoryAllocation != nil {
+			d.Set("memory_reservation", int(mvm.Config.MemoryAllocation.Reservation))
+		}
+
+		devices := object.VirtualDeviceList(mvm.Config.Hardware.Device)
+
+		networkInterfaces, err := getVMNetworkInterfaces(context.Background(), client, devices)
+
+		if err != nil {
+			return err
+		}
+
+		d.Set("scsi_type", getSCSIControllerType(devices))
+
+		attachedDisks := getVMAttachedDisks(devices, d.Get("attached_disk").([]interface{}))
+
+		d.Set("disk", getVMDisks(devices, getAttachedDiskPaths(attachedDisks), d.Get("disk").([]interface{})))
+		d.Set("attached_disk", attachedDisks)
+		d.Set("network_interface", networkInterfaces)
+
+		poweredOn := mvm.Summary.Runtime.PowerState == types.VirtualMachinePowerStatePoweredOn
+		d.Set("cdrom", getVMCdroms(devices, poweredOn, d.Get("cdrom").([]interface{})))
+	}
+
+	err = readVMPlacement(d, meta, finder, vm, &mvm)
+
+	if err != nil {
+		return err
+	}
+
+	return readCustomAttributes(d, meta, vm.Reference())
 }
 
 func resourceVsphereVMUpdate(d *schema.ResourceData, meta interface{}) error {
@@ -214,9 +921,123 @@ func resourceVsphereVMUpdate(d *schema.ResourceData, meta interface{}) error {
 		return err
 	}
 
-	configspec := types.VirtualMachineConfigSpec{
-		NumCPUs:  d.Get("cpus").(int),
-		MemoryMB: int64(d.Get("memory_mb").(int)),
+	// The helper/schema version this provider is built with has no hook to
+	// customize the plan so changes needing a power cycle are found here,
+	// before the VM is changed in any way
+	old, new := getVMPowerCycleSettings(d)
+
+	changes := getPowerCycleChanges(old, new)
+
+	poweredOn, err := isVMPoweredOn(context.Background(), vm)
+
+	if err != nil {
+		return err
+	}
+
+	powerCycle := poweredOn && len(changes) > 0
+
+	if powerCycle && !d.Get("allow_reboot_for_reconfigure").(bool) {
+		return fmt.Errorf("changing %s of VM '%s' requires it to be powered off. set allow_reboot_for_reconfigure to have it shut down for the change", strings.Join(changes, ", "), d.Get("vm_name").(string))
+	}
+
+	// Placement changes migrate the VM instead of replacing it
+	if d.HasChange("host") || d.HasChange("resource_pool") || d.HasChange("datastore") || d.HasChange("datastore_cluster") || d.HasChange("disk") {
+
+		err = relocateVM(d, meta, finder, vm)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	configspec := getVMConfigSpec(d)
+
+	// Settings that cannot be changed on a running VM are only sent when they change
+	if !d.HasChange("cpu_hot_add_enabled") {
+		configspec.CpuHotAddEnabled = nil
+	}
+	if !d.HasChange("cpu_hot_remove_enabled") {
+		configspec.CpuHotRemoveEnabled = nil
+	}
+	if !d.HasChange("memory_hot_add_enabled") {
+		configspec.MemoryHotAddEnabled = nil
+	}
+	if !d.HasChange("nested_hv_enabled") {
+		configspec.NestedHVEnabled = nil
+	}
+	if !d.HasChange("cores_per_socket") {
+		configspec.NumCoresPerSocket = 0
+	}
+	if !d.HasChange("guest_id") {
+		configspec.GuestId = ""
+	}
+
+	if powerCycle {
+
+		log.Printf("[DEBUG] Shutting down VM '%s' to change %s", d.Get("vm_name").(string), strings.Join(changes, ", "))
+
+		err = shutdownVM(client, vm)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	err = reconfigureVM(d, meta, finder, datacenter, vm, configspec)
+
+	// A VM shut down for the change is powered on again even if the
+	// change could not be applied so that it keeps its power state
+	if powerCycle {
+
+		log.Printf("[DEBUG] Powering on VM '%s' after reconfiguring it", d.Get("vm_name").(string))
+
+		powerOnErr := powerOnVM(client, vm)
+
+		if err == nil {
+			err = powerOnErr
+		} else if powerOnErr != nil {
+			log.Printf("[ERROR] Unable to power on VM '%s' after its reconfiguration failed: %s", d.Get("vm_name").(string), powerOnErr.Error())
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return resourceVsphereVMRead(d, meta)
+}
+
+// Applies the attached disk, CD-ROM, configuration and custom attribute
+// changes of a VM.
+func reconfigureVM(d *schema.ResourceData, meta interface{}, finder *find.Finder, datacenter *object.Datacenter, vm *object.VirtualMachine, configspec types.VirtualMachineConfigSpec) error {
+	client := meta.(*govmomi.Client)
+
+	if d.HasChange("attached_disk") {
+
+		old, _ := d.GetChange("attached_disk")
+
+		err := configureVMAttachedDisks(context.Background(), client, vm, old.([]interface{}), d.Get("attached_disk").([]interface{}))
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if d.HasChange("cdrom") {
+
+		old, _ := d.GetChange("cdrom")
+
+		err := uploadVMCdromIsos(d, meta, finder, datacenter, old.([]interface{}))
+
+		if err != nil {
+			return err
+		}
+
+		err = configureVMCdroms(context.Background(), vm, d.Get("cdrom").([]interface{}))
+
+		if err != nil {
+			return err
+		}
 	}
 
 	task, err := vm.Reconfigure(context.Background(), configspec)
@@ -225,13 +1046,13 @@ func resourceVsphereVMUpdate(d *schema.ResourceData, meta interface{}) error {
 		return err
 	}
 
-	_, err = task.WaitForResult(context.Background(), nil)
+	_, err = waitForTask(client.Client, task, fmt.Sprintf("reconfigure VM '%s'", d.Id()), defaultTaskTimeout)
 
 	if err != nil {
 		return err
 	}
 
-	return resourceVsphereVMRead(d, meta)
+	return updateCustomAttributes(d, meta, vm.Reference())
 }
 
 func resourceVsphereVMDelete(d *schema.ResourceData, meta interface{}) error {
@@ -263,19 +1084,29 @@ func resourceVsphereVMDelete(d *schema.ResourceData, meta interface{}) error {
 		return err
 	}
 
-	_, err = task.WaitForResult(context.Background(), nil)
+	_, err = waitForTask(client.Client, task, fmt.Sprintf("power off VM '%s'", d.Id()), defaultTaskTimeout)
 
 	if err != nil {
 		return err
 	}
 
+	// Attached disks outlive the VM so they are detached before it is destroyed
+	if attachedDisks, ok := d.GetOk("attached_disk"); ok {
+
+		err = configureVMAttachedDisks(context.Background(), client, vm, attachedDisks.([]interface{}), nil)
+
+		if err != nil {
+			return err
+		}
+	}
+
 	task, err = vm.Destroy(context.Background())
 
 	if err != nil {
 		return err
 	}
 
-	_, err = task.WaitForResult(context.Background(), nil)
+	_, err = waitForTask(client.Client, task, fmt.Sprintf("destroy VM '%s'", d.Id()), defaultTaskTimeout)
 
 	if err != nil {
 		return err
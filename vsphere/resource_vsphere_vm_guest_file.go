@@ -0,0 +1,201 @@
+package vsphere
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strconv"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func resourceVsphereVMGuestFile() *schema.Resource {
+
+	return &schema.Resource{
+
+		Create: resourceVsphereVMGuestFileCreate,
+		Read:   resourceVsphereVMGuestFileRead,
+		Update: resourceVsphereVMGuestFileUpdate,
+		Delete: resourceVsphereVMGuestFileDelete,
+
+		// The guest credentials are kept in the state like any other argument
+		// as this version of terraform cannot mark arguments as sensitive.
+		// They are never logged.
+		Schema: map[string]*schema.Schema{
+
+			"datacenter_id": &schema.Schema{
+				Type: schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"vm_name": &schema.Schema{
+				Type: schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"guest_user": &schema.Schema{
+				Type: schema.TypeString,
+				Required: true,
+			},
+			"guest_password": &schema.Schema{
+				Type: schema.TypeString,
+				Required: true,
+			},
+			"path": &schema.Schema{
+				Type: schema.TypeString, // Absolute path of the file in the guest
+				Required: true,
+				ForceNew: true,
+			},
+			"content": &schema.Schema{
+				Type: schema.TypeString,
+				Required: true,
+			},
+			"permissions": &schema.Schema{
+				Type: schema.TypeString, // Octal POSIX permissions i.e. "0644"
+				Optional: true,
+				Computed: true,
+			},
+			"keep": &schema.Schema{
+				Type: schema.TypeBool,
+				Optional: true,
+			},
+		},
+	}
+}
+
+func resourceVsphereVMGuestFileCreate(d *schema.ResourceData, meta interface{}) error {
+
+	session, err := getGuestFileSession(d, meta)
+	if err != nil {
+		return err
+	}
+
+	err = uploadGuestFile(d, session)
+	if err != nil {
+		return err
+	}
+
+	d.SetId(fmt.Sprintf("%s:%s", d.Get("vm_name").(string), d.Get("path").(string)))
+	return resourceVsphereVMGuestFileRead(d, meta)
+}
+
+func resourceVsphereVMGuestFileRead(d *schema.ResourceData, meta interface{}) error {
+
+	session, err := getGuestFileSession(d, meta)
+	if err != nil {
+		if _, ok := err.(*find.NotFoundError); ok {
+			log.Printf("[DEBUG] VM of guest file '%s' no longer exists", d.Id())
+			d.SetId("")
+			return nil
+		}
+		return err
+	}
+
+	path := d.Get("path").(string)
+
+	info, err := session.stat(context.Background(), path)
+	if err != nil {
+		return err
+	}
+	if info == nil {
+		log.Printf("[DEBUG] Guest file '%s' no longer exists", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	content, err := session.download(context.Background(), path)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(content, []byte(d.Get("content").(string))) {
+		log.Printf("[DEBUG] Content of guest file '%s' has changed", d.Id())
+		d.Set("content", string(content))
+	}
+
+	if attrs, ok := info.Attributes.(*types.GuestPosixFileAttributes); ok {
+		d.Set("permissions", fmt.Sprintf("%04o", attrs.Permissions & 07777))
+	}
+	return nil
+}
+
+func resourceVsphereVMGuestFileUpdate(d *schema.ResourceData, meta interface{}) error {
+
+	if d.HasChange("content") || d.HasChange("permissions") {
+
+		session, err := getGuestFileSession(d, meta)
+		if err != nil {
+			return err
+		}
+
+		err = uploadGuestFile(d, session)
+		if err != nil {
+			return err
+		}
+	}
+
+	return resourceVsphereVMGuestFileRead(d, meta)
+}
+
+func resourceVsphereVMGuestFileDelete(d *schema.ResourceData, meta interface{}) error {
+
+	if keep, ok := d.GetOk("keep"); !ok || !keep.(bool) {
+
+		session, err := getGuestFileSession(d, meta)
+		if err != nil {
+			if _, ok := err.(*find.NotFoundError); ok {
+				return nil
+			}
+			return err
+		}
+
+		err = session.remove(context.Background(), d.Get("path").(string))
+		if err != nil {
+			log.Printf("[ERROR] Unable to delete guest file '%s'", d.Id())
+			return err
+		}
+	}
+	return nil
+}
+
+func getGuestFileSession(d *schema.ResourceData, meta interface{}) (*guestSession, error) {
+
+	finder, datacenter, err := getFinder(d, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	vm, err := findGuestVM(context.Background(), meta.(*govmomi.Client), finder, datacenter, d.Get("vm_name").(string))
+	if err != nil {
+		return nil, err
+	}
+
+	return newGuestSession(context.Background(), meta.(*govmomi.Client), vm,
+		d.Get("guest_user").(string), d.Get("guest_password").(string))
+}
+
+func uploadGuestFile(d *schema.ResourceData, session *guestSession) error {
+
+	var permissions int64
+	if v, ok := d.GetOk("permissions"); ok {
+
+		p, err := strconv.ParseInt(v.(string), 8, 64)
+		if err != nil {
+			return fmt.Errorf("invalid permissions '%s'. they should be given in octal i.e. \"0644\"", v.(string))
+		}
+		permissions = p
+	}
+
+	content := []byte(d.Get("content").(string))
+	path := d.Get("path").(string)
+
+	err := session.upload(context.Background(), bytes.NewReader(content), int64(len(content)), path, permissions)
+	if err != nil {
+		log.Printf("[ERROR] Unable to upload guest file '%s' to VM '%s'", path, d.Get("vm_name").(string))
+	}
+	return err
+}
@@ -0,0 +1,158 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/kr/pretty"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/find"
+)
+
+// The guest file test needs an existing powered on VM with VMware Tools
+// running in the default datacenter and the credentials of a guest user
+var testGuest struct {
+	VMName string
+	User string
+	Password string
+}
+
+func TestAccVsphereVMGuestFile_normal(t *testing.T) {
+
+	_, filename, _, _ := runtime.Caller(0)
+	ut := os.Getenv("UNIT_TEST")
+	if ut == "" || ut == filepath.Base(filename) {
+
+		testGuest.VMName = os.Getenv("GUEST_VM_NAME")
+		testGuest.User = os.Getenv("GUEST_USER")
+		testGuest.Password = os.Getenv("GUEST_PASSWORD")
+		if testGuest.VMName == "" || testGuest.User == "" {
+			t.Skip("GUEST_VM_NAME, GUEST_USER and GUEST_PASSWORD must be set for the guest file acceptance test to run.")
+		}
+
+		resource.Test( t,
+			resource.TestCase {
+				PreCheck: func() { testAccPreCheck(t) },
+				Providers: testAccProviders,
+				CheckDestroy: testAccCheckVMGuestFileDestroy,
+				Steps: []resource.TestStep {
+					resource.TestStep {
+						Config: fmt.Sprintf( testAccVMGuestFileConfig,
+							testGuest.VMName,
+							testGuest.User,
+							testGuest.Password,
+							"hello",
+							"0644",
+						),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckVMGuestFileExists("vsphere_vm_guest_file.gf1", "hello"),
+							resource.TestCheckResourceAttr("vsphere_vm_guest_file.gf1", "permissions", "0644"),
+						),
+					},
+					resource.TestStep {
+						Config: fmt.Sprintf( testAccVMGuestFileConfig,
+							testGuest.VMName,
+							testGuest.User,
+							testGuest.Password,
+							"hello again",
+							"0600",
+						),
+						Check: resource.ComposeTestCheckFunc(
+							testAccCheckVMGuestFileExists("vsphere_vm_guest_file.gf1", "hello again"),
+							resource.TestCheckResourceAttr("vsphere_vm_guest_file.gf1", "permissions", "0600"),
+						),
+					},
+				},
+			} )
+	}
+}
+
+func testAccCheckVMGuestFileExists(resource, content string) resource.TestCheckFunc {
+
+	return func(s *terraform.State) error {
+
+		rs, ok := s.RootModule().Resources[resource]
+		if !ok {
+			return fmt.Errorf("guest file '%s' not found in terraform state", resource)
+		}
+
+		log.Printf("[DEBUG] Terraform guest file: %# v", pretty.Formatter(rs))
+
+		session, err := getTestGuestSession()
+		if err != nil {
+			return err
+		}
+
+		actual, err := session.download(context.Background(), rs.Primary.Attributes["path"])
+		if err != nil {
+			return err
+		}
+		if string(actual) != content {
+			return fmt.Errorf("guest file '%s' has content '%s' but expected '%s'", rs.Primary.ID, string(actual), content)
+		}
+		return nil
+	}
+}
+
+func testAccCheckVMGuestFileDestroy(s *terraform.State) error {
+
+	const gf1 = "vsphere_vm_guest_file.gf1"
+
+	_, ok := s.RootModule().Resources[gf1]
+	if ok {
+		return fmt.Errorf("guest file '%s' still exists in the terraform state", gf1)
+	}
+
+	session, err := getTestGuestSession()
+	if err != nil {
+		return err
+	}
+
+	info, err := session.stat(context.Background(), "/tmp/terraform-guest-file.txt")
+	if err != nil {
+		return err
+	}
+	if info != nil {
+		return fmt.Errorf("guest file '%s' was not deleted as expected", gf1)
+	}
+	return nil
+}
+
+func getTestGuestSession() (*guestSession, error) {
+
+	client := testAccProvider.Meta().(*govmomi.Client)
+
+	finder := find.NewFinder(client.Client, false)
+	datacenter, err := finder.DefaultDatacenter(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	finder.SetDatacenter(datacenter)
+
+	vm, err := finder.VirtualMachine(context.Background(), testGuest.VMName)
+	if err != nil {
+		return nil, err
+	}
+	return newGuestSession(context.Background(), client, vm, testGuest.User, testGuest.Password)
+}
+
+const testAccVMGuestFileConfig = `
+
+resource "vsphere_vm_guest_file" "gf1" {
+	vm_name = "%s"
+	guest_user = "%s"
+	guest_password = "%s"
+
+	path = "/tmp/terraform-guest-file.txt"
+	content = "%s"
+	permissions = "%s"
+}
+`
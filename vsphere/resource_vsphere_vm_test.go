@@ -1,12 +1,15 @@
 package vsphere
 
 import (
-//	"fmt"
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/terraform/helper/resource"
 //	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/terraform"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
 )
 
 func TestAccVsphereVM_normal(t *testing.T) {
@@ -24,3 +27,349 @@ func TestAccVsphereVM_normal(t *testing.T) {
 func testAccCheckVMDestroy(s *terraform.State) error {
 	return nil
 }
+
+func TestVMDiskDevices_scratch(t *testing.T) {
+
+	var disks []interface{}
+	for i := 1; i <= 9; i++ {
+		disks = append(disks, map[string]interface{}{ "size_gb": i, "thin_provisioned": i%2 == 0 })
+	}
+
+	devices, err := newVMDiskDevices("pvscsi", disks)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if len(devices) != 10 {
+		t.Fatalf("expected a controller and 9 disks but got %d devices", len(devices))
+	}
+	if _, ok := devices[0].(*types.ParaVirtualSCSIController); !ok {
+		t.Fatalf("expected a pvscsi controller but got %T", devices[0])
+	}
+
+	keys := make(map[int]bool)
+	units := []int{ -1, 1, 2, 3, 4, 5, 6, 8, 9 }
+
+	for i, device := range devices[1:] {
+
+		disk := device.(*types.VirtualDisk)
+		if keys[disk.Key] || disk.Key >= 0 {
+			t.Fatalf("disk %d has an invalid or duplicate key %d", i, disk.Key)
+		}
+		keys[disk.Key] = true
+
+		if disk.ControllerKey != newSCSIControllerKey {
+			t.Fatalf("disk %d is not attached to the new controller", i)
+		}
+		if disk.UnitNumber != units[i] {
+			t.Fatalf("expected disk %d on unit %d but got %d", i, units[i], disk.UnitNumber)
+		}
+		if disk.CapacityInKB != int64(i+1)*1024*1024 {
+			t.Fatalf("unexpected capacity %d for disk %d", disk.CapacityInKB, i)
+		}
+	}
+
+	disks = getVMDisks(devices, nil, nil)
+	if len(disks) != 9 || disks[1].(map[string]interface{})["size_gb"].(int) != 2 || !disks[1].(map[string]interface{})["thin_provisioned"].(bool) {
+		t.Fatalf("disks were not read back as created: %#v", disks)
+	}
+
+	for _, spec := range addDeviceConfigSpecs(devices) {
+
+		s := spec.GetVirtualDeviceConfigSpec()
+		_, isDisk := s.Device.(*types.VirtualDisk)
+		if isDisk != (s.FileOperation == types.VirtualDeviceConfigSpecFileOperationCreate) {
+			t.Fatalf("unexpected file operation '%s' for %T", s.FileOperation, s.Device)
+		}
+	}
+	if getSCSIControllerType(devices) != "pvscsi" {
+		t.Fatalf("unexpected SCSI controller type '%s'", getSCSIControllerType(devices))
+	}
+}
+
+func TestVMDiskDevices_unknownController(t *testing.T) {
+
+	_, err := newVMDiskDevices("ide", nil)
+	if err == nil {
+		t.Fatalf("expected an error for an unknown SCSI controller type")
+	}
+}
+
+func TestVMCdrom_roundTrip(t *testing.T) {
+
+	cdroms := []map[string]interface{}{
+		{ "datastore": "datastore1", "path": "iso/seed.iso", "host_device": "", "start_connected": true, "connected": true },
+		{ "datastore": "", "path": "", "host_device": "/vmfs/devices/cdrom/mpx.vmhba1:C0:T0:L0", "start_connected": false, "connected": true },
+		{ "datastore": "", "path": "", "host_device": "", "start_connected": false, "connected": false },
+	}
+
+	for i, cdrom := range cdroms {
+
+		device := &types.VirtualCdrom{}
+		setCdromBacking(nil, device, cdrom)
+
+		actual := getVMCdrom(device)
+		if !cdromMatches(actual, cdrom, true) {
+			t.Fatalf("cdrom %d was not read back as configured: %#v", i, actual)
+		}
+	}
+
+	device := &types.VirtualCdrom{}
+	setCdromBacking(nil, device, cdroms[0])
+
+	swapped := map[string]interface{}{ "datastore": "datastore1", "path": "iso/other.iso", "host_device": "", "start_connected": true, "connected": true }
+	if cdromMatches(getVMCdrom(device), swapped, true) {
+		t.Fatalf("a different ISO image was not detected")
+	}
+
+	disconnected := map[string]interface{}{ "datastore": "datastore1", "path": "iso/seed.iso", "host_device": "", "start_connected": true, "connected": false }
+	if cdromMatches(getVMCdrom(device), disconnected, true) {
+		t.Fatalf("a change of the connection state was not detected")
+	}
+	if !cdromMatches(getVMCdrom(device), disconnected, false) {
+		t.Fatalf("the connection state of a VM that is not running should be ignored")
+	}
+}
+
+func TestVMAttachedDisks_notOwned(t *testing.T) {
+
+	var devices object.VirtualDeviceList
+
+	controller, err := devices.CreateSCSIController("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	devices = append(devices, controller)
+	devices = append(devices, devices.CreateDisk(controller.(types.BaseVirtualController), "[datastore1] vm/vm.vmdk"))
+	devices = append(devices, devices.CreateDisk(controller.(types.BaseVirtualController), "[datastore1] data/db.vmdk"))
+
+	attachedDisks := []interface{}{
+		map[string]interface{}{ "datastore": "datastore1", "vmdk_path": "data/db.vmdk" },
+		map[string]interface{}{ "datastore": "datastore1", "vmdk_path": "data/detached.vmdk" },
+	}
+
+	attached := getVMAttachedDisks(devices, attachedDisks)
+	if len(attached) != 1 || getAttachedDiskPath(attached[0]) != "[datastore1] data/db.vmdk" {
+		t.Fatalf("unexpected attached disks: %#v", attached)
+	}
+
+	disks := getVMDisks(devices, getAttachedDiskPaths(attached), nil)
+	if len(disks) != 1 {
+		t.Fatalf("attached disks should not be reported as disks of the VM: %#v", disks)
+	}
+}
+
+func TestVMExtraConfig_changes(t *testing.T) {
+
+	old := map[string]interface{}{ "guestinfo.userdata": "a", "guestinfo.removed": "b" }
+	new := map[string]interface{}{ "guestinfo.userdata": "c", "guestinfo.metadata": "d" }
+
+	options := getExtraConfigChanges(old, new)
+
+	expected := []string{ "guestinfo.metadata=d", "guestinfo.removed=", "guestinfo.userdata=c" }
+	if len(options) != len(expected) {
+		t.Fatalf("expected %d options but got %d", len(expected), len(options))
+	}
+	for i, option := range options {
+		o := option.GetOptionValue()
+		if actual := fmt.Sprintf("%s=%v", o.Key, o.Value); actual != expected[i] {
+			t.Fatalf("expected option '%s' but got '%s'", expected[i], actual)
+		}
+	}
+
+	extraConfig := []types.BaseOptionValue{
+		&types.OptionValue{ Key: "guestinfo.userdata", Value: "c" },
+		&types.OptionValue{ Key: "svga.present", Value: "TRUE" },
+	}
+	values := getVMExtraConfig(extraConfig, new)
+	if len(values) != 1 || values["guestinfo.userdata"] != "c" {
+		t.Fatalf("only the configured keys should be read back: %#v", values)
+	}
+}
+
+func TestVMPowerCycleChanges(t *testing.T) {
+
+	settings := func(cpus, memory int, cpuHotAdd, cpuHotRemove, memoryHotAdd bool, cdromCount int) map[string]interface{} {
+		return map[string]interface{}{
+			"cpus": cpus,
+			"memory_mb": memory,
+			"cores_per_socket": 1,
+			"cpu_hot_add_enabled": cpuHotAdd,
+			"cpu_hot_remove_enabled": cpuHotRemove,
+			"memory_hot_add_enabled": memoryHotAdd,
+			"nested_hv_enabled": false,
+			"guest_id": "otherGuest64",
+			"cdrom_count": cdromCount,
+		}
+	}
+
+	tests := []struct {
+		old, new map[string]interface{}
+		expected string
+	}{
+		{ settings(2, 1024, true, false, true, 1), settings(4, 2048, true, false, true, 1), "" },
+		{ settings(2, 1024, false, false, true, 1), settings(4, 1024, false, false, true, 1), "cpus" },
+		{ settings(4, 1024, true, false, true, 1), settings(2, 1024, true, false, true, 1), "cpus" },
+		{ settings(4, 1024, true, true, true, 1), settings(2, 1024, true, true, true, 1), "" },
+		{ settings(2, 2048, true, true, true, 1), settings(2, 1024, true, true, true, 1), "memory_mb" },
+		{ settings(2, 1024, true, true, false, 1), settings(2, 2048, true, true, false, 1), "memory_mb" },
+		{ settings(2, 1024, false, false, false, 1), settings(2, 1024, true, false, false, 1), "cpu_hot_add_enabled" },
+		{ settings(2, 1024, true, true, true, 1), settings(2, 1024, true, true, true, 2), "cdrom" },
+		{ settings(2, 1024, true, true, true, 2), settings(2, 1024, true, true, true, 1), "cdrom" },
+	}
+
+	for i, test := range tests {
+		if actual := strings.Join(getPowerCycleChanges(test.old, test.new), ","); actual != test.expected {
+			t.Fatalf("test %d: expected changes '%s' but got '%s'", i, test.expected, actual)
+		}
+	}
+}
+
+func TestVMCustomizationResult(t *testing.T) {
+
+	started := &types.CustomizationStartedEvent{}
+	succeeded := &types.CustomizationSucceeded{}
+	failed := &types.CustomizationSysprepFailed{}
+	failed.FullFormattedMessage = "Sysprep failed"
+	failed.LogLocation = `C:\Windows\TEMP\vmware-imc\guestcust.log`
+
+	if done, err := getVMCustomizationResult([]types.BaseEvent{ started }); done || err != nil {
+		t.Fatalf("expected customization to be in progress but got done=%v err=%v", done, err)
+	}
+	if done, err := getVMCustomizationResult([]types.BaseEvent{ started, succeeded }); !done || err != nil {
+		t.Fatalf("expected customization to have succeeded but got done=%v err=%v", done, err)
+	}
+	done, err := getVMCustomizationResult([]types.BaseEvent{ started, failed })
+	if !done || err == nil {
+		t.Fatalf("expected customization to have failed but got done=%v err=%v", done, err)
+	}
+	if !strings.Contains(err.Error(), "Sysprep failed") || !strings.Contains(err.Error(), "guestcust.log") {
+		t.Fatalf("expected the guest's error in '%s'", err.Error())
+	}
+}
+
+func TestVMDiskLocators(t *testing.T) {
+
+	var devices object.VirtualDeviceList
+
+	controller, err := devices.CreateSCSIController("pvscsi")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	devices = append(devices, controller)
+
+	ds1 := types.ManagedObjectReference{ Type: "Datastore", Value: "datastore-1" }
+	ds2 := types.ManagedObjectReference{ Type: "Datastore", Value: "datastore-2" }
+
+	paths := []string{ "[datastore1] vm/vm.vmdk", "[datastore1] data/db.vmdk", "[datastore1] vm/vm_1.vmdk" }
+	for i, p := range paths {
+		disk := devices.CreateDisk(controller.(types.BaseVirtualController), p)
+		disk.Key = 2000 + i
+		disk.Backing.(*types.VirtualDiskFlatVer2BackingInfo).Datastore = &ds1
+		devices = append(devices, disk)
+	}
+
+	attached := map[string]bool{ "[datastore1] data/db.vmdk": true }
+	configured := []interface{}{
+		map[string]interface{}{ "size_gb": 10, "thin_provisioned": true, "datastore": "" },
+		map[string]interface{}{ "size_gb": 20, "thin_provisioned": true, "datastore": "datastore2" },
+	}
+	datastores := map[string]types.ManagedObjectReference{ "datastore2": ds2 }
+
+	locators := getVMDiskLocators(devices, attached, configured, datastores, false)
+	if len(locators) != 1 || locators[0].DiskId != 2002 || locators[0].Datastore != ds2 {
+		t.Fatalf("expected only the second disk of the VM to be moved but got: %#v", locators)
+	}
+
+	// Moving the VM pins the attached disk and the disk with its own datastore
+	locators = getVMDiskLocators(devices, attached, configured, datastores, true)
+	if len(locators) != 2 || locators[0].DiskId != 2001 || locators[0].Datastore != ds1 || locators[1].DiskId != 2002 {
+		t.Fatalf("expected the attached disk and the second disk to be pinned but got: %#v", locators)
+	}
+
+	configured[1].(map[string]interface{})["datastore"] = "datastore1"
+	datastores = map[string]types.ManagedObjectReference{ "datastore1": ds1 }
+	locators = getVMDiskLocators(devices, attached, configured, datastores, false)
+	if len(locators) != 0 {
+		t.Fatalf("expected no disks to be moved but got: %#v", locators)
+	}
+
+	disks := getVMDisks(devices, attached, configured)
+	if len(disks) != 2 || disks[0].(map[string]interface{})["datastore"] != "" || disks[1].(map[string]interface{})["datastore"] != "datastore1" {
+		t.Fatalf("expected only the configured disk datastore to be read but got: %#v", disks)
+	}
+}
+
+func TestDatastorePathName(t *testing.T) {
+
+	for path, name := range map[string]string{
+		"[datastore1] vm/vm.vmx": "datastore1",
+		"[shared ds] vm.vmx": "shared ds",
+		"vm/vm.vmx": "",
+		"": "",
+	} {
+		if actual := getDatastorePathName(path); actual != name {
+			t.Fatalf("expected datastore '%s' for path '%s' but got '%s'", name, path, actual)
+		}
+	}
+}
+
+func TestStorageDrsPlacement(t *testing.T) {
+
+	recommendations := []types.ClusterRecommendation{
+		types.ClusterRecommendation{ Key: "1", Rating: 3 },
+		types.ClusterRecommendation{ Key: "2", Rating: 5 },
+		types.ClusterRecommendation{ Key: "3", Rating: 4 },
+	}
+	if r := getBestStorageDrsRecommendation(recommendations); r == nil || r.Key != "2" {
+		t.Fatalf("expected the highest rated recommendation but got: %v", r)
+	}
+	if r := getBestStorageDrsRecommendation(nil); r != nil {
+		t.Fatalf("expected no recommendation but got: %v", r)
+	}
+
+	devices, err := newVMDiskDevices("lsilogic", []interface{}{
+		map[string]interface{}{ "size_gb": 10, "thin_provisioned": true },
+		map[string]interface{}{ "size_gb": 20, "thin_provisioned": false },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	locators := getPodDiskLocators(addDeviceConfigSpecs(devices))
+	if len(locators) != 2 || locators[0].DiskId != newDiskKeyBase || locators[1].DiskId != newDiskKeyBase-1 {
+		t.Fatalf("expected a locator for each new disk but got: %#v", locators)
+	}
+}
+
+func TestVMStorageInDatastores(t *testing.T) {
+
+	var devices object.VirtualDeviceList
+
+	controller, err := devices.CreateSCSIController("pvscsi")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	devices = append(devices, controller)
+
+	for i, p := range []string{ "[pod-ds1] vm/vm.vmdk", "[shared] data/db.vmdk" } {
+		disk := devices.CreateDisk(controller.(types.BaseVirtualController), p)
+		disk.Key = 2000 + i
+		devices = append(devices, disk)
+	}
+	cdrom := &types.VirtualCdrom{}
+	setCdromBacking(nil, cdrom, map[string]interface{}{ "datastore": "isos", "path": "ubuntu.iso", "host_device": "", "start_connected": true, "connected": true })
+	devices = append(devices, cdrom)
+
+	pod := map[string]bool{ "pod-ds1": true, "pod-ds2": true }
+	attached := map[string]bool{ "[shared] data/db.vmdk": true }
+
+	if !isVMStorageInDatastores(devices, "[pod-ds1] vm/vm.vmx", attached, pod) {
+		t.Fatalf("expected ISO images and attached disks outside the datastore cluster to be ignored")
+	}
+	if isVMStorageInDatastores(devices, "[pod-ds1] vm/vm.vmx", map[string]bool{}, pod) {
+		t.Fatalf("expected an owned disk outside the datastore cluster to be detected")
+	}
+	if isVMStorageInDatastores(devices, "[other] vm/vm.vmx", attached, pod) {
+		t.Fatalf("expected a configuration file outside the datastore cluster to be detected")
+	}
+}
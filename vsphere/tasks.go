@@ -0,0 +1,115 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/progress"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// Time allowed for tasks that change configuration or power state
+const defaultTaskTimeout = 15 * time.Minute
+
+// Time allowed for tasks that copy data such as clones, OVF imports and
+// adding hosts
+const longTaskTimeout = 2 * time.Hour
+
+// Context shared by all tasks of the provider which is cancelled when
+// terraform is interrupted. This version of terraform has no way to ask a
+// provider to stop so the interrupt the plugin process receives along with
+// terraform is used instead.
+var (
+	stopContext, stopTasks = context.WithCancel(context.Background())
+	handleStopOnce sync.Once
+)
+
+// Starts cancelling running tasks once the process is interrupted. A second
+// interrupt is left to the default handling.
+func handleStop() {
+
+	handleStopOnce.Do(func() {
+
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+
+		go func() {
+			s := <-ch
+			signal.Stop(ch)
+
+			log.Printf("[DEBUG] Received %s. Cancelling running vSphere tasks.", s.String())
+			stopTasks()
+		}()
+	})
+}
+
+// Waits for a vSphere task to complete logging its progress. The task is
+// cancelled in vCenter when it does not complete within the timeout or
+// when terraform is interrupted.
+func waitForTask(c *vim25.Client, task *object.Task, description string, timeout time.Duration) (*types.TaskInfo, error) {
+
+	ctx, cancel := context.WithTimeout(stopContext, timeout)
+	defer cancel()
+
+	ch := make(chan progress.Report)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		logTaskProgress(description, ch)
+	}()
+
+	info, err := task.WaitForResult(ctx, progress.SinkFunc(func() chan<- progress.Report { return ch }))
+	<-done
+
+	if ctx.Err() != nil {
+
+		log.Printf("[DEBUG] Cancelling task '%s' to %s", task.Reference().Value, description)
+
+		_, cancelErr := methods.CancelTask(context.Background(), c, &types.CancelTask{ This: task.Reference() })
+		if cancelErr != nil {
+			log.Printf("[ERROR] Unable to cancel task '%s': %s", task.Reference().Value, cancelErr.Error())
+		}
+
+		return nil, getStopError("task to "+description, timeout)
+	}
+	if err != nil {
+		log.Printf("[ERROR] Task to %s failed: %s", description, err.Error())
+	}
+	return info, err
+}
+
+// Returns the error of an operation whose context ended either because
+// terraform was interrupted or because the timeout passed.
+func getStopError(description string, timeout time.Duration) error {
+
+	if stopContext.Err() != nil {
+		return fmt.Errorf("%s was cancelled as terraform was interrupted", description)
+	}
+	return fmt.Errorf("%s did not complete within %s", description, timeout.String())
+}
+
+// Logs the progress reports of a task each time its percentage changes.
+func logTaskProgress(description string, ch <-chan progress.Report) {
+
+	last := -1
+	for r := range ch {
+		if r.Error() != nil {
+			continue
+		}
+		if p := int(r.Percentage()); p != last {
+			log.Printf("[DEBUG] %s: %d%%", description, p)
+			last = p
+		}
+	}
+}
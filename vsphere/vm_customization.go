@@ -0,0 +1,111 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/event"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// Interval at which the events of a VM being customized are queried
+const vmCustomizationPollInterval = 10 * time.Second
+
+// Time allowed for a powered on clone to report its IP address
+const vmIPTimeout = 15 * time.Minute
+
+// Event types that end the customization of a guest. The failure subtypes
+// are listed as the event type filter does not match subtypes.
+var vmCustomizationEventTypes = []string{
+	"CustomizationSucceeded",
+	"CustomizationFailed",
+	"CustomizationLinuxIdentityFailed",
+	"CustomizationNetworkSetupFailed",
+	"CustomizationSysprepFailed",
+	"CustomizationUnknownFailure",
+}
+
+// Waits for vCenter to report that the customization of a cloned VM's guest
+// has completed. Customization runs in the guest after the VM is first
+// powered on so the clone task ends long before it does. Waiting stops when
+// terraform is interrupted.
+func waitForVMCustomization(client *govmomi.Client, vm *object.VirtualMachine, timeout time.Duration) error {
+
+	ctx, cancel := context.WithTimeout(stopContext, timeout)
+	defer cancel()
+
+	manager := event.NewManager(client.Client)
+	filter := types.EventFilterSpec{
+		Entity: &types.EventFilterSpecByEntity{
+			Entity: vm.Reference(),
+			Recursion: types.EventFilterSpecRecursionOptionSelf,
+		},
+		Type: vmCustomizationEventTypes,
+	}
+
+	log.Printf("[DEBUG] Waiting for the customization of VM '%s' to complete", vm.Reference().Value)
+
+	for {
+		events, err := manager.QueryEvents(ctx, filter)
+		if err != nil {
+			if ctx.Err() != nil {
+				return getStopError(fmt.Sprintf("customization of VM '%s'", vm.Reference().Value), timeout)
+			}
+			return err
+		}
+
+		done, err := getVMCustomizationResult(events)
+		if done {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return getStopError(fmt.Sprintf("customization of VM '%s'", vm.Reference().Value), timeout)
+		case <-time.After(vmCustomizationPollInterval):
+		}
+	}
+}
+
+// Waits for the guest of a powered on VM to report its IP address. Waiting
+// stops when terraform is interrupted.
+func waitForVMIP(vm *object.VirtualMachine) (string, error) {
+
+	ctx, cancel := context.WithTimeout(stopContext, vmIPTimeout)
+	defer cancel()
+
+	ip, err := vm.WaitForIP(ctx)
+	if err != nil && ctx.Err() != nil {
+		return "", getStopError(fmt.Sprintf("waiting for the IP address of VM '%s'", vm.Reference().Value), vmIPTimeout)
+	}
+	return ip, err
+}
+
+// Returns whether the given events show that customization has completed
+// and the guest's error if it failed.
+func getVMCustomizationResult(events []types.BaseEvent) (bool, error) {
+
+	for _, e := range events {
+		switch c := e.(type) {
+		case *types.CustomizationSucceeded:
+			return true, nil
+		case types.BaseCustomizationFailed:
+			f := c.GetCustomizationFailed()
+			return true, getVMCustomizationError(f.GetEvent(), f.LogLocation)
+		}
+	}
+	return false, nil
+}
+
+func getVMCustomizationError(e *types.Event, logLocation string) error {
+
+	if logLocation != "" {
+		return fmt.Errorf("customization failed: %s (see %s in the guest)", e.FullFormattedMessage, logLocation)
+	}
+	return fmt.Errorf("customization failed: %s", e.FullFormattedMessage)
+}
@@ -0,0 +1,535 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"golang.org/x/net/context"
+
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// Device keys of new devices only need to be unique and negative
+// within a config spec. vCenter assigns the actual keys.
+const (
+	newSCSIControllerKey = -100
+	newDiskKeyBase       = -200
+	newEthernetKeyBase   = -300
+)
+
+// The unit number of a SCSI controller on its own bus
+const scsiControllerUnitNumber = 7
+
+// Returns the SCSI controller and the disks of a VM built from scratch. Disk
+// files are created in the VM's folder and named after the VM by vCenter.
+func newVMDiskDevices(scsiType string, disks []interface{}) (object.VirtualDeviceList, error) {
+
+	var devices object.VirtualDeviceList
+
+	controller, err := devices.CreateSCSIController(scsiType)
+	if err != nil {
+		return nil, err
+	}
+	controller.GetVirtualDevice().Key = newSCSIControllerKey
+	devices = append(devices, controller)
+
+	unitNumber := 0
+	for i, d := range disks {
+
+		disk := d.(map[string]interface{})
+
+		device := devices.CreateDisk(controller.(types.BaseVirtualController), "")
+		device.Key = newDiskKeyBase - i
+		device.CapacityInKB = int64(disk["size_gb"].(int)) * 1024 * 1024
+
+		thin := disk["thin_provisioned"].(bool)
+		device.Backing.(*types.VirtualDiskFlatVer2BackingInfo).ThinProvisioned = &thin
+
+		if unitNumber == scsiControllerUnitNumber {
+			unitNumber++
+		}
+		// A unit number of 0 is dropped when serialized so -1 is
+		// passed to have vCenter pick the first free unit instead
+		if unitNumber == 0 {
+			device.UnitNumber = -1
+		} else {
+			device.UnitNumber = unitNumber
+		}
+		unitNumber++
+
+		devices = append(devices, device)
+	}
+
+	return devices, nil
+}
+
+// Returns the network adapters of a VM built from scratch.
+func newVMNetworkDevices(finder *find.Finder, networkInterfaces []interface{}) (object.VirtualDeviceList, error) {
+
+	var devices object.VirtualDeviceList
+
+	for i, n := range networkInterfaces {
+
+		networkInterface := n.(map[string]interface{})
+
+		network, err := finder.Network(context.Background(), networkInterface["label"].(string))
+		if err != nil {
+			return nil, err
+		}
+		backing, err := network.EthernetCardBackingInfo(context.Background())
+		if err != nil {
+			return nil, err
+		}
+
+		device, err := devices.CreateEthernetCard(networkInterface["adapter_type"].(string), backing)
+		if err != nil {
+			return nil, err
+		}
+		device.GetVirtualDevice().Key = newEthernetKeyBase - i
+
+		devices = append(devices, device)
+	}
+
+	return devices, nil
+}
+
+// Returns the config spec changes adding the given new devices. Disks
+// without a backing file name have their files created.
+func addDeviceConfigSpecs(devices object.VirtualDeviceList) []types.BaseVirtualDeviceConfigSpec {
+
+	var specs []types.BaseVirtualDeviceConfigSpec
+
+	for _, device := range devices {
+
+		spec := &types.VirtualDeviceConfigSpec{
+			Operation: types.VirtualDeviceConfigSpecOperationAdd,
+			Device: device,
+		}
+		if disk, ok := device.(*types.VirtualDisk); ok {
+			if backing, ok := disk.Backing.(*types.VirtualDiskFlatVer2BackingInfo); ok && backing.FileName == "" {
+				spec.FileOperation = types.VirtualDeviceConfigSpecFileOperationCreate
+			}
+		}
+		specs = append(specs, spec)
+	}
+
+	return specs
+}
+
+// Returns the disks of a VM in the form of the vsphere_vm disk list.
+// Disks attached from other resources are given by their datastore paths.
+func getVMDisks(devices object.VirtualDeviceList, attached map[string]bool, configured []interface{}) []interface{} {
+
+	disks := []interface{}{}
+
+	for _, device := range devices.SelectByType((*types.VirtualDisk)(nil)) {
+
+		disk := device.(*types.VirtualDisk)
+		thin := false
+		fileName := ""
+		if backing, ok := disk.Backing.(*types.VirtualDiskFlatVer2BackingInfo); ok {
+			if attached[backing.FileName] {
+				continue
+			}
+			if backing.ThinProvisioned != nil {
+				thin = *backing.ThinProvisioned
+			}
+			fileName = backing.FileName
+		}
+
+		// The datastore of a disk is only tracked when its placement is configured
+		datastore := ""
+		if i := len(disks); i < len(configured) && configured[i].(map[string]interface{})["datastore"].(string) != "" {
+			datastore = getDatastorePathName(fileName)
+		}
+
+		disks = append(disks, map[string]interface{}{
+			"size_gb": int(disk.CapacityInKB / (1024 * 1024)),
+			"thin_provisioned": thin,
+			"datastore": datastore,
+		})
+	}
+
+	return disks
+}
+
+// Returns the name of the datastore of a path in the form '[datastore] path'.
+func getDatastorePathName(path string) string {
+
+	if strings.HasPrefix(path, "[") {
+		if i := strings.Index(path, "]"); i > 0 {
+			return path[1:i]
+		}
+	}
+	return ""
+}
+
+// Returns the network adapters of a VM in the form of the vsphere_vm
+// network_interface list. Distributed port groups are resolved by name.
+func getVMNetworkInterfaces(ctx context.Context, client *govmomi.Client, devices object.VirtualDeviceList) ([]interface{}, error) {
+
+	networkInterfaces := []interface{}{}
+
+	for _, device := range devices.SelectByType((*types.VirtualEthernetCard)(nil)) {
+
+		card := device.(types.BaseVirtualEthernetCard).GetVirtualEthernetCard()
+
+		var label string
+		switch backing := card.Backing.(type) {
+		case *types.VirtualEthernetCardNetworkBackingInfo:
+			label = backing.DeviceName
+		case *types.VirtualEthernetCardDistributedVirtualPortBackingInfo:
+			name, err := getObjectName(ctx, client.Client,
+				types.ManagedObjectReference{ Type: "DistributedVirtualPortgroup", Value: backing.Port.PortgroupKey })
+			if err != nil {
+				return nil, err
+			}
+			label = name
+		}
+
+		networkInterfaces = append(networkInterfaces, map[string]interface{}{
+			"label": label,
+			"adapter_type": getEthernetCardType(device),
+			"mac_address": card.MacAddress,
+		})
+	}
+
+	return networkInterfaces, nil
+}
+
+// Returns the adapter type of a network adapter.
+func getEthernetCardType(device types.BaseVirtualDevice) string {
+
+	switch device.(type) {
+	case *types.VirtualE1000:
+		return "e1000"
+	case *types.VirtualE1000e:
+		return "e1000e"
+	case *types.VirtualVmxnet3:
+		return "vmxnet3"
+	case *types.VirtualVmxnet2:
+		return "vmxnet2"
+	case *types.VirtualPCNet32:
+		return "pcnet32"
+	}
+	return ""
+}
+
+// Returns the type of the first SCSI controller of a VM as accepted
+// by VirtualDeviceList.CreateSCSIController.
+func getSCSIControllerType(devices object.VirtualDeviceList) string {
+
+	controllers := devices.SelectByType((*types.VirtualSCSIController)(nil))
+	if len(controllers) == 0 {
+		return ""
+	}
+	return devices.Type(controllers[0])
+}
+
+// Attaches, edits and removes the CD-ROM drives of a VM so that they match
+// the vsphere_vm cdrom list. Media in existing drives is swapped or ejected
+// in place so drives keep their position on the IDE controllers.
+func configureVMCdroms(ctx context.Context, vm *object.VirtualMachine, cdroms []interface{}) error {
+
+	devices, err := vm.Device(ctx)
+	if err != nil {
+		return err
+	}
+	existing := devices.SelectByType((*types.VirtualCdrom)(nil))
+
+	var mvm mo.VirtualMachine
+
+	err = vm.Properties(ctx, vm.Reference(), []string{"runtime.powerState"}, &mvm)
+	if err != nil {
+		return err
+	}
+	poweredOn := mvm.Runtime.PowerState == types.VirtualMachinePowerStatePoweredOn
+
+	for i, c := range cdroms {
+
+		cdrom := c.(map[string]interface{})
+
+		if i < len(existing) {
+
+			device := existing[i].(*types.VirtualCdrom)
+			if cdromMatches(getVMCdrom(device), cdrom, poweredOn) {
+				continue
+			}
+
+			log.Printf("[DEBUG] Changing media of CD-ROM drive %d", i)
+
+			setCdromBacking(devices, device, cdrom)
+			err = vm.EditDevice(ctx, device)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		ide, err := devices.FindIDEController("")
+		if err != nil {
+			return err
+		}
+		device, err := devices.CreateCdrom(ide)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("[DEBUG] Adding CD-ROM drive %d", i)
+
+		setCdromBacking(devices, device, cdrom)
+		err = vm.AddDevice(ctx, device)
+		if err != nil {
+			return err
+		}
+
+		// Reload the devices so the next drive is given a free unit
+		devices, err = vm.Device(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(existing) > len(cdroms) {
+
+		log.Printf("[DEBUG] Removing %d CD-ROM drives", len(existing)-len(cdroms))
+
+		err = vm.RemoveDevice(ctx, existing[len(cdroms):]...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Sets the media of a CD-ROM drive to an ISO image in a datastore, a host
+// device or if neither is given to the client device.
+func setCdromBacking(devices object.VirtualDeviceList, device *types.VirtualCdrom, cdrom map[string]interface{}) {
+
+	isoPath, _ := cdrom["path"].(string)
+	hostDevice, _ := cdrom["host_device"].(string)
+
+	switch {
+	case isoPath != "":
+		devices.InsertIso(device, fmt.Sprintf("[%s] %s", cdrom["datastore"].(string), isoPath))
+	case hostDevice != "":
+		device.Backing = &types.VirtualCdromAtapiBackingInfo{
+			VirtualDeviceDeviceBackingInfo: types.VirtualDeviceDeviceBackingInfo{
+				DeviceName: hostDevice,
+				UseAutoDetect: types.NewBool(false),
+			},
+		}
+	default:
+		device.Backing = &types.VirtualCdromRemotePassthroughBackingInfo{
+			VirtualDeviceRemoteDeviceBackingInfo: types.VirtualDeviceRemoteDeviceBackingInfo{
+				UseAutoDetect: types.NewBool(false),
+			},
+		}
+	}
+
+	device.Connectable = &types.VirtualDeviceConnectInfo{
+		AllowGuestControl: true,
+		StartConnected: cdrom["start_connected"].(bool),
+		Connected: cdrom["connected"].(bool),
+	}
+}
+
+// Returns the CD-ROM drives of a VM in the form of the vsphere_vm cdrom
+// list. Drives of a VM that is not running are never connected so the
+// configured connected flags are kept for them.
+func getVMCdroms(devices object.VirtualDeviceList, poweredOn bool, configured []interface{}) []interface{} {
+
+	cdroms := []interface{}{}
+
+	for i, device := range devices.SelectByType((*types.VirtualCdrom)(nil)) {
+
+		cdrom := getVMCdrom(device.(*types.VirtualCdrom))
+
+		if !poweredOn && i < len(configured) {
+			cdrom["connected"] = configured[i].(map[string]interface{})["connected"]
+		}
+		if i < len(configured) {
+			cdrom["upload_iso"] = configured[i].(map[string]interface{})["upload_iso"]
+		}
+		cdroms = append(cdroms, cdrom)
+	}
+
+	return cdroms
+}
+
+func getVMCdrom(device *types.VirtualCdrom) map[string]interface{} {
+
+	cdrom := map[string]interface{}{
+		"datastore": "",
+		"path": "",
+		"host_device": "",
+		"start_connected": false,
+		"connected": false,
+	}
+
+	switch backing := device.Backing.(type) {
+	case *types.VirtualCdromIsoBackingInfo:
+		// ISO file names are of the form "[datastore] path"
+		if parts := strings.SplitN(strings.TrimPrefix(backing.FileName, "["), "]", 2); len(parts) == 2 {
+			cdrom["datastore"] = parts[0]
+			cdrom["path"] = strings.TrimSpace(parts[1])
+		}
+	case *types.VirtualCdromAtapiBackingInfo:
+		cdrom["host_device"] = backing.DeviceName
+	}
+
+	if device.Connectable != nil {
+		cdrom["start_connected"] = device.Connectable.StartConnected
+		cdrom["connected"] = device.Connectable.Connected
+	}
+
+	return cdrom
+}
+
+func cdromMatches(actual, desired map[string]interface{}, poweredOn bool) bool {
+
+	for _, k := range []string{"path", "host_device", "start_connected"} {
+		if actual[k] != desired[k] {
+			return false
+		}
+	}
+	if poweredOn && actual["connected"] != desired["connected"] {
+		return false
+	}
+	return desired["path"] == "" || actual["datastore"] == desired["datastore"]
+}
+
+// Attaches the existing virtual disks of the vsphere_vm attached_disk list
+// and detaches the disks removed from the list. Attached disks are owned by
+// other resources so their files are never created or deleted.
+func configureVMAttachedDisks(ctx context.Context, client *govmomi.Client, vm *object.VirtualMachine, old, attachedDisks []interface{}) error {
+
+	devices, err := vm.Device(ctx)
+	if err != nil {
+		return err
+	}
+
+	attached := getAttachedDiskPaths(attachedDisks)
+
+	var detach []types.BaseVirtualDevice
+	for _, a := range old {
+		diskPath := getAttachedDiskPath(a)
+		if attached[diskPath] {
+			continue
+		}
+		if disk := findVMDiskByPath(devices, diskPath); disk != nil {
+			detach = append(detach, disk)
+		}
+	}
+	if len(detach) > 0 {
+
+		log.Printf("[DEBUG] Detaching %d virtual disks", len(detach))
+
+		err = detachVMDisks(ctx, client, vm, detach...)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, a := range attachedDisks {
+
+		diskPath := getAttachedDiskPath(a)
+		if findVMDiskByPath(devices, diskPath) != nil {
+			continue
+		}
+
+		controller, err := devices.FindSCSIController("")
+		if err != nil {
+			return err
+		}
+
+		disk := devices.CreateDisk(controller, diskPath)
+		if disk.UnitNumber == scsiControllerUnitNumber {
+			disk.UnitNumber++
+		}
+
+		log.Printf("[DEBUG] Attaching virtual disk: %s", diskPath)
+
+		// Disks without a capacity are attached rather than created
+		err = vm.AddDevice(ctx, disk)
+		if err != nil {
+			return err
+		}
+
+		devices, err = vm.Device(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Removes disks from a VM without deleting their files.
+func detachVMDisks(ctx context.Context, client *govmomi.Client, vm *object.VirtualMachine, disks ...types.BaseVirtualDevice) error {
+
+	spec := types.VirtualMachineConfigSpec{}
+
+	for _, disk := range disks {
+		spec.DeviceChange = append(spec.DeviceChange, &types.VirtualDeviceConfigSpec{
+			Operation: types.VirtualDeviceConfigSpecOperationRemove,
+			Device: disk,
+		})
+	}
+
+	task, err := vm.Reconfigure(ctx, spec)
+	if err != nil {
+		return err
+	}
+	_, err = waitForTask(client.Client, task, fmt.Sprintf("detach disks from VM '%s'", vm.Reference().Value), defaultTaskTimeout)
+	return err
+}
+
+// Returns the attached disks of the vsphere_vm attached_disk list that are
+// still attached to the VM.
+func getVMAttachedDisks(devices object.VirtualDeviceList, attachedDisks []interface{}) []interface{} {
+
+	disks := []interface{}{}
+
+	for _, a := range attachedDisks {
+		if findVMDiskByPath(devices, getAttachedDiskPath(a)) != nil {
+			disks = append(disks, a)
+		}
+	}
+
+	return disks
+}
+
+func getAttachedDiskPaths(attachedDisks []interface{}) map[string]bool {
+
+	paths := make(map[string]bool)
+	for _, a := range attachedDisks {
+		paths[getAttachedDiskPath(a)] = true
+	}
+	return paths
+}
+
+// Returns the datastore path of the form "[datastore] path" of an attached disk.
+func getAttachedDiskPath(attachedDisk interface{}) string {
+
+	a := attachedDisk.(map[string]interface{})
+	return fmt.Sprintf("[%s] %s", a["datastore"].(string), a["vmdk_path"].(string))
+}
+
+func findVMDiskByPath(devices object.VirtualDeviceList, diskPath string) *types.VirtualDisk {
+
+	for _, device := range devices.SelectByType((*types.VirtualDisk)(nil)) {
+		if backing, ok := device.GetVirtualDevice().Backing.(types.BaseVirtualDeviceFileBackingInfo); ok {
+			if backing.GetVirtualDeviceFileBackingInfo().FileName == diskPath {
+				return device.(*types.VirtualDisk)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"path"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// Migrates a VM to the host, resource pool and datastores it is configured
+// to be placed on. The VM is moved with vMotion and Storage vMotion when it
+// is running and nothing is done when it is already placed as configured.
+func relocateVM(d *schema.ResourceData, meta interface{}, finder *find.Finder, vm *object.VirtualMachine) error {
+
+	client := meta.(*govmomi.Client)
+	name := d.Get("vm_name").(string)
+
+	spec, err := getVMRelocateSpec(context.Background(), d, meta, finder, vm)
+	if err != nil {
+		return err
+	}
+	if spec == nil {
+		return relocateVMToDatastoreCluster(d, meta, finder, vm)
+	}
+
+	log.Printf("[DEBUG] Migrating VM '%s' with priority %s", name, d.Get("migration_priority").(string))
+
+	req := types.RelocateVM_Task{
+		This: vm.Reference(),
+		Spec: *spec,
+		Priority: types.VirtualMachineMovePriority(d.Get("migration_priority").(string)),
+	}
+	res, err := methods.RelocateVM_Task(context.Background(), client.Client, &req)
+	if err != nil {
+		log.Printf("[ERROR] Unable to migrate VM '%s'", name)
+		return err
+	}
+
+	_, err = waitForTask(client.Client, object.NewTask(client.Client, res.Returnval), fmt.Sprintf("migrate VM '%s'", name), longTaskTimeout)
+	if err != nil {
+		return err
+	}
+
+	return relocateVMToDatastoreCluster(d, meta, finder, vm)
+}
+
+// Returns the relocate spec that moves a VM to its configured placement or
+// nil if the VM is already placed as configured.
+func getVMRelocateSpec(ctx context.Context, d *schema.ResourceData, meta interface{}, finder *find.Finder, vm *object.VirtualMachine) (*types.VirtualMachineRelocateSpec, error) {
+
+	client := meta.(*govmomi.Client)
+
+	var mvm mo.VirtualMachine
+
+	err := vm.Properties(ctx, vm.Reference(), []string{"resourcePool", "runtime", "config"}, &mvm)
+	if err != nil {
+		return nil, err
+	}
+	if mvm.Config == nil {
+		return nil, fmt.Errorf("configuration of VM '%s' is not available", d.Get("vm_name").(string))
+	}
+
+	spec := &types.VirtualMachineRelocateSpec{}
+
+	host, err := getVMHost(d, meta, finder)
+	if err != nil {
+		return nil, err
+	}
+	if host != nil && (mvm.Runtime.Host == nil || *mvm.Runtime.Host != host.Reference()) {
+		ref := host.Reference()
+		spec.Host = &ref
+	}
+
+	if v, ok := d.GetOk("resource_pool"); ok {
+
+		pool, err := getResourcePoolParent(v.(string), finder, client)
+		if err != nil {
+			return nil, err
+		}
+		if mvm.ResourcePool == nil || *mvm.ResourcePool != pool.Reference() {
+			ref := pool.Reference()
+			spec.Pool = &ref
+		}
+
+	} else if spec.Host != nil && mvm.ResourcePool != nil {
+
+		// A host of another cluster can only be moved to together with
+		// a resource pool of that cluster
+		pool, err := host.ResourcePool(ctx)
+		if err != nil {
+			return nil, err
+		}
+		owner, err := getResourcePoolOwner(ctx, client.Client, *mvm.ResourcePool)
+		if err != nil {
+			return nil, err
+		}
+		targetOwner, err := getResourcePoolOwner(ctx, client.Client, pool.Reference())
+		if err != nil {
+			return nil, err
+		}
+		if owner != targetOwner {
+			ref := pool.Reference()
+			spec.Pool = &ref
+		}
+	}
+
+	moveAll := false
+	if v, ok := d.GetOk("datastore"); ok {
+
+		datastore, err := findDatastore(ctx, finder, v.(string))
+		if err != nil {
+			return nil, err
+		}
+		if getDatastorePathName(mvm.Config.Files.VmPathName) != datastore.Name() {
+			ref := datastore.Reference()
+			spec.Datastore = &ref
+			moveAll = true
+		}
+	}
+
+	disks := d.Get("disk").([]interface{})
+
+	datastores := make(map[string]types.ManagedObjectReference)
+	for _, disk := range disks {
+		name := disk.(map[string]interface{})["datastore"].(string)
+		if _, ok := datastores[name]; name == "" || ok {
+			continue
+		}
+		datastore, err := findDatastore(ctx, finder, name)
+		if err != nil {
+			return nil, err
+		}
+		datastores[name] = datastore.Reference()
+	}
+
+	devices := object.VirtualDeviceList(mvm.Config.Hardware.Device)
+	attached := getAttachedDiskPaths(getVMAttachedDisks(devices, d.Get("attached_disk").([]interface{})))
+
+	spec.Disk = getVMDiskLocators(devices, attached, disks, datastores, moveAll)
+
+	if spec.Host == nil && spec.Pool == nil && spec.Datastore == nil && len(spec.Disk) == 0 {
+		return nil, nil
+	}
+	return spec, nil
+}
+
+// Returns the locators of the disks to move. When the whole VM is moved to
+// another datastore disks with their own datastore and disks attached from
+// other resources are pinned to where they are configured or are now.
+func getVMDiskLocators(devices object.VirtualDeviceList, attached map[string]bool, configured []interface{},
+	datastores map[string]types.ManagedObjectReference, moveAll bool) []types.VirtualMachineRelocateSpecDiskLocator {
+
+	var locators []types.VirtualMachineRelocateSpecDiskLocator
+
+	// Disks are indexed as in the vsphere_vm disk list
+	i := 0
+	for _, device := range devices.SelectByType((*types.VirtualDisk)(nil)) {
+
+		disk := device.(*types.VirtualDisk)
+
+		var file *types.VirtualDeviceFileBackingInfo
+		if backing, ok := disk.Backing.(types.BaseVirtualDeviceFileBackingInfo); ok {
+			file = backing.GetVirtualDeviceFileBackingInfo()
+		}
+
+		if file != nil && attached[file.FileName] {
+			if moveAll && file.Datastore != nil {
+				locators = append(locators, types.VirtualMachineRelocateSpecDiskLocator{ DiskId: disk.Key, Datastore: *file.Datastore })
+			}
+			continue
+		}
+
+		index := i
+		i++
+
+		if file == nil || file.Datastore == nil || index >= len(configured) {
+			continue
+		}
+		name := configured[index].(map[string]interface{})["datastore"].(string)
+		if name == "" {
+			continue
+		}
+
+		datastore := datastores[name]
+		if moveAll || datastore != *file.Datastore {
+			locators = append(locators, types.VirtualMachineRelocateSpecDiskLocator{ DiskId: disk.Key, Datastore: datastore })
+		}
+	}
+
+	return locators
+}
+
+// Returns the host a VM is configured to run on by its name or object_id
+// or nil if no host is configured.
+func getVMHost(d *schema.ResourceData, meta interface{}, finder *find.Finder) (*object.HostSystem, error) {
+
+	v, ok := d.GetOk("host")
+	if !ok {
+		return nil, nil
+	}
+
+	client := meta.(*govmomi.Client)
+
+	ref, err := findObjectReference(context.Background(), client.Client, "HostSystem", v.(string))
+	if err != nil {
+		return nil, err
+	}
+	if ref != nil {
+		return object.NewHostSystem(client.Client, *ref), nil
+	}
+
+	return finder.HostSystem(context.Background(), fmt.Sprintf("*/%s", v.(string)))
+}
+
+// Returns the cluster or standalone host a resource pool or vApp belongs to.
+func getResourcePoolOwner(ctx context.Context, client *vim25.Client, ref types.ManagedObjectReference) (types.ManagedObjectReference, error) {
+
+	pc := property.DefaultCollector(client)
+
+	if ref.Type == "VirtualApp" {
+		var app mo.VirtualApp
+		err := pc.RetrieveOne(ctx, ref, []string{"owner"}, &app)
+		return app.Owner, err
+	}
+
+	var pool mo.ResourcePool
+	err := pc.RetrieveOne(ctx, ref, []string{"owner"}, &pool)
+	return pool.Owner, err
+}
+
+// Reads the host, resource pool, datastore and datastore cluster of a VM when
+// they are configured so that migrations made outside of terraform show as
+// changes.
+func readVMPlacement(d *schema.ResourceData, meta interface{}, finder *find.Finder, vm *object.VirtualMachine, mvm *mo.VirtualMachine) error {
+
+	client := meta.(*govmomi.Client)
+
+	if v, ok := d.GetOk("host"); ok && mvm.Summary.Runtime.Host != nil {
+
+		host := *mvm.Summary.Runtime.Host
+		if v.(string) != host.Value {
+
+			name, err := getObjectName(context.Background(), client.Client, host)
+			if err != nil {
+				return err
+			}
+			if v.(string) != name {
+				d.Set("host", name)
+			}
+		}
+	}
+
+	if v, ok := d.GetOk("resource_pool"); ok && mvm.ResourcePool != nil && v.(string) != mvm.ResourcePool.Value {
+
+		pool, err := getResourcePoolParent(v.(string), finder, client)
+		if err != nil {
+			log.Printf("[ERROR] Unable to find resource pool '%s' of VM '%s'", v.(string), d.Id())
+			return err
+		}
+		if pool.Reference().Value != mvm.ResourcePool.Value {
+			d.Set("resource_pool", mvm.ResourcePool.Value)
+		}
+	}
+
+	if v, ok := d.GetOk("datastore"); ok && mvm.Config != nil {
+
+		name := getDatastorePathName(mvm.Config.Files.VmPathName)
+		if path.Base(v.(string)) != name {
+			d.Set("datastore", name)
+		}
+	}
+
+	if _, ok := d.GetOk("datastore_cluster"); ok {
+
+		pod, err := getVMDatastoreCluster(d, meta, finder)
+		if err != nil {
+			return err
+		}
+		inPod, err := isVMInDatastoreCluster(context.Background(), client, vm, pod, d.Get("attached_disk").([]interface{}))
+		if err != nil {
+			return err
+		}
+		if !inPod {
+			d.Set("datastore_cluster", "")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// Time given to the guest to shut down before the VM is powered off
+const vmShutdownTimeout = 5 * time.Minute
+
+// Settings of vsphere_vm that are compared to find changes that cannot
+// be applied while the VM is running
+var vmPowerCycleSettings = []string{
+	"cpus",
+	"memory_mb",
+	"cores_per_socket",
+	"cpu_hot_add_enabled",
+	"cpu_hot_remove_enabled",
+	"memory_hot_add_enabled",
+	"nested_hv_enabled",
+	"guest_id",
+}
+
+// Returns the old and new values of the settings of a VM that may need it
+// to be powered off to change. The number of CD-ROM drives is included as
+// cdrom_count since IDE devices cannot be added to or removed from a running VM.
+func getVMPowerCycleSettings(d *schema.ResourceData) (map[string]interface{}, map[string]interface{}) {
+
+	old := make(map[string]interface{})
+	new := make(map[string]interface{})
+
+	for _, k := range vmPowerCycleSettings {
+		old[k], new[k] = d.GetChange(k)
+	}
+
+	oldCdroms, newCdroms := d.GetChange("cdrom")
+	old["cdrom_count"] = len(oldCdroms.([]interface{}))
+	new["cdrom_count"] = len(newCdroms.([]interface{}))
+
+	return old, new
+}
+
+// Returns the settings whose change cannot be applied to a running VM. CPUs
+// and memory can only be changed while running when the VM's current hot
+// plug flags allow it and memory can never be removed.
+func getPowerCycleChanges(old, new map[string]interface{}) []string {
+
+	var changes []string
+
+	if o, n := old["cpus"].(int), new["cpus"].(int); (n > o && !old["cpu_hot_add_enabled"].(bool)) || (n < o && !old["cpu_hot_remove_enabled"].(bool)) {
+		changes = append(changes, "cpus")
+	}
+	if o, n := old["memory_mb"].(int), new["memory_mb"].(int); (n > o && !old["memory_hot_add_enabled"].(bool)) || n < o {
+		changes = append(changes, "memory_mb")
+	}
+	for _, k := range []string{"cores_per_socket", "cpu_hot_add_enabled", "cpu_hot_remove_enabled", "memory_hot_add_enabled", "nested_hv_enabled", "guest_id"} {
+		if old[k] != new[k] {
+			changes = append(changes, k)
+		}
+	}
+	if new["cdrom_count"].(int) != old["cdrom_count"].(int) {
+		changes = append(changes, "cdrom")
+	}
+
+	return changes
+}
+
+func isVMPoweredOn(ctx context.Context, vm *object.VirtualMachine) (bool, error) {
+
+	var mvm mo.VirtualMachine
+
+	err := vm.Properties(ctx, vm.Reference(), []string{"runtime.powerState"}, &mvm)
+	if err != nil {
+		return false, err
+	}
+	return mvm.Runtime.PowerState == types.VirtualMachinePowerStatePoweredOn, nil
+}
+
+// Shuts down the guest OS of a VM and waits for the VM to power off. A VM
+// whose guest cannot be shut down or does not shut down in time is powered off.
+func shutdownVM(client *govmomi.Client, vm *object.VirtualMachine) error {
+
+	err := vm.ShutdownGuest(context.Background())
+	if err == nil {
+
+		ctx, cancel := context.WithTimeout(context.Background(), vmShutdownTimeout)
+		defer cancel()
+
+		err = waitForVMPowerState(ctx, client, vm, types.VirtualMachinePowerStatePoweredOff)
+		if err == nil {
+			return nil
+		}
+	}
+
+	log.Printf("[DEBUG] Guest of VM '%s' did not shut down so it is powered off: %s", vm.Reference().Value, err.Error())
+
+	task, err := vm.PowerOff(context.Background())
+	if err != nil {
+		return err
+	}
+	_, err = waitForTask(client.Client, task, fmt.Sprintf("power off VM '%s'", vm.Reference().Value), defaultTaskTimeout)
+	return err
+}
+
+func powerOnVM(client *govmomi.Client, vm *object.VirtualMachine) error {
+
+	task, err := vm.PowerOn(context.Background())
+	if err != nil {
+		return err
+	}
+	_, err = waitForTask(client.Client, task, fmt.Sprintf("power on VM '%s'", vm.Reference().Value), defaultTaskTimeout)
+	return err
+}
+
+func waitForVMPowerState(ctx context.Context, client *govmomi.Client, vm *object.VirtualMachine, state types.VirtualMachinePowerState) error {
+
+	p := property.DefaultCollector(client.Client)
+
+	return property.Wait(ctx, p, vm.Reference(), []string{"runtime.powerState"}, func(pc []types.PropertyChange) bool {
+		for _, c := range pc {
+			if c.Name == "runtime.powerState" && fmt.Sprintf("%v", c.Val) == string(state) {
+				return true
+			}
+		}
+		return false
+	})
+}
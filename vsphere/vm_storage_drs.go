@@ -0,0 +1,191 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+
+	"golang.org/x/net/context"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// Returns the datastore cluster a VM is configured to be placed in or nil
+// if no datastore cluster is configured.
+func getVMDatastoreCluster(d *schema.ResourceData, meta interface{}, finder *find.Finder) (*object.StoragePod, error) {
+
+	v, ok := d.GetOk("datastore_cluster")
+	if !ok {
+		return nil, nil
+	}
+
+	datacenter, err := finder.DefaultDatacenter(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	pod, err := getDatastoreCluster(context.Background(), meta.(*govmomi.Client), datacenter, v.(string))
+	if err != nil {
+		return nil, err
+	}
+	if pod == nil {
+		return nil, fmt.Errorf("datastore cluster '%s' was not found", v.(string))
+	}
+	return pod, nil
+}
+
+// Asks storage DRS where to place a VM and applies the highest rated
+// recommendation. Returns the VM that was placed.
+func applyStorageDrsPlacement(ctx context.Context, client *govmomi.Client, spec types.StoragePlacementSpec, description string) (*types.ManagedObjectReference, error) {
+
+	sr := object.NewStorageResourceManager(client.Client)
+
+	result, err := sr.RecommendDatastores(ctx, spec)
+	if err != nil {
+		log.Printf("[ERROR] Unable to get storage DRS recommendations to %s", description)
+		return nil, err
+	}
+
+	recommendation := getBestStorageDrsRecommendation(result.Recommendations)
+	if recommendation == nil {
+		return nil, fmt.Errorf("storage DRS has no recommendation to %s", description)
+	}
+
+	log.Printf("[DEBUG] Applying storage DRS recommendation '%s' to %s: %s", recommendation.Key, description, recommendation.ReasonText)
+
+	task, err := sr.ApplyStorageDrsRecommendation(ctx, []string{ recommendation.Key })
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := waitForTask(client.Client, task, description, longTaskTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	switch r := info.Result.(type) {
+		case types.ApplyStorageRecommendationResult:
+			return r.Vm, nil
+		case *types.ApplyStorageRecommendationResult:
+			return r.Vm, nil
+	}
+	return spec.Vm, nil
+}
+
+func getBestStorageDrsRecommendation(recommendations []types.ClusterRecommendation) *types.ClusterRecommendation {
+
+	var best *types.ClusterRecommendation
+	for i, r := range recommendations {
+		if best == nil || r.Rating > best.Rating {
+			best = &recommendations[i]
+		}
+	}
+	return best
+}
+
+// Returns the disk locators that let storage DRS place the new disks of a
+// VM built from scratch.
+func getPodDiskLocators(devices []types.BaseVirtualDeviceConfigSpec) []types.PodDiskLocator {
+
+	var locators []types.PodDiskLocator
+	for _, spec := range devices {
+		if disk, ok := spec.GetVirtualDeviceConfigSpec().Device.(*types.VirtualDisk); ok {
+			locators = append(locators, types.PodDiskLocator{
+				DiskId: disk.Key,
+				DiskBackingInfo: disk.Backing,
+			})
+		}
+	}
+	return locators
+}
+
+// Moves a VM into its configured datastore cluster unless all of its files
+// already are on datastores of that datastore cluster.
+func relocateVMToDatastoreCluster(d *schema.ResourceData, meta interface{}, finder *find.Finder, vm *object.VirtualMachine) error {
+
+	client := meta.(*govmomi.Client)
+
+	pod, err := getVMDatastoreCluster(d, meta, finder)
+	if err != nil || pod == nil {
+		return err
+	}
+
+	inPod, err := isVMInDatastoreCluster(context.Background(), client, vm, pod, d.Get("attached_disk").([]interface{}))
+	if err != nil || inPod {
+		return err
+	}
+
+	vmRef := vm.Reference()
+	podRef := pod.Reference()
+
+	_, err = applyStorageDrsPlacement(context.Background(), client, types.StoragePlacementSpec{
+			Type: "relocate",
+			Priority: types.VirtualMachineMovePriority(d.Get("migration_priority").(string)),
+			Vm: &vmRef,
+			RelocateSpec: &types.VirtualMachineRelocateSpec{},
+			PodSelectionSpec: types.StorageDrsPodSelectionSpec{ StoragePod: &podRef },
+		}, fmt.Sprintf("migrate VM '%s' to datastore cluster '%s'", d.Get("vm_name").(string), d.Get("datastore_cluster").(string)))
+	return err
+}
+
+// Returns whether the files a VM owns, its configuration and the disks that
+// are not attached from other resources, are all on datastores of a
+// datastore cluster. ISO images and attached disks may live elsewhere.
+func isVMInDatastoreCluster(ctx context.Context, client *govmomi.Client, vm *object.VirtualMachine, pod *object.StoragePod, attachedDisks []interface{}) (bool, error) {
+
+	var mvm mo.VirtualMachine
+	var mpod mo.StoragePod
+	var mdatastores []mo.Datastore
+
+	err := vm.Properties(ctx, vm.Reference(), []string{"config"}, &mvm)
+	if err != nil {
+		return false, err
+	}
+	if mvm.Config == nil {
+		return false, nil
+	}
+
+	pc := property.DefaultCollector(client.Client)
+
+	err = pc.RetrieveOne(ctx, pod.Reference(), []string{"childEntity"}, &mpod)
+	if err != nil {
+		return false, err
+	}
+	if len(mpod.ChildEntity) == 0 {
+		return false, nil
+	}
+	err = pc.Retrieve(ctx, mpod.ChildEntity, []string{"name"}, &mdatastores)
+	if err != nil {
+		return false, err
+	}
+
+	members := make(map[string]bool)
+	for _, ds := range mdatastores {
+		members[ds.Name] = true
+	}
+
+	devices := object.VirtualDeviceList(mvm.Config.Hardware.Device)
+	return isVMStorageInDatastores(devices, mvm.Config.Files.VmPathName,
+		getAttachedDiskPaths(getVMAttachedDisks(devices, attachedDisks)), members), nil
+}
+
+func isVMStorageInDatastores(devices object.VirtualDeviceList, vmPathName string, attached map[string]bool, datastores map[string]bool) bool {
+
+	if !datastores[getDatastorePathName(vmPathName)] {
+		return false
+	}
+	for _, device := range devices.SelectByType((*types.VirtualDisk)(nil)) {
+		if backing, ok := device.GetVirtualDevice().Backing.(types.BaseVirtualDeviceFileBackingInfo); ok {
+			fileName := backing.GetVirtualDeviceFileBackingInfo().FileName
+			if !attached[fileName] && !datastores[getDatastorePathName(fileName)] {
+				return false
+			}
+		}
+	}
+	return true
+}